@@ -0,0 +1,93 @@
+package main
+
+import (
+	"backend/internal/repos"
+	"backend/internal/services"
+	"backend/pkg/passwordpolicy"
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const adminUsage = "usage: server admin create -username NAME | list | reset-totp -username NAME"
+
+// runAdmin implements the `admin` subcommand, used to bootstrap the first admin account
+// and to recover accounts locked out of the admin panel.
+func runAdmin(ctx context.Context, db *pgxpool.Pool, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(adminUsage)
+	}
+
+	passwordPolicy, err := passwordpolicy.FromEnv()
+	if err != nil {
+		return err
+	}
+	// The CLI never authenticates, so it needs no lockout or security events
+	adminService := services.NewAdminAuthService(repos.NewAdminAccountRepo(db), passwordPolicy, nil, nil, "")
+
+	flags := flag.NewFlagSet("admin "+args[0], flag.ContinueOnError)
+	username := flags.String("username", "", "admin account username")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "create":
+		if *username == "" {
+			return fmt.Errorf(adminUsage)
+		}
+		password, err := readAdminPassword()
+		if err != nil {
+			return err
+		}
+		account, err := adminService.CreateAccount(ctx, *username, password, "")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created admin account %s (%s)\n", account.Username, account.Id.String())
+	case "list":
+		accounts, err := adminService.ListAccounts(ctx)
+		if err != nil {
+			return err
+		}
+		for _, account := range accounts {
+			state := "active"
+			if account.Disabled() {
+				state = "disabled"
+			}
+			fmt.Printf("%s  %-30s %-8s totp=%t\n", account.Id.String(), account.Username, state, account.TOTPEnabled())
+		}
+	case "reset-totp":
+		if *username == "" {
+			return fmt.Errorf(adminUsage)
+		}
+		if err := adminService.ResetTOTP(ctx, *username); err != nil {
+			return err
+		}
+		fmt.Printf("Removed two-factor authentication from %s\n", *username)
+	default:
+		return fmt.Errorf(adminUsage)
+	}
+
+	return nil
+}
+
+// readAdminPassword takes the password from ADMIN_PASSWORD, or the first line of stdin,
+// so it never has to appear in the process arguments
+func readAdminPassword() (string, error) {
+	if password := os.Getenv("ADMIN_PASSWORD"); password != "" {
+		return password, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
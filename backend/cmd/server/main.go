@@ -2,16 +2,25 @@ package main
 
 import (
 	"backend/internal/di"
+	"backend/pkg/utilities"
 	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "keygen" {
+		if err := runKeygen(os.Args[2:]); err != nil {
+			log.Fatalf("keygen: %v", err)
+		}
+		return
+	}
+
 	fmt.Println("Starting server...")
 
 	// Create a new ServeMux for routing
@@ -29,8 +38,60 @@ func main() {
 	}
 	defer db.Close()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), db, os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
+	if err := migrateOnStart(context.Background(), db); err != nil {
+		log.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		if err := runAdmin(context.Background(), db, os.Args[2:]); err != nil {
+			log.Fatalf("admin: %v", err)
+		}
+		return
+	}
+
+	// Refuse to start without key material to sign tokens with
+	keyRing, err := utilities.LoadKeyRing(keysDir())
+	if err != nil {
+		log.Fatalf("failed to load JWT signing keys: %v (create one with `keygen`)", err)
+	}
+	utilities.SetKeyRing(keyRing)
+	// Pick up keys added or removed on disk so rotations need no restart
+	go keyRing.Watch(context.Background(), time.Minute)
+
+	// Only believe X-Forwarded-For from our own reverse proxies
+	trustedProxies, err := utilities.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatalf("TRUSTED_PROXIES must be a comma separated list of addresses or CIDR ranges: %v", err)
+	}
+	utilities.SetTrustedProxies(trustedProxies)
+
 	// Dependency injection container
-	container := di.NewContainer(db)
+	container := di.NewContainer(db, keyRing)
+
+	// Deliver events from every replica to the WebSocket clients connected here
+	go container.RealtimeHub.Run(context.Background())
+
+	// Keep directory users' workspace roles in line with their LDAP groups
+	if container.LDAPAuthenticator != nil {
+		syncInterval := 15 * time.Minute
+		if value := os.Getenv("LDAP_SYNC_INTERVAL"); value != "" {
+			syncInterval, err = time.ParseDuration(value)
+			if err != nil || syncInterval <= 0 {
+				log.Fatalf("LDAP_SYNC_INTERVAL must be a positive duration such as 15m")
+			}
+		}
+		go container.LDAPAuthenticator.Run(context.Background(), syncInterval)
+	}
+
+	// Send queued outgoing webhook deliveries; replicas share the queue
+	go container.OutgoingWebhookService.Run(context.Background(), 5*time.Second)
 
 	// Serve static files from the "./uploads" directory
 	uploadsDir := "./uploads/"
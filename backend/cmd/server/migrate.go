@@ -0,0 +1,92 @@
+package main
+
+import (
+	"backend/internal/migrations"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const migrateUsage = "usage: server migrate up | down [steps] | status"
+
+// runMigrate implements the `migrate` subcommand
+func runMigrate(ctx context.Context, db *pgxpool.Pool, args []string) error {
+	runner, err := migrations.NewRunner(db)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := runner.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+		for _, migration := range applied {
+			fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		rolledBack, err := runner.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		for _, migration := range rolledBack {
+			fmt.Printf("Rolled back %04d_%s\n", migration.Version, migration.Name)
+		}
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, state)
+		}
+	default:
+		return fmt.Errorf(migrateUsage)
+	}
+
+	return nil
+}
+
+// migrateOnStart brings the schema up to date before the server accepts traffic.
+// Set MIGRATE_ON_START=false to manage migrations separately with `migrate up`.
+func migrateOnStart(ctx context.Context, db *pgxpool.Pool) error {
+	if os.Getenv("MIGRATE_ON_START") == "false" {
+		return nil
+	}
+
+	runner, err := migrations.NewRunner(db)
+	if err != nil {
+		return err
+	}
+
+	applied, err := runner.Up(ctx)
+	if err != nil {
+		return err
+	}
+	for _, migration := range applied {
+		fmt.Printf("Applied migration %04d_%s\n", migration.Version, migration.Name)
+	}
+	return nil
+}
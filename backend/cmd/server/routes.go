@@ -23,7 +23,19 @@ func SetupRoutes(mux *http.ServeMux, container *di.Container) {
 	container.AdminAuthHandler.RegisterRoutes(mux)
 	container.AdminDashboardHandler.RegisterRoutes(mux)
 	container.UserHandler.RegisterRoutes(mux)
+	container.SessionHandler.RegisterRoutes(mux)
+	container.APITokenHandler.RegisterRoutes(mux)
+	container.MFAHandler.RegisterRoutes(mux)
+	container.JWKSHandler.RegisterRoutes(mux)
 	container.UserAuthHandler.RegisterRoutes(mux)
+	container.RegistrationHandler.RegisterRoutes(mux)
+	container.OIDCHandler.RegisterRoutes(mux)
 	container.WorkspaceHandler.RegisterRoutes(mux)
 	container.RoleHandler.RegisterRoutes(mux)
+	container.InviteHandler.RegisterRoutes(mux)
+	container.BotHandler.RegisterRoutes(mux)
+	container.MessageHandler.RegisterRoutes(mux)
+	container.IncomingWebhookHandler.RegisterRoutes(mux)
+	container.OutgoingWebhookHandler.RegisterRoutes(mux)
+	container.RealtimeHandler.RegisterRoutes(mux)
 }
@@ -1,46 +1,68 @@
 package di
 
 import (
+	"backend/internal/eventbus"
 	"backend/internal/handlers"
+	"backend/internal/realtime"
 	"backend/internal/repos"
 	"backend/internal/services"
+	"backend/pkg/grouproles"
+	"backend/pkg/ldap"
+	"backend/pkg/oidc"
+	"backend/pkg/passwordpolicy"
 	"backend/pkg/ratelimiter"
 	"backend/pkg/utilities"
+	"backend/pkg/webhook"
 	"os"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Container struct {
-	AdminPanelPasswordHash []byte
 	AdminDashboardHandler  *handlers.AdminDashboardHandler
 	AdminAuthHandler       *handlers.AdminAuthHandler
+	AdminAuthService       *services.AdminAuthService
+	SecurityEventService   *services.SecurityEventService
 	UserAuthHandler 	   *handlers.UserAuthHandler
 	UserService            *services.UserService
 	UserRepo               *repos.UserRepo
 	UserHandler            *handlers.UserHandler
+	SessionHandler         *handlers.SessionHandler
+	APITokenHandler        *handlers.APITokenHandler
+	MFAHandler             *handlers.MFAHandler
+	MFAService             *services.MFAService
+	SettingsService        *services.SettingsService
+	JWKSHandler            *handlers.JWKSHandler
 	WorkspaceHandler       *handlers.WorkspaceHandler
+	WorkspaceService       *services.WorkspaceService
 	RoleHandler            *handlers.RoleHandler
 	RoleService            *services.RoleService
 	RoleRepo               *repos.RoleRepo
+	InviteHandler          *handlers.InviteHandler
+	InviteService          *services.InviteService
+	BotHandler             *handlers.BotHandler
+	RegistrationHandler    *handlers.RegistrationHandler
+	OIDCHandler            *handlers.OIDCHandler
+	SSOService             *services.SSOService
+	// LDAPAuthenticator is nil unless a directory is configured
+	LDAPAuthenticator      *services.LDAPAuthenticator
+	MessageHandler         *handlers.MessageHandler
+	MessageService         *services.MessageService
+	MessageRepo            *repos.MessageRepo
+	IncomingWebhookHandler *handlers.IncomingWebhookHandler
+	OutgoingWebhookHandler *handlers.OutgoingWebhookHandler
+	OutgoingWebhookService *services.OutgoingWebhookService
+	EventBus               eventbus.Bus
+	RealtimeHub            *realtime.Hub
+	RealtimeHandler        *handlers.RealtimeHandler
 	DefaultLimiter         ratelimiter.RateLimiter
 	DB                     *pgxpool.Pool
 	SessionStore           utilities.SessionStore
 }
 
-func NewContainer(db *pgxpool.Pool) *Container {
-	panelPassword := os.Getenv("ADMIN_PANEL_PASSWORD")
-	if panelPassword == "" {
-		panic("please set an admin panel password for security reasons")
-	}
-	adminPanelPasswordHash, err := bcrypt.GenerateFromPassword([]byte(panelPassword), bcrypt.DefaultCost)
-	if err != nil {
-		panic("failed to generate password hash")
-	}
-
+func NewContainer(db *pgxpool.Pool, keyRing *utilities.KeyRing) *Container {
 	// Redis connection
 	redisHost := os.Getenv("REDIS_HOST")
 	if redisHost == "" {
@@ -58,7 +80,7 @@ func NewContainer(db *pgxpool.Pool) *Container {
 		DB:       0,
 	})
 
-	sessionStore := utilities.NewRedisSessionStore(redisClient)
+	var sessionStore utilities.SessionStore = utilities.NewRedisSessionStore(redisClient)
 	
 	authLimiter, err := ratelimiter.NewRedisRateLimiter(redisClient, 1*time.Minute, 5)
 	if err != nil {
@@ -69,34 +91,147 @@ func NewContainer(db *pgxpool.Pool) *Container {
 		panic("failed to create rate limiter: " + err.Error())
 	}
 	userRepo := repos.NewUserRepo(db)
-	userService := services.NewUserService(userRepo)
+	securityEventService := services.NewSecurityEventService(repos.NewSecurityEventRepo(db))
+	// Personal access tokens are accepted wherever a session's access token is
+	apiTokenService := services.NewAPITokenService(repos.NewAPITokenRepo(db), userRepo, securityEventService)
+	sessionStore = utilities.WithAPITokens(sessionStore, apiTokenService)
+	// Five failed logins for a username within 15 minutes lock it for a minute,
+	// doubling with every further lockout up to an hour
+	userLockout := ratelimiter.NewRedisBackoffLockout(redisClient, 5, 15*time.Minute, time.Minute, time.Hour)
+	passwordPolicy, err := passwordpolicy.FromEnv()
+	if err != nil {
+		panic("failed to load password policy: " + err.Error())
+	}
+	workspaceRepo := repos.NewWorkspaceRepo(db)
+	roleRepo := repos.NewRoleRepo(db)
+	groupRoleSync := services.NewGroupRoleSync(workspaceRepo, roleRepo)
+	permissionChecker := utilities.NewPermissionChecker(userRepo)
+	eventBus := eventbus.NewRedisBus(redisClient, eventbus.DefaultStreamKey, 10000)
+	realtimeHub := realtime.NewHub(eventBus, realtime.NewWorkspaceAuthorizer(workspaceRepo, permissionChecker))
+	messageRepo := repos.NewMessageRepo(db)
+	// Deliveries to internal addresses are refused unless explicitly allowed, e.g. in development
+	webhookClient := webhook.NewClient(10*time.Second, os.Getenv("OUTGOING_WEBHOOK_ALLOW_PRIVATE") == "true")
+	outgoingWebhookService := services.NewOutgoingWebhookService(repos.NewOutgoingWebhookRepo(db), messageRepo, webhook.NewSender(webhookClient), webhook.DefaultRetryPolicy)
+	// Handlers' events reach WebSocket clients and any outgoing webhooks subscribed to them
+	events := realtime.Publishers{realtimeHub, outgoingWebhookService}
+
+	// Local passwords are checked first so directory outages never lock local users out
+	authenticators := []services.Authenticator{services.NewPasswordAuthenticator(userRepo)}
+	var ldapAuthenticator *services.LDAPAuthenticator
+	ldapConfig, err := ldap.DirectoryConfigFromEnv()
+	if err != nil {
+		panic("failed to load LDAP settings: " + err.Error())
+	}
+	if ldapConfig != nil {
+		groupRoles, err := grouproles.Parse(os.Getenv("LDAP_GROUP_ROLES"))
+		if err != nil {
+			panic("failed to parse LDAP_GROUP_ROLES: " + err.Error())
+		}
+		ldapAuthenticator = services.NewLDAPAuthenticator(ldap.NewDirectory(*ldapConfig), groupRoles, userRepo, groupRoleSync, events, securityEventService)
+		authenticators = append(authenticators, ldapAuthenticator)
+	}
+	userService := services.NewUserService(userRepo, passwordPolicy, userLockout, securityEventService, authenticators...)
 	userHandler := handlers.NewUserHandler(userService, sessionStore, limiter)
 	
-	permissionChecker := utilities.NewPermissionChecker(userService, userRepo)
-	
-	userAuthHandler := handlers.NewUserAuthHandler(userService, sessionStore, authLimiter)
+	settingsService := services.NewSettingsService(repos.NewSettingsRepo(db))
+	mfaService := services.NewMFAService(repos.NewMFARepo(db), userRepo, settingsService, userLockout, redisClient, os.Getenv("MFA_ISSUER"))
+	mfaHandler := handlers.NewMFAHandler(mfaService, sessionStore, limiter)
+
+	// Ten failed admin logins from one address within 15 minutes lock it out for 15 minutes
+	adminLockout := ratelimiter.NewRedisLockout(redisClient, 10, 15*time.Minute, 15*time.Minute)
+	adminAuthService := services.NewAdminAuthService(repos.NewAdminAccountRepo(db), passwordPolicy, adminLockout, securityEventService, os.Getenv("MFA_ISSUER"))
+
+	passwordResetService := services.NewPasswordResetService(userService, redisClient, securityEventService)
+	userAuthHandler := handlers.NewUserAuthHandler(userService, mfaService, passwordResetService, sessionStore, authLimiter)
 	
-	workspaceRepo := repos.NewWorkspaceRepo(db)
-	workspaceHandler := handlers.NewWorkspaceHandler(workspaceRepo, sessionStore, limiter, permissionChecker)
+	workspaceService := services.NewWorkspaceService(workspaceRepo, os.Getenv("DEFAULT_WORKSPACE_ROLE"))
+
+	realtimeHandler := handlers.NewRealtimeHandler(realtimeHub, sessionStore, limiter)
+
+	workspaceHandler := handlers.NewWorkspaceHandler(workspaceRepo, sessionStore, limiter, permissionChecker, events)
 	
-	roleRepo := repos.NewRoleRepo(db)
-	roleService := services.NewRoleService(roleRepo, userRepo)
-	roleHandler := handlers.NewRoleHandler(roleService, sessionStore, limiter, permissionChecker)
+	botRepo := repos.NewBotRepo(db)
+	roleService := services.NewRoleService(roleRepo, userRepo, botRepo)
+	roleHandler := handlers.NewRoleHandler(roleService, sessionStore, limiter, permissionChecker, events)
+
+	inviteService := services.NewInviteService(repos.NewInviteRepo(db), roleService, userRepo)
+	botService := services.NewBotService(botRepo, userRepo, roleService, apiTokenService)
+	botHandler := handlers.NewBotHandler(botService, sessionStore, limiter, permissionChecker, events)
+
+	inviteHandler := handlers.NewInviteHandler(inviteService, sessionStore, limiter, permissionChecker, events)
+
+	registrationService := services.NewRegistrationService(userService, userRepo, settingsService, inviteService, securityEventService)
+	registrationHandler := handlers.NewRegistrationHandler(registrationService, sessionStore, authLimiter, limiter, events)
+
+	// Single sign-on is only offered when an OpenID provider is configured
+	var ssoService *services.SSOService
+	oidcConfig, err := oidc.ConfigFromEnv()
+	if err != nil {
+		panic("failed to load OpenID Connect settings: " + err.Error())
+	}
+	if oidcConfig != nil {
+		groupRoles, err := grouproles.Parse(os.Getenv("OIDC_GROUP_ROLES"))
+		if err != nil {
+			panic("failed to parse OIDC_GROUP_ROLES: " + err.Error())
+		}
+		providerName := os.Getenv("OIDC_PROVIDER_NAME")
+		if providerName == "" {
+			providerName = "SSO"
+		}
+		ssoService = services.NewSSOService(oidc.NewProvider(*oidcConfig, nil), providerName, groupRoles, os.Getenv("OIDC_AUTO_PROVISION") == "true",
+			repos.NewIdentityRepo(db), userRepo, groupRoleSync, redisClient, securityEventService)
+	}
+	oidcHandler := handlers.NewOIDCHandler(ssoService, mfaService, sessionStore, authLimiter, events, os.Getenv("OIDC_FRONTEND_URL"))
+
+	messageService := services.NewMessageService(messageRepo)
+	messageHandler := handlers.NewMessageHandler(messageService, sessionStore, limiter, permissionChecker, events)
+
+	// Each incoming webhook may post 30 messages a minute
+	webhookLimiter, err := ratelimiter.NewRedisRateLimiter(redisClient, time.Minute, 30)
+	if err != nil {
+		panic("failed to create rate limiter: " + err.Error())
+	}
+	incomingWebhookService := services.NewIncomingWebhookService(repos.NewIncomingWebhookRepo(db), messageRepo)
+	incomingWebhookHandler := handlers.NewIncomingWebhookHandler(incomingWebhookService, sessionStore, limiter, webhookLimiter, permissionChecker, events)
 	
 	return &Container{
-		AdminPanelPasswordHash: adminPanelPasswordHash,
 		DB:                     db,
-		AdminDashboardHandler:  handlers.NewAdminDashboardHandler(sessionStore, limiter, adminPanelPasswordHash, userService, workspaceRepo),
-		AdminAuthHandler:       handlers.NewAdminAuthHandler(adminPanelPasswordHash, sessionStore, limiter),
+		AdminDashboardHandler:  handlers.NewAdminDashboardHandler(sessionStore, limiter, userService, workspaceRepo, workspaceService, settingsService, mfaService, adminAuthService, securityEventService, passwordResetService, events),
+		AdminAuthHandler:       handlers.NewAdminAuthHandler(adminAuthService, sessionStore, authLimiter),
+		AdminAuthService:       adminAuthService,
+		SecurityEventService:   securityEventService,
 		SessionStore:           sessionStore,
 		UserService:            userService,
 		UserHandler:            userHandler,
+		SessionHandler:         handlers.NewSessionHandler(sessionStore, limiter),
+		APITokenHandler:        handlers.NewAPITokenHandler(apiTokenService, sessionStore, limiter),
+		MFAHandler:             mfaHandler,
+		MFAService:             mfaService,
+		SettingsService:        settingsService,
+		JWKSHandler:            handlers.NewJWKSHandler(keyRing, limiter),
 		UserRepo:               userRepo,
 		UserAuthHandler:        userAuthHandler,
 		DefaultLimiter:         limiter,
 		WorkspaceHandler:       workspaceHandler,
+		WorkspaceService:       workspaceService,
 		RoleHandler:            roleHandler,
 		RoleService:            roleService,
 		RoleRepo:               roleRepo,
+		InviteHandler:          inviteHandler,
+		InviteService:          inviteService,
+		BotHandler:             botHandler,
+		RegistrationHandler:    registrationHandler,
+		OIDCHandler:            oidcHandler,
+		SSOService:             ssoService,
+		LDAPAuthenticator:      ldapAuthenticator,
+		MessageHandler:         messageHandler,
+		MessageService:         messageService,
+		MessageRepo:            messageRepo,
+		IncomingWebhookHandler: incomingWebhookHandler,
+		OutgoingWebhookHandler: handlers.NewOutgoingWebhookHandler(outgoingWebhookService, sessionStore, limiter, permissionChecker),
+		OutgoingWebhookService: outgoingWebhookService,
+		EventBus:               eventBus,
+		RealtimeHub:            realtimeHub,
+		RealtimeHandler:        realtimeHandler,
 	}
 }
@@ -0,0 +1,20 @@
+package eventbus
+
+import (
+	"backend/internal/models"
+	"context"
+)
+
+// Bus carries workspace events between backend replicas
+type Bus interface {
+	// Publish appends an event and returns the ID the bus assigned to it
+	Publish(ctx context.Context, event models.Event) (string, error)
+
+	// Subscribe streams every event published after the call, from any replica,
+	// until ctx is cancelled
+	Subscribe(ctx context.Context) (<-chan models.Event, error)
+
+	// Since returns up to limit events published after lastID.
+	// ok is false when lastID is unknown or has been trimmed from the bus.
+	Since(ctx context.Context, lastID string, limit int) (events []models.Event, ok bool, err error)
+}
@@ -0,0 +1,90 @@
+package eventbus
+
+import (
+	"backend/internal/models"
+	"context"
+	"strconv"
+	"sync"
+)
+
+// MemoryBus is an in-process Bus for tests and single-replica development
+type MemoryBus struct {
+	mu          sync.Mutex
+	seq         int
+	history     []models.Event
+	maxLen      int
+	subscribers map[chan models.Event]struct{}
+}
+
+func NewMemoryBus(maxLen int) *MemoryBus {
+	return &MemoryBus{
+		maxLen:      maxLen,
+		subscribers: make(map[chan models.Event]struct{}),
+	}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, event models.Event) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	event.ID = strconv.Itoa(b.seq)
+
+	b.history = append(b.history, event)
+	if b.maxLen > 0 && len(b.history) > b.maxLen {
+		b.history = b.history[len(b.history)-b.maxLen:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	return event.ID, nil
+}
+
+func (b *MemoryBus) Subscribe(ctx context.Context) (<-chan models.Event, error) {
+	ch := make(chan models.Event, 64)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		b.mu.Unlock()
+		close(ch)
+	}()
+
+	return ch, nil
+}
+
+// Subscribers reports how many subscriptions are active
+func (b *MemoryBus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
+
+func (b *MemoryBus) Since(ctx context.Context, lastID string, limit int) ([]models.Event, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, event := range b.history {
+		if event.ID != lastID {
+			continue
+		}
+		missed := b.history[i+1:]
+		if limit > 0 && len(missed) > limit {
+			missed = missed[:limit]
+		}
+		return append([]models.Event(nil), missed...), true, nil
+	}
+
+	return nil, false, nil
+}
@@ -0,0 +1,160 @@
+package eventbus
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultStreamKey = "events:workspaces"
+	eventField       = "event"
+	readBlock        = 5 * time.Second
+	retryDelay       = time.Second
+)
+
+// RedisBus shares events between replicas through a Redis Stream.
+// Stream entry IDs double as event IDs, so any replica can resume a client.
+type RedisBus struct {
+	rdb    *redis.Client
+	stream string
+	maxLen int64
+}
+
+func NewRedisBus(rdb *redis.Client, stream string, maxLen int64) *RedisBus {
+	if stream == "" {
+		stream = DefaultStreamKey
+	}
+	return &RedisBus{rdb: rdb, stream: stream, maxLen: maxLen}
+}
+
+func (b *RedisBus) Publish(ctx context.Context, event models.Event) (string, error) {
+	event.ID = ""
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	id, err := b.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.stream,
+		MaxLen: b.maxLen,
+		Approx: true,
+		Values: map[string]any{eventField: data},
+	}).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	return id, nil
+}
+
+func (b *RedisBus) Subscribe(ctx context.Context) (<-chan models.Event, error) {
+	// Resolve "$" once so no entry is skipped between blocking reads
+	lastID := "0-0"
+	entries, err := b.rdb.XRevRangeN(ctx, b.stream, "+", "-", 1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream head: %w", err)
+	}
+	if len(entries) > 0 {
+		lastID = entries[0].ID
+	}
+
+	ch := make(chan models.Event, 64)
+	go func() {
+		defer close(ch)
+		for {
+			streams, err := b.rdb.XRead(ctx, &redis.XReadArgs{
+				Streams: []string{b.stream, lastID},
+				Block:   readBlock,
+				Count:   100,
+			}).Result()
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			if err != nil {
+				log.Printf("eventbus: failed to read stream %s: %v", b.stream, err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(retryDelay):
+				}
+				continue
+			}
+
+			for _, stream := range streams {
+				for _, message := range stream.Messages {
+					lastID = message.ID
+					event, err := decodeMessage(message)
+					if err != nil {
+						log.Printf("eventbus: skipping malformed entry %s: %v", message.ID, err)
+						continue
+					}
+					select {
+					case ch <- event:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+func (b *RedisBus) Since(ctx context.Context, lastID string, limit int) ([]models.Event, bool, error) {
+	// The entry itself must still be in the stream, otherwise events may have been trimmed
+	anchor, err := b.rdb.XRangeN(ctx, b.stream, lastID, lastID, 1).Result()
+	if err != nil {
+		if isInvalidStreamID(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to look up event %s: %w", lastID, err)
+	}
+	if len(anchor) == 0 {
+		return nil, false, nil
+	}
+
+	messages, err := b.rdb.XRangeN(ctx, b.stream, "("+lastID, "+", int64(limit)).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read events after %s: %w", lastID, err)
+	}
+
+	events := make([]models.Event, 0, len(messages))
+	for _, message := range messages {
+		event, err := decodeMessage(message)
+		if err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events, true, nil
+}
+
+func decodeMessage(message redis.XMessage) (models.Event, error) {
+	var event models.Event
+	raw, ok := message.Values[eventField].(string)
+	if !ok {
+		return event, errors.New("missing event field")
+	}
+	if err := json.Unmarshal([]byte(raw), &event); err != nil {
+		return event, err
+	}
+	event.ID = message.ID
+	return event, nil
+}
+
+func isInvalidStreamID(err error) bool {
+	return strings.Contains(err.Error(), "Invalid stream ID")
+}
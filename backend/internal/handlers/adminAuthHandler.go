@@ -1,29 +1,28 @@
 package handlers
 
 import (
+	"backend/internal/services"
 	"backend/pkg/middleware"
 	"backend/pkg/ratelimiter"
 	"backend/pkg/utilities"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
-	"os"
+	"strconv"
 	"time"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
-const RefreshTokenCookieName = "refreshToken"
-
 type AdminAuthHandler struct {
-	adminPanelPasswordHash []byte
-	SessionStore           utilities.SessionStore
-	Limiter                ratelimiter.RateLimiter
+	adminAuthService *services.AdminAuthService
+	SessionStore     utilities.SessionStore
+	Limiter          ratelimiter.RateLimiter
 }
 
 // NewAdminAuthHandler creates a new instance of AdminAuthHandler
-func NewAdminAuthHandler(adminPanelPasswordHash []byte, sessionStore utilities.SessionStore, limiter ratelimiter.RateLimiter) *AdminAuthHandler {
-	return &AdminAuthHandler{adminPanelPasswordHash: adminPanelPasswordHash, SessionStore: sessionStore, Limiter: limiter}
+func NewAdminAuthHandler(adminAuthService *services.AdminAuthService, sessionStore utilities.SessionStore, limiter ratelimiter.RateLimiter) *AdminAuthHandler {
+	return &AdminAuthHandler{adminAuthService: adminAuthService, SessionStore: sessionStore, Limiter: limiter}
 }
 
 func (h *AdminAuthHandler) RegisterRoutes(router *http.ServeMux) {
@@ -34,9 +33,25 @@ func (h *AdminAuthHandler) RegisterRoutes(router *http.ServeMux) {
 		http.HandlerFunc(h.Login),
 		authChain...,
 	))
+	router.Handle("/api/auth/admin/totp/setup", middleware.Chain(
+		http.HandlerFunc(h.BeginTOTPSetup),
+		authChain...,
+	))
+	router.Handle("/api/auth/admin/totp/confirm", middleware.Chain(
+		http.HandlerFunc(h.ConfirmTOTPSetup),
+		authChain...,
+	))
 }
 
-// Login handles admin login requests
+// adminCredentials is the request body shared by the admin login and TOTP setup endpoints
+type adminCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// Login handles admin login requests. Accounts with TOTP get a 401 with totpRequired
+// set until the request includes a code.
 func (h *AdminAuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		fmt.Println("Invalid request method: " + r.Method)
@@ -45,54 +60,108 @@ func (h *AdminAuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		SecretKey string `json:"secretKey"`
-	}
+	var req adminCredentials
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
 		return
 	}
 
-	// Compare the hash from the container with the provided password
-	err := bcrypt.CompareHashAndPassword(h.adminPanelPasswordHash, []byte(req.SecretKey))
+	account, err := h.adminAuthService.Authenticate(r.Context(), clientInfo(r), req.Username, req.Password, req.Code)
 	if err != nil {
-		http.Error(w, `{"error": "Invalid secret password"}`, http.StatusUnauthorized)
+		writeAdminAuthError(w, err)
 		return
 	}
 
-	// --- Password is correct, generate tokens for the admin user ---
-	userId := AdminUserID // Use the predefined admin user ID
+	// --- Credentials are correct, generate tokens for the admin account ---
+	adminId := account.Id.String()
+
+	session, err := utilities.StartAdminSession(r.Context(), h.SessionStore, adminId, r.UserAgent(), utilities.ClientIP(r))
+	if err != nil {
+		http.Error(w, `{"error": "Could not create session"}`, http.StatusInternalServerError)
+		return
+	}
 
-	accessToken, err := utilities.GenerateAccessToken(r.Context(), h.SessionStore, userId)
+	accessToken, err := utilities.GenerateAccessToken(r.Context(), h.SessionStore, adminId, session.ID)
 	if err != nil {
 		http.Error(w, `{"error": "Could not generate access token"}`, http.StatusInternalServerError)
 		return
 	}
 
-	refreshToken, err := utilities.GenerateRefreshToken(r.Context(), h.SessionStore, userId)
+	refreshToken, err := utilities.GenerateRefreshToken(r.Context(), h.SessionStore, adminId, session.ID)
 	if err != nil {
 		http.Error(w, `{"error": "Could not generate refresh token"}`, http.StatusInternalServerError)
 		return
 	}
 
 	// Set the refresh token as a cookie
-	maxAge := 3600 * 24 * 30
-	sameSite := http.SameSiteLaxMode
-	if os.Getenv("DEV") != "" {
-		sameSite = http.SameSiteNoneMode
-	}
-	http.SetCookie(w, &http.Cookie{
-		Name:     RefreshTokenCookieName,
-		Value:    refreshToken,
-		Path:     "/",
-		MaxAge:   maxAge,
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: sameSite,
-	})
+	utilities.SetRefreshTokenCookie(w, refreshToken)
 
 	// Return the access token in the response body
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"accessToken": accessToken})
 }
+
+// BeginTOTPSetup returns a new secret for an admin account. It only works until a secret is confirmed.
+func (h *AdminAuthHandler) BeginTOTPSetup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req adminCredentials
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	enrollment, err := h.adminAuthService.BeginTOTPSetup(r.Context(), clientInfo(r), req.Username, req.Password)
+	if err != nil {
+		writeAdminAuthError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// ConfirmTOTPSetup enables the TOTP requirement after checking a code from the new secret
+func (h *AdminAuthHandler) ConfirmTOTPSetup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error": "Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req adminCredentials
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.adminAuthService.ConfirmTOTPSetup(r.Context(), clientInfo(r), req.Username, req.Password, req.Code); err != nil {
+		writeAdminAuthError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeAdminAuthError(w http.ResponseWriter, err error) {
+	var lockoutErr *services.LockoutError
+	switch {
+	case errors.As(err, &lockoutErr):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockoutErr.RetryAfter.Seconds()))))
+		http.Error(w, `{"error": "Too many failed attempts. Please try again later."}`, http.StatusTooManyRequests)
+	case errors.Is(err, services.ErrInvalidAdminCredentials):
+		http.Error(w, `{"error": "Invalid credentials"}`, http.StatusUnauthorized)
+	case errors.Is(err, services.ErrAdminTOTPCodeRequired):
+		http.Error(w, `{"error": "Two-factor code required", "totpRequired": true}`, http.StatusUnauthorized)
+	case errors.Is(err, services.ErrAdminTOTPAlreadyConfigured):
+		http.Error(w, `{"error": "Two-factor authentication is already configured"}`, http.StatusConflict)
+	case errors.Is(err, services.ErrAdminTOTPSetupNotFound):
+		http.Error(w, `{"error": "No two-factor setup in progress"}`, http.StatusConflict)
+	default:
+		fmt.Println("Admin auth error:", err)
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+	}
+}
@@ -1,33 +1,40 @@
 package handlers
 
 import (
+	"backend/internal/models"
+	"backend/internal/realtime"
 	"backend/internal/repos"
 	"backend/internal/services"
 	"backend/pkg/middleware"
+	"backend/pkg/passwordpolicy"
 	"backend/pkg/ratelimiter"
 	"backend/pkg/utilities"
 	"encoding/json"
+	"errors"
 	"fmt"
 	_ "image/jpeg"
 	_ "image/png"
 	"net/http"
+	"strconv"
 	"time"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
-const AdminUserID = "admin"
-
 type AdminDashboardHandler struct {
 	SessionStore           utilities.SessionStore
 	Limiter                ratelimiter.RateLimiter
-	adminPanelPasswordHash []byte
 	userService            *services.UserService
 	workspaceRepo          *repos.WorkspaceRepo
+	workspaceService       *services.WorkspaceService
+	settingsService        *services.SettingsService
+	mfaService             *services.MFAService
+	adminAuthService       *services.AdminAuthService
+	securityEventService   *services.SecurityEventService
+	passwordResetService   *services.PasswordResetService
+	events                 realtime.Publisher
 }
 
-func NewAdminDashboardHandler(SessionStore utilities.SessionStore, Limiter ratelimiter.RateLimiter, adminPanelPasswordHash []byte, userService *services.UserService, workspaceRepo *repos.WorkspaceRepo) *AdminDashboardHandler {
-	return &AdminDashboardHandler{SessionStore: SessionStore, Limiter: Limiter, adminPanelPasswordHash: adminPanelPasswordHash, userService: userService, workspaceRepo: workspaceRepo}
+func NewAdminDashboardHandler(SessionStore utilities.SessionStore, Limiter ratelimiter.RateLimiter, userService *services.UserService, workspaceRepo *repos.WorkspaceRepo, workspaceService *services.WorkspaceService, settingsService *services.SettingsService, mfaService *services.MFAService, adminAuthService *services.AdminAuthService, securityEventService *services.SecurityEventService, passwordResetService *services.PasswordResetService, events realtime.Publisher) *AdminDashboardHandler {
+	return &AdminDashboardHandler{SessionStore: SessionStore, Limiter: Limiter, userService: userService, workspaceRepo: workspaceRepo, workspaceService: workspaceService, settingsService: settingsService, mfaService: mfaService, adminAuthService: adminAuthService, securityEventService: securityEventService, passwordResetService: passwordResetService, events: events}
 }
 
 func (h *AdminDashboardHandler) RegisterRoutes(router *http.ServeMux) {
@@ -49,6 +56,46 @@ func (h *AdminDashboardHandler) RegisterRoutes(router *http.ServeMux) {
 		adminStack...,
 	))
 
+	router.Handle("/api/admin/users/{userId}/sessions", middleware.Chain(
+		http.HandlerFunc(h.UserSessions),
+		adminStack...,
+	))
+
+	router.Handle("/api/admin/users/{userId}/mfa", middleware.Chain(
+		http.HandlerFunc(h.ResetUserMFA),
+		adminStack...,
+	))
+
+	router.Handle("/api/admin/users/{userId}/password-reset", middleware.Chain(
+		http.HandlerFunc(h.IssuePasswordReset),
+		adminStack...,
+	))
+
+	router.Handle("/api/admin/settings", middleware.Chain(
+		http.HandlerFunc(h.InstanceSettings),
+		adminStack...,
+	))
+
+	router.Handle("/api/admin/account", middleware.Chain(
+		http.HandlerFunc(h.GetCurrentAdminAccount),
+		adminStack...,
+	))
+
+	router.Handle("/api/admin/accounts", middleware.Chain(
+		http.HandlerFunc(h.AdminAccounts),
+		adminStack...,
+	))
+
+	router.Handle("/api/admin/accounts/{adminId}", middleware.Chain(
+		http.HandlerFunc(h.DisableAdminAccount),
+		adminStack...,
+	))
+
+	router.Handle("/api/admin/security-events", middleware.Chain(
+		http.HandlerFunc(h.GetSecurityEvents),
+		adminStack...,
+	))
+
 	router.Handle("/api/admin/create-workspace", middleware.Chain(
 		http.HandlerFunc(h.CreateWorkspace),
 		adminStack...,
@@ -76,8 +123,7 @@ func (h *AdminDashboardHandler) GetDashboardData(w http.ResponseWriter, r *http.
 		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
 		return
 	}
-	userIDFromContext, _ := r.Context().Value(utilities.UserIDKey).(string)
-	if userIDFromContext != AdminUserID {
+	if !utilities.IsInstanceAdmin(r.Context()) {
 		w.WriteHeader(http.StatusForbidden)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Internal server error"})
 		return
@@ -94,8 +140,7 @@ func (h *AdminDashboardHandler) CreateNewUser(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	userIDFromContext, _ := r.Context().Value(utilities.UserIDKey).(string)
-	if userIDFromContext != AdminUserID {
+	if !utilities.IsInstanceAdmin(r.Context()) {
 		w.WriteHeader(http.StatusForbidden)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Forbidden"})
 		return
@@ -112,15 +157,23 @@ func (h *AdminDashboardHandler) CreateNewUser(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(credentials.Password), bcrypt.DefaultCost)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Internal server error"})
-		return
-	}
-
-	user, err := h.userService.CreateUser(r.Context(), credentials.Username, string(hashedPassword))
+	user, err := h.userService.CreateUser(r.Context(), credentials.Username, credentials.Password)
 	if err != nil {
+		var validationErr *passwordpolicy.ValidationError
+		if errors.As(err, &validationErr) {
+			writePasswordPolicyError(w, validationErr)
+			return
+		}
+		if errors.Is(err, services.ErrInvalidUsername) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, repos.ErrUsernameTaken) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Internal server error"})
 		return
@@ -136,8 +189,7 @@ func (h *AdminDashboardHandler) GetAllUsers(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	userId, _ := r.Context().Value(utilities.UserIDKey).(string)
-	if userId != AdminUserID {
+	if !utilities.IsInstanceAdmin(r.Context()) {
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
@@ -151,14 +203,322 @@ func (h *AdminDashboardHandler) GetAllUsers(w http.ResponseWriter, r *http.Reque
 	json.NewEncoder(w).Encode(users)
 }
 
+// UserSessions lists a user's active sessions (GET) or force-logs them out of every device (DELETE)
+func (h *AdminDashboardHandler) UserSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !utilities.IsInstanceAdmin(r.Context()) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	targetUserID := r.PathValue("userId")
+	if targetUserID == "" {
+		http.Error(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		sessions, err := h.SessionStore.ListSessions(r.Context(), targetUserID)
+		if err != nil {
+			fmt.Println("Unable to list sessions:", err)
+			http.Error(w, "Unable to list sessions", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sessions)
+		return
+	}
+
+	revoked, err := utilities.RevokeAllUserSessions(r.Context(), h.SessionStore, targetUserID)
+	if err != nil {
+		fmt.Println("Unable to revoke sessions:", err)
+		http.Error(w, "Unable to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"revoked": revoked})
+}
+
+// ResetUserMFA removes a user's second factor so they can enroll again
+func (h *AdminDashboardHandler) ResetUserMFA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !utilities.IsInstanceAdmin(r.Context()) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	targetUserID := r.PathValue("userId")
+	if targetUserID == "" {
+		http.Error(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.mfaService.Reset(r.Context(), targetUserID); err != nil {
+		fmt.Println("Unable to reset two-factor authentication:", err)
+		http.Error(w, "Unable to reset two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// IssuePasswordReset creates a single-use link token for a user who forgot their
+// password. The token is only returned here, for the admin to pass on.
+func (h *AdminDashboardHandler) IssuePasswordReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !utilities.IsInstanceAdmin(r.Context()) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	targetUserID := r.PathValue("userId")
+	if targetUserID == "" {
+		http.Error(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	adminId, _ := utilities.GetUserID(r.Context())
+	reset, err := h.passwordResetService.IssueToken(r.Context(), targetUserID, adminId, clientInfo(r))
+	if err != nil {
+		if errors.Is(err, repos.ErrUserNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, services.ErrExternalAccount) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		fmt.Println("Unable to issue password reset:", err)
+		http.Error(w, "Unable to issue password reset", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(reset)
+}
+
+// InstanceSettings returns (GET) or partially updates (PUT) the instance-wide settings
+func (h *AdminDashboardHandler) InstanceSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !utilities.IsInstanceAdmin(r.Context()) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	var settings *models.InstanceSettings
+	var err error
+	if r.Method == http.MethodGet {
+		settings, err = h.settingsService.GetSettings(r.Context())
+	} else {
+		var req models.UpdateInstanceSettingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		settings, err = h.settingsService.UpdateSettings(r.Context(), req)
+	}
+	if errors.Is(err, services.ErrInvalidRegistrationMode) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		fmt.Println("Unable to access instance settings:", err)
+		http.Error(w, "Unable to access instance settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// GetCurrentAdminAccount returns the admin account the request was made with
+func (h *AdminDashboardHandler) GetCurrentAdminAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !utilities.IsInstanceAdmin(r.Context()) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	adminId, _ := utilities.GetUserID(r.Context())
+	account, err := h.adminAuthService.GetActiveAccount(r.Context(), adminId)
+	if err != nil {
+		if errors.Is(err, repos.ErrAdminAccountNotFound) {
+			http.Error(w, "Admin account not found", http.StatusNotFound)
+			return
+		}
+		fmt.Println("Unable to get admin account:", err)
+		http.Error(w, "Unable to get admin account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+// AdminAccounts lists the admin accounts (GET) or creates a new one (POST)
+func (h *AdminDashboardHandler) AdminAccounts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !utilities.IsInstanceAdmin(r.Context()) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		accounts, err := h.adminAuthService.ListAccounts(r.Context())
+		if err != nil {
+			fmt.Println("Unable to list admin accounts:", err)
+			http.Error(w, "Unable to list admin accounts", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(accounts)
+		return
+	}
+
+	var req models.CreateAdminAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	adminId, _ := utilities.GetUserID(r.Context())
+	account, err := h.adminAuthService.CreateAccount(r.Context(), req.Username, req.Password, adminId)
+	if err != nil {
+		var validationErr *passwordpolicy.ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			writePasswordPolicyError(w, validationErr)
+		case errors.Is(err, services.ErrInvalidAdminUsername):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, repos.ErrAdminUsernameTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			fmt.Println("Unable to create admin account:", err)
+			http.Error(w, "Unable to create admin account", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(account)
+}
+
+// DisableAdminAccount stops another admin from signing in and ends their sessions
+func (h *AdminDashboardHandler) DisableAdminAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !utilities.IsInstanceAdmin(r.Context()) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	targetAdminID := r.PathValue("adminId")
+	adminId, _ := utilities.GetUserID(r.Context())
+	if err := h.adminAuthService.DisableAccount(r.Context(), targetAdminID, adminId); err != nil {
+		switch {
+		case errors.Is(err, repos.ErrAdminAccountNotFound):
+			http.Error(w, "Admin account not found", http.StatusNotFound)
+		case errors.Is(err, services.ErrCannotDisableSelf), errors.Is(err, services.ErrLastAdminAccount):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			fmt.Println("Unable to disable admin account:", err)
+			http.Error(w, "Unable to disable admin account", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if _, err := utilities.RevokeAllUserSessions(r.Context(), h.SessionStore, targetAdminID); err != nil {
+		fmt.Println("Unable to revoke admin sessions:", err)
+		http.Error(w, "Unable to revoke admin sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSecurityEvents lists recent authentication events, newest first. It accepts the
+// optional query parameters type, username, before (an event ID) and limit.
+func (h *AdminDashboardHandler) GetSecurityEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !utilities.IsInstanceAdmin(r.Context()) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.SecurityEventFilter{
+		Type:     query.Get("type"),
+		Username: query.Get("username"),
+	}
+	if before := query.Get("before"); before != "" {
+		beforeID, err := strconv.ParseInt(before, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid before parameter", http.StatusBadRequest)
+			return
+		}
+		filter.BeforeID = beforeID
+	}
+	if limit := query.Get("limit"); limit != "" {
+		parsedLimit, err := strconv.Atoi(limit)
+		if err != nil {
+			http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = parsedLimit
+	}
+
+	events, err := h.securityEventService.List(r.Context(), filter)
+	if err != nil {
+		fmt.Println("Unable to list security events:", err)
+		http.Error(w, "Unable to list security events", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
 func (h *AdminDashboardHandler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
-	userId, _ := r.Context().Value(utilities.UserIDKey).(string)
-	if userId != AdminUserID {
+	if !utilities.IsInstanceAdmin(r.Context()) {
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
@@ -183,8 +543,7 @@ func (h *AdminDashboardHandler) GetWorkspaces(w http.ResponseWriter, r *http.Req
 		return
 	}
 	ctx := r.Context()
-	userId, _ := utilities.GetUserID(ctx)
-	if userId != AdminUserID {
+	if !utilities.IsInstanceAdmin(ctx) {
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
@@ -207,8 +566,7 @@ func (h *AdminDashboardHandler) GetWorkspace(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	userId, _ := r.Context().Value(utilities.UserIDKey).(string)
-	if userId != AdminUserID {
+	if !utilities.IsInstanceAdmin(r.Context()) {
 		fmt.Println("Forbidden")
 		w.WriteHeader(http.StatusForbidden)
 		return
@@ -240,8 +598,7 @@ func (h *AdminDashboardHandler) AddUserToWorkspace(w http.ResponseWriter, r *htt
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	userId, _ := r.Context().Value(utilities.UserIDKey).(string)
-	if userId != AdminUserID {
+	if !utilities.IsInstanceAdmin(r.Context()) {
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
@@ -253,17 +610,26 @@ func (h *AdminDashboardHandler) AddUserToWorkspace(w http.ResponseWriter, r *htt
 		return
 	}
 
-	userId = r.PathValue("userId")
+	userId := r.PathValue("userId")
 	fmt.Println("User ID:", userId)
 	if userId == "" {
 		http.Error(w, "User ID is required", http.StatusBadRequest)
 		return
 	}
 
-	err := h.workspaceRepo.AddUserToWorkspace(r.Context(), userId, workspaceID)
+	added, err := h.workspaceService.AddUserToWorkspace(r.Context(), userId, workspaceID)
 	if err != nil {
+		fmt.Println("Unable to add user to workspace:", err)
 		http.Error(w, "Unable to add user to workspace", http.StatusInternalServerError)
 		return
 	}
+	if !added {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	event := realtime.NewEvent(models.EventMemberAdded, workspaceID, map[string]string{"user_id": userId, "workspace_id": workspaceID})
+	event.SubjectUserID = userId
+	h.events.Publish(r.Context(), event)
 	w.WriteHeader(http.StatusOK)
 }
\ No newline at end of file
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/repos"
+	"backend/internal/services"
+	"backend/pkg/middleware"
+	"backend/pkg/ratelimiter"
+	"backend/pkg/utilities"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type APITokenHandler struct {
+	apiTokenService *services.APITokenService
+	store           utilities.SessionStore
+	limiter         ratelimiter.RateLimiter
+}
+
+func NewAPITokenHandler(apiTokenService *services.APITokenService, store utilities.SessionStore, limiter ratelimiter.RateLimiter) *APITokenHandler {
+	return &APITokenHandler{apiTokenService: apiTokenService, store: store, limiter: limiter}
+}
+
+// RegisterRoutes adds the token management routes. They are only reachable from a
+// browser session: no token scope covers them.
+func (h *APITokenHandler) RegisterRoutes(router *http.ServeMux) {
+	stack := []middleware.Middleware{
+		middleware.TokenAuthMiddleware(h.store),
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "user_api_tokens"),
+	}
+
+	router.Handle("/api/user/tokens", middleware.Chain(
+		http.HandlerFunc(h.Tokens),
+		stack...,
+	))
+	router.Handle("/api/user/tokens/{tokenId}", middleware.Chain(
+		http.HandlerFunc(h.RevokeToken),
+		stack...,
+	))
+}
+
+// Tokens lists the user's personal access tokens (GET) or creates one (POST)
+func (h *APITokenHandler) Tokens(w http.ResponseWriter, r *http.Request) {
+	userID, ok := utilities.GetUserID(r.Context())
+	if !ok || utilities.IsInstanceAdmin(r.Context()) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		tokens, err := h.apiTokenService.ListTokens(r.Context(), userID)
+		if err != nil {
+			fmt.Println("Error listing API tokens:", err)
+			http.Error(w, "Failed to list tokens", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokens)
+	case http.MethodPost:
+		var req models.CreateAPITokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		token, err := h.apiTokenService.CreateToken(r.Context(), userID, req, clientInfo(r))
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidAPITokenName),
+				errors.Is(err, services.ErrInvalidAPITokenScopes),
+				errors.Is(err, services.ErrInvalidAPITokenExpiry):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, services.ErrTooManyAPITokens):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				fmt.Println("Error creating API token:", err)
+				http.Error(w, "Failed to create token", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(token)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *APITokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := utilities.GetUserID(r.Context())
+	if !ok || utilities.IsInstanceAdmin(r.Context()) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err := h.apiTokenService.RevokeToken(r.Context(), userID, r.PathValue("tokenId"), clientInfo(r))
+	if err != nil {
+		if errors.Is(err, repos.ErrAPITokenNotFound) {
+			http.Error(w, "Token not found", http.StatusNotFound)
+			return
+		}
+		fmt.Println("Error revoking API token:", err)
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
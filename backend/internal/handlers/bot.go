@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"backend/internal/repos"
+	"backend/internal/services"
+	"backend/pkg/middleware"
+	"backend/pkg/ratelimiter"
+	"backend/pkg/utilities"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type BotHandler struct {
+	botService        *services.BotService
+	store             utilities.SessionStore
+	limiter           ratelimiter.RateLimiter
+	permissionChecker *utilities.PermissionChecker
+	events            realtime.Publisher
+}
+
+func NewBotHandler(botService *services.BotService, store utilities.SessionStore, limiter ratelimiter.RateLimiter, permissionChecker *utilities.PermissionChecker, events realtime.Publisher) *BotHandler {
+	return &BotHandler{
+		botService:        botService,
+		store:             store,
+		limiter:           limiter,
+		permissionChecker: permissionChecker,
+		events:            events,
+	}
+}
+
+func (h *BotHandler) RegisterRoutes(router *http.ServeMux) {
+	manageStack := []middleware.Middleware{
+		middleware.TokenAuthMiddleware(h.store),
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "workspace_bots"),
+		middleware.PermissionMiddleware(h.permissionChecker, "workspace:manage-users"),
+	}
+
+	router.Handle("/api/workspaces/{workspaceId}/bots", middleware.Chain(
+		http.HandlerFunc(h.Bots),
+		manageStack...,
+	))
+	router.Handle("/api/workspaces/{workspaceId}/bots/{botId}", middleware.Chain(
+		http.HandlerFunc(h.DeactivateBot),
+		manageStack...,
+	))
+	router.Handle("/api/workspaces/{workspaceId}/bots/{botId}/tokens", middleware.Chain(
+		http.HandlerFunc(h.Tokens),
+		manageStack...,
+	))
+	router.Handle("/api/workspaces/{workspaceId}/bots/{botId}/tokens/{tokenId}", middleware.Chain(
+		http.HandlerFunc(h.RevokeToken),
+		manageStack...,
+	))
+}
+
+// Bots lists the workspace's bots (GET) or creates one (POST)
+func (h *BotHandler) Bots(w http.ResponseWriter, r *http.Request) {
+	workspaceID := r.PathValue("workspaceId")
+
+	switch r.Method {
+	case http.MethodGet:
+		bots, err := h.botService.ListBots(r.Context(), workspaceID)
+		if err != nil {
+			fmt.Println("Error listing bots:", err)
+			http.Error(w, "Failed to list bots", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(bots)
+	case http.MethodPost:
+		userID, ok := utilities.GetUserID(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req models.CreateBotRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		bot, assignments, err := h.botService.CreateBot(r.Context(), workspaceID, userID, utilities.IsInstanceAdmin(r.Context()), req)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidUsername),
+				errors.Is(err, services.ErrInvalidBotDescription),
+				errors.Is(err, services.ErrInvalidRoleID),
+				errors.Is(err, services.ErrRoleNotFound):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, repos.ErrUsernameTaken):
+				http.Error(w, err.Error(), http.StatusConflict)
+			case errors.Is(err, services.ErrRoleNotGrantable):
+				http.Error(w, err.Error(), http.StatusForbidden)
+			default:
+				fmt.Println("Error creating bot:", err)
+				http.Error(w, "Failed to create bot", http.StatusInternalServerError)
+			}
+			return
+		}
+		h.publishBotJoined(r, workspaceID, bot, assignments)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(bot)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *BotHandler) publishBotJoined(r *http.Request, workspaceID string, bot *models.Bot, assignments []*models.WorkspaceUserRole) {
+	botID := bot.ID.String()
+	event := realtime.NewEvent(models.EventMemberAdded, workspaceID, map[string]string{"user_id": botID, "workspace_id": workspaceID})
+	event.SubjectUserID = botID
+	h.events.Publish(r.Context(), event)
+
+	for _, assignment := range assignments {
+		event := realtime.NewEvent(models.EventRoleAssigned, workspaceID, assignment)
+		event.SubjectUserID = botID
+		h.events.Publish(r.Context(), event)
+	}
+}
+
+// DeactivateBot removes the bot from the workspace and revokes its tokens
+func (h *BotHandler) DeactivateBot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	workspaceID, botID := r.PathValue("workspaceId"), r.PathValue("botId")
+	if err := h.botService.DeactivateBot(r.Context(), workspaceID, botID); err != nil {
+		writeBotError(w, err)
+		return
+	}
+
+	event := realtime.NewEvent(models.EventMemberRemoved, workspaceID, map[string]string{"user_id": botID, "workspace_id": workspaceID})
+	event.SubjectUserID = botID
+	h.events.Publish(r.Context(), event)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Tokens lists the bot's API tokens (GET) or creates one (POST)
+func (h *BotHandler) Tokens(w http.ResponseWriter, r *http.Request) {
+	workspaceID, botID := r.PathValue("workspaceId"), r.PathValue("botId")
+
+	switch r.Method {
+	case http.MethodGet:
+		tokens, err := h.botService.ListTokens(r.Context(), workspaceID, botID)
+		if err != nil {
+			writeBotError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokens)
+	case http.MethodPost:
+		var req models.CreateAPITokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		token, err := h.botService.CreateToken(r.Context(), workspaceID, botID, req, clientInfo(r))
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidAPITokenName),
+				errors.Is(err, services.ErrInvalidAPITokenScopes),
+				errors.Is(err, services.ErrInvalidAPITokenExpiry):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, services.ErrTooManyAPITokens):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				writeBotError(w, err)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(token)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *BotHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := h.botService.RevokeToken(r.Context(), r.PathValue("workspaceId"), r.PathValue("botId"), r.PathValue("tokenId"), clientInfo(r))
+	if err != nil {
+		if errors.Is(err, repos.ErrAPITokenNotFound) {
+			http.Error(w, "Token not found", http.StatusNotFound)
+			return
+		}
+		writeBotError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeBotError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repos.ErrBotNotFound) {
+		http.Error(w, "Bot not found", http.StatusNotFound)
+		return
+	}
+	fmt.Println("Bot error:", err)
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+}
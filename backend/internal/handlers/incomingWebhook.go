@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"backend/internal/repos"
+	"backend/internal/services"
+	"backend/pkg/middleware"
+	"backend/pkg/ratelimiter"
+	"backend/pkg/utilities"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// maxIncomingWebhookBody caps payloads well above what the message and attachment
+// limits allow
+const maxIncomingWebhookBody = 256 << 10
+
+type IncomingWebhookHandler struct {
+	webhookService    *services.IncomingWebhookService
+	store             utilities.SessionStore
+	limiter           ratelimiter.RateLimiter
+	webhookLimiter    ratelimiter.RateLimiter
+	permissionChecker *utilities.PermissionChecker
+	events            realtime.Publisher
+}
+
+// NewIncomingWebhookHandler limits posts per webhook with webhookLimiter, so one noisy
+// integration cannot exhaust the limit of others calling from the same address
+func NewIncomingWebhookHandler(webhookService *services.IncomingWebhookService, store utilities.SessionStore, limiter ratelimiter.RateLimiter, webhookLimiter ratelimiter.RateLimiter, permissionChecker *utilities.PermissionChecker, events realtime.Publisher) *IncomingWebhookHandler {
+	return &IncomingWebhookHandler{
+		webhookService:    webhookService,
+		store:             store,
+		limiter:           limiter,
+		webhookLimiter:    webhookLimiter,
+		permissionChecker: permissionChecker,
+		events:            events,
+	}
+}
+
+func (h *IncomingWebhookHandler) RegisterRoutes(router *http.ServeMux) {
+	manageStack := []middleware.Middleware{
+		middleware.TokenAuthMiddleware(h.store),
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "incoming_webhooks"),
+		middleware.PermissionMiddleware(h.permissionChecker, "workspace:manage-channels"),
+	}
+
+	router.Handle("/api/workspaces/{workspaceId}/channels/{channelId}/webhooks", middleware.Chain(
+		http.HandlerFunc(h.Webhooks),
+		manageStack...,
+	))
+	router.Handle("/api/workspaces/{workspaceId}/channels/{channelId}/webhooks/{webhookId}", middleware.Chain(
+		http.HandlerFunc(h.DeleteWebhook),
+		manageStack...,
+	))
+
+	// The token in the URL is the only credential
+	router.Handle(services.IncomingWebhookPath+"{token}", middleware.Chain(
+		http.HandlerFunc(h.Post),
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "incoming_webhook_post"),
+	))
+}
+
+// Webhooks lists the channel's incoming webhooks (GET) or creates one (POST)
+func (h *IncomingWebhookHandler) Webhooks(w http.ResponseWriter, r *http.Request) {
+	workspaceID, channelID, ok := messagePathParams(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		webhooks, err := h.webhookService.ListWebhooks(r.Context(), workspaceID, channelID)
+		if err != nil {
+			writeIncomingWebhookError(w, err, "Failed to list webhooks")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(webhooks)
+	case http.MethodPost:
+		userID, ok := utilities.GetUserID(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req models.CreateIncomingWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		webhook, err := h.webhookService.CreateWebhook(r.Context(), workspaceID, channelID, userID, utilities.IsInstanceAdmin(r.Context()), req)
+		if err != nil {
+			writeIncomingWebhookError(w, err, "Failed to create webhook")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(webhook)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *IncomingWebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	workspaceID, channelID, ok := messagePathParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(r.Context(), workspaceID, channelID, r.PathValue("webhookId")); err != nil {
+		writeIncomingWebhookError(w, err, "Failed to delete webhook")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Post stores a payload sent to a webhook's secret URL as a message in its channel
+func (h *IncomingWebhookHandler) Post(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	webhook, err := h.webhookService.Authenticate(r.Context(), r.PathValue("token"))
+	if err != nil {
+		writeIncomingWebhookError(w, err, "Failed to post message")
+		return
+	}
+
+	allowed, err := h.webhookLimiter.Allow(r.Context(), "rate_limit:incoming_webhook:"+webhook.ID.String(), time.Minute)
+	if err != nil {
+		fmt.Println("Incoming webhook rate limit error:", err)
+		http.Error(w, "Rate limit error", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "Too many requests. Please try again later.", http.StatusTooManyRequests)
+		return
+	}
+
+	var payload models.IncomingWebhookPayload
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxIncomingWebhookBody)).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.webhookService.Post(r.Context(), webhook, payload)
+	if err != nil {
+		writeIncomingWebhookError(w, err, "Failed to post message")
+		return
+	}
+
+	h.events.Publish(r.Context(), realtime.NewEvent(models.EventMessageCreated, webhook.WorkspaceID.String(), message))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+}
+
+func writeIncomingWebhookError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhookName),
+		errors.Is(err, services.ErrInvalidWebhookDisplayName),
+		errors.Is(err, services.ErrTooManyAttachments),
+		errors.Is(err, services.ErrInvalidAttachment),
+		errors.Is(err, services.ErrEmptyMessage),
+		errors.Is(err, services.ErrMessageTooLong):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrTooManyIncomingWebhooks):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrChannelNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repos.ErrIncomingWebhookNotFound):
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+	default:
+		fmt.Println(fallback+":", err)
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
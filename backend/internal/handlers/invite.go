@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"backend/internal/repos"
+	"backend/internal/services"
+	"backend/pkg/middleware"
+	"backend/pkg/ratelimiter"
+	"backend/pkg/utilities"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type InviteHandler struct {
+	inviteService     *services.InviteService
+	store             utilities.SessionStore
+	limiter           ratelimiter.RateLimiter
+	permissionChecker *utilities.PermissionChecker
+	events            realtime.Publisher
+}
+
+func NewInviteHandler(inviteService *services.InviteService, store utilities.SessionStore, limiter ratelimiter.RateLimiter, permissionChecker *utilities.PermissionChecker, events realtime.Publisher) *InviteHandler {
+	return &InviteHandler{
+		inviteService:     inviteService,
+		store:             store,
+		limiter:           limiter,
+		permissionChecker: permissionChecker,
+		events:            events,
+	}
+}
+
+func (h *InviteHandler) RegisterRoutes(router *http.ServeMux) {
+	manageStack := []middleware.Middleware{
+		middleware.TokenAuthMiddleware(h.store),
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "workspace_invites"),
+		middleware.PermissionMiddleware(h.permissionChecker, "workspace:manage-users"),
+	}
+
+	router.Handle("/api/workspaces/{workspaceId}/invites", middleware.Chain(
+		http.HandlerFunc(h.Invites),
+		manageStack...,
+	))
+	router.Handle("/api/workspaces/{workspaceId}/invites/{inviteId}", middleware.Chain(
+		http.HandlerFunc(h.RevokeInvite),
+		manageStack...,
+	))
+
+	redeemStack := []middleware.Middleware{
+		middleware.TokenAuthMiddleware(h.store),
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "invite_redeem"),
+	}
+
+	router.Handle("/api/invites/{code}", middleware.Chain(
+		http.HandlerFunc(h.Invite),
+		redeemStack...,
+	))
+}
+
+// Invites lists the workspace's invites (GET) or creates one (POST)
+func (h *InviteHandler) Invites(w http.ResponseWriter, r *http.Request) {
+	workspaceID := r.PathValue("workspaceId")
+
+	switch r.Method {
+	case http.MethodGet:
+		invites, err := h.inviteService.ListInvites(r.Context(), workspaceID)
+		if err != nil {
+			fmt.Println("Error listing invites:", err)
+			http.Error(w, "Failed to list invites", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(invites)
+	case http.MethodPost:
+		userID, ok := utilities.GetUserID(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req models.CreateInviteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		invite, err := h.inviteService.CreateInvite(r.Context(), workspaceID, userID, utilities.IsInstanceAdmin(r.Context()), req)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidInviteExpiry),
+				errors.Is(err, services.ErrInvalidInviteMaxUses),
+				errors.Is(err, services.ErrInvalidRoleID),
+				errors.Is(err, services.ErrRoleNotFound):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, services.ErrRoleNotGrantable):
+				http.Error(w, err.Error(), http.StatusForbidden)
+			default:
+				fmt.Println("Error creating invite:", err)
+				http.Error(w, "Failed to create invite", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(invite)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *InviteHandler) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := h.inviteService.RevokeInvite(r.Context(), r.PathValue("workspaceId"), r.PathValue("inviteId"))
+	if err != nil {
+		if errors.Is(err, repos.ErrInviteNotFound) {
+			http.Error(w, "Invite not found", http.StatusNotFound)
+			return
+		}
+		fmt.Println("Error revoking invite:", err)
+		http.Error(w, "Failed to revoke invite", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Invite shows which workspace a code invites to (GET) or joins it (POST)
+func (h *InviteHandler) Invite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	code := r.PathValue("code")
+	if r.Method == http.MethodGet {
+		preview, err := h.inviteService.PreviewInvite(r.Context(), code)
+		if err != nil {
+			writeInviteError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(preview)
+		return
+	}
+
+	// Admin accounts are not users and cannot belong to workspaces
+	if utilities.IsInstanceAdmin(r.Context()) {
+		http.Error(w, "Admin accounts cannot join workspaces", http.StatusForbidden)
+		return
+	}
+	userID, ok := utilities.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	workspaceID, err := h.inviteService.RedeemInvite(r.Context(), code, userID)
+	if err != nil {
+		writeInviteError(w, err)
+		return
+	}
+
+	event := realtime.NewEvent(models.EventMemberAdded, workspaceID, map[string]string{"user_id": userID, "workspace_id": workspaceID})
+	event.SubjectUserID = userID
+	h.events.Publish(r.Context(), event)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"workspace_id": workspaceID})
+}
+
+func writeInviteError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repos.ErrInviteNotFound):
+		http.Error(w, "Invite not found", http.StatusNotFound)
+	case errors.Is(err, repos.ErrInviteUnavailable):
+		http.Error(w, err.Error(), http.StatusGone)
+	case errors.Is(err, repos.ErrAlreadyMember):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		fmt.Println("Invite error:", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
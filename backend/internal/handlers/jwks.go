@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"backend/pkg/middleware"
+	"backend/pkg/ratelimiter"
+	"backend/pkg/utilities"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// JWKSHandler publishes the public token signing keys so other services can verify tokens
+type JWKSHandler struct {
+	keyRing *utilities.KeyRing
+	limiter ratelimiter.RateLimiter
+}
+
+func NewJWKSHandler(keyRing *utilities.KeyRing, limiter ratelimiter.RateLimiter) *JWKSHandler {
+	return &JWKSHandler{keyRing: keyRing, limiter: limiter}
+}
+
+func (h *JWKSHandler) RegisterRoutes(router *http.ServeMux) {
+	jwksStack := []middleware.Middleware{
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "jwks"),
+	}
+
+	router.Handle("/.well-known/jwks.json", middleware.Chain(
+		http.HandlerFunc(h.GetJWKS),
+		jwksStack...,
+	))
+}
+
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Short cache so verifiers notice newly scheduled keys well before they sign
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.keyRing.JWKS())
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"backend/internal/services"
+	"backend/pkg/middleware"
+	"backend/pkg/ratelimiter"
+	"backend/pkg/utilities"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type MessageHandler struct {
+	messageService    *services.MessageService
+	store             utilities.SessionStore
+	limiter           ratelimiter.RateLimiter
+	permissionChecker *utilities.PermissionChecker
+	events            realtime.Publisher
+}
+
+func NewMessageHandler(messageService *services.MessageService, store utilities.SessionStore, limiter ratelimiter.RateLimiter, permissionChecker *utilities.PermissionChecker, events realtime.Publisher) *MessageHandler {
+	return &MessageHandler{
+		messageService:    messageService,
+		store:             store,
+		limiter:           limiter,
+		permissionChecker: permissionChecker,
+		events:            events,
+	}
+}
+
+func (h *MessageHandler) RegisterRoutes(router *http.ServeMux) {
+	readStack := []middleware.Middleware{
+		middleware.TokenAuthMiddleware(h.store),
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "messages_read"),
+		middleware.PermissionMiddleware(h.permissionChecker, "workspace:view-channels"),
+	}
+
+	writeStack := []middleware.Middleware{
+		middleware.TokenAuthMiddleware(h.store),
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "messages_write"),
+		middleware.PermissionMiddleware(h.permissionChecker, "workspace:send-messages"),
+	}
+
+	getMessages := middleware.Chain(http.HandlerFunc(h.GetMessages), readStack...)
+	postMessage := middleware.Chain(http.HandlerFunc(h.PostMessage), writeStack...)
+
+	router.Handle("/api/workspaces/{workspaceId}/channels/{channelId}/messages", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			getMessages.ServeHTTP(w, r)
+		case http.MethodPost:
+			postMessage.ServeHTTP(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+}
+
+// GetMessages returns a page of channel history using keyset pagination.
+// Query parameters: before (cursor from a previous page) and limit.
+func (h *MessageHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
+	workspaceID, channelID, ok := messagePathParams(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 0
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	page, err := h.messageService.GetMessages(r.Context(), workspaceID, channelID, r.URL.Query().Get("before"), limit)
+	if err != nil {
+		writeMessageError(w, err, "Failed to get messages")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// PostMessage posts a new message into a channel as the authenticated user
+func (h *MessageHandler) PostMessage(w http.ResponseWriter, r *http.Request) {
+	workspaceID, channelID, ok := messagePathParams(w, r)
+	if !ok {
+		return
+	}
+
+	userID, ok := utilities.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.messageService.PostMessage(r.Context(), workspaceID, channelID, userID, req.Message)
+	if err != nil {
+		writeMessageError(w, err, "Failed to post message")
+		return
+	}
+
+	h.events.Publish(r.Context(), realtime.NewEvent(models.EventMessageCreated, workspaceID, message))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+}
+
+func messagePathParams(w http.ResponseWriter, r *http.Request) (string, int, bool) {
+	workspaceID := r.PathValue("workspaceId")
+	if workspaceID == "" {
+		http.Error(w, "Workspace ID is required", http.StatusBadRequest)
+		return "", 0, false
+	}
+
+	channelID, err := strconv.Atoi(r.PathValue("channelId"))
+	if err != nil {
+		http.Error(w, services.ErrInvalidChannelID.Error(), http.StatusBadRequest)
+		return "", 0, false
+	}
+
+	return workspaceID, channelID, true
+}
+
+func writeMessageError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrEmptyMessage),
+		errors.Is(err, services.ErrMessageTooLong),
+		errors.Is(err, services.ErrInvalidChannelID),
+		errors.Is(err, services.ErrInvalidCursor):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrChannelNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		log.Printf("%s: %v", fallback, err)
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
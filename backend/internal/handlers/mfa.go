@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/services"
+	"backend/pkg/middleware"
+	"backend/pkg/ratelimiter"
+	"backend/pkg/utilities"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// MFAHandler lets signed-in users manage their own second factor
+type MFAHandler struct {
+	mfaService *services.MFAService
+	store      utilities.SessionStore
+	limiter    ratelimiter.RateLimiter
+}
+
+func NewMFAHandler(mfaService *services.MFAService, store utilities.SessionStore, limiter ratelimiter.RateLimiter) *MFAHandler {
+	return &MFAHandler{mfaService: mfaService, store: store, limiter: limiter}
+}
+
+func (h *MFAHandler) RegisterRoutes(router *http.ServeMux) {
+	mfaStack := []middleware.Middleware{
+		middleware.TokenAuthMiddleware(h.store),
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "user_mfa"),
+	}
+
+	router.Handle("/api/user/mfa", middleware.Chain(
+		http.HandlerFunc(h.GetStatus),
+		mfaStack...,
+	))
+	router.Handle("/api/user/mfa/enroll", middleware.Chain(
+		http.HandlerFunc(h.BeginEnrollment),
+		mfaStack...,
+	))
+	router.Handle("/api/user/mfa/confirm", middleware.Chain(
+		http.HandlerFunc(h.ConfirmEnrollment),
+		mfaStack...,
+	))
+	router.Handle("/api/user/mfa/disable", middleware.Chain(
+		http.HandlerFunc(h.Disable),
+		mfaStack...,
+	))
+	router.Handle("/api/user/mfa/recovery-codes", middleware.Chain(
+		http.HandlerFunc(h.RegenerateRecoveryCodes),
+		mfaStack...,
+	))
+}
+
+func (h *MFAHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := utilities.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.mfaService.Status(r.Context(), userID)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// BeginEnrollment returns a secret and otpauth:// URI for the frontend to show as a QR code
+func (h *MFAHandler) BeginEnrollment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := utilities.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	enrollment, err := h.mfaService.BeginEnrollment(r.Context(), userID)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// ConfirmEnrollment enables 2FA and returns the one-time view of the recovery codes
+func (h *MFAHandler) ConfirmEnrollment(w http.ResponseWriter, r *http.Request) {
+	userID, req, ok := h.codeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.mfaService.ConfirmEnrollment(r.Context(), userID, req.Code)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.RecoveryCodes{RecoveryCodes: codes})
+}
+
+func (h *MFAHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	userID, req, ok := h.codeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.mfaService.Disable(r.Context(), userID, req.Code); err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *MFAHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, req, ok := h.codeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(r.Context(), userID, req.Code)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.RecoveryCodes{RecoveryCodes: codes})
+}
+
+// codeRequest handles the shared POST {"code": ...} request shape
+func (h *MFAHandler) codeRequest(w http.ResponseWriter, r *http.Request) (string, models.MFACodeRequest, bool) {
+	var req models.MFACodeRequest
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return "", req, false
+	}
+	userID, ok := utilities.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", req, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return "", req, false
+	}
+	return userID, req, true
+}
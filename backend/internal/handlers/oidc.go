@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"backend/internal/services"
+	"backend/pkg/middleware"
+	"backend/pkg/ratelimiter"
+	"backend/pkg/utilities"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// oidcStateCookieName holds a hash of the state of the sign-in this browser started
+const oidcStateCookieName = "oidc_state"
+
+type OIDCHandler struct {
+	// ssoService is nil when single sign-on is not configured
+	ssoService *services.SSOService
+	mfaService *services.MFAService
+	store      utilities.SessionStore
+	limiter    ratelimiter.RateLimiter
+	events     realtime.Publisher
+	// frontendURL is the page the callback sends the browser back to. It finishes
+	// the sign-in by trading the refresh cookie for an access token.
+	frontendURL string
+}
+
+func NewOIDCHandler(ssoService *services.SSOService, mfaService *services.MFAService, store utilities.SessionStore, limiter ratelimiter.RateLimiter, events realtime.Publisher, frontendURL string) *OIDCHandler {
+	if frontendURL == "" {
+		frontendURL = "/user-login"
+	}
+	return &OIDCHandler{
+		ssoService:  ssoService,
+		mfaService:  mfaService,
+		store:       store,
+		limiter:     limiter,
+		events:      events,
+		frontendURL: frontendURL,
+	}
+}
+
+func (h *OIDCHandler) RegisterRoutes(router *http.ServeMux) {
+	ssoStack := []middleware.Middleware{
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "oidc"),
+	}
+
+	router.Handle("/api/auth/oidc/config", middleware.Chain(
+		http.HandlerFunc(h.GetConfig),
+		ssoStack...,
+	))
+	router.Handle("/api/auth/oidc/login", middleware.Chain(
+		http.HandlerFunc(h.Login),
+		ssoStack...,
+	))
+	router.Handle("/api/auth/oidc/callback", middleware.Chain(
+		http.HandlerFunc(h.Callback),
+		ssoStack...,
+	))
+	router.Handle("/api/auth/oidc/link", middleware.Chain(
+		http.HandlerFunc(h.Link),
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "oidc"),
+		middleware.TokenAuthMiddleware(h.store),
+	))
+}
+
+// GetConfig tells the login page whether to offer single sign-on
+func (h *OIDCHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	config := models.SSOConfig{}
+	if h.ssoService != nil {
+		config = h.ssoService.Config()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(config)
+}
+
+// Login sends the browser to the provider to sign in
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.ssoService == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	authURL, state, err := h.ssoService.StartLogin(r.Context(), "")
+	if err != nil {
+		fmt.Println("SSO login error:", err)
+		http.Error(w, "Single sign-on is unavailable", http.StatusBadGateway)
+		return
+	}
+	setStateCookie(w, state)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Link returns the provider URL a signed-in user visits to link their provider
+// account, so they can sign in with it from then on
+func (h *OIDCHandler) Link(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.ssoService == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if utilities.IsInstanceAdmin(r.Context()) {
+		http.Error(w, "Admin accounts cannot use single sign-on", http.StatusForbidden)
+		return
+	}
+	userID, ok := utilities.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	authURL, state, err := h.ssoService.StartLogin(r.Context(), userID)
+	if err != nil {
+		fmt.Println("SSO link error:", err)
+		http.Error(w, "Single sign-on is unavailable", http.StatusBadGateway)
+		return
+	}
+	setStateCookie(w, state)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"url": authURL})
+}
+
+// Callback is where the provider sends the browser back. Sign-ins go through the same
+// two-factor step as a password login, then get a session and refresh cookie; the
+// browser is sent to the frontend, which exchanges the cookie for an access token.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.ssoService == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	// Only the browser that started the sign-in may finish it, so a callback URL from
+	// someone else's sign-in cannot log this browser in or link their account to it
+	stateMatches := stateCookieMatches(r, query.Get("state"))
+	clearStateCookie(w)
+	if providerErr := query.Get("error"); providerErr != "" {
+		// The user cancelled or the provider refused; the state is left to expire
+		h.redirect(w, r, "sso_error", "Sign-in was cancelled or refused by the provider")
+		return
+	}
+	if !stateMatches {
+		h.redirect(w, r, "sso_error", services.ErrSSOState.Error())
+		return
+	}
+
+	result, err := h.ssoService.Complete(r.Context(), query.Get("state"), query.Get("code"), clientInfo(r))
+	if err != nil {
+		if services.IsSSOUserError(err) {
+			h.redirect(w, r, "sso_error", err.Error())
+			return
+		}
+		fmt.Println("SSO callback error:", err)
+		h.redirect(w, r, "sso_error", "Single sign-on failed")
+		return
+	}
+
+	services.PublishGroupRoleChanges(r.Context(), h.events, result.UserID, result.Changes)
+
+	if result.Linked {
+		h.redirect(w, r, "sso", "linked")
+		return
+	}
+
+	challenge, err := h.mfaService.StartLoginChallenge(r.Context(), result.UserID)
+	if err != nil {
+		var lockoutErr *services.LockoutError
+		if errors.As(err, &lockoutErr) {
+			h.redirect(w, r, "sso_error", "Too many failed attempts. Please try again later.")
+			return
+		}
+		fmt.Println("MFA challenge error:", err)
+		h.redirect(w, r, "sso_error", "Failed to start login")
+		return
+	}
+	if challenge != nil {
+		h.redirectToChallenge(w, r, challenge)
+		return
+	}
+
+	session, err := utilities.StartSession(r.Context(), h.store, result.UserID, r.UserAgent(), utilities.ClientIP(r))
+	if err != nil {
+		fmt.Println("Session creation error:", err)
+		h.redirect(w, r, "sso_error", "Failed to create session")
+		return
+	}
+	refreshToken, err := utilities.GenerateRefreshToken(r.Context(), h.store, result.UserID, session.ID)
+	if err != nil {
+		fmt.Println("Refresh token generation error:", err)
+		h.redirect(w, r, "sso_error", "Failed to create session")
+		return
+	}
+	utilities.SetRefreshTokenCookie(w, refreshToken)
+	h.redirect(w, r, "sso", "success")
+}
+
+// redirect sends the browser back to the frontend with the outcome in the query
+func (h *OIDCHandler) redirect(w http.ResponseWriter, r *http.Request, key string, value string) {
+	target, err := url.Parse(h.frontendURL)
+	if err != nil {
+		http.Error(w, value, http.StatusInternalServerError)
+		return
+	}
+	query := target.Query()
+	query.Set(key, value)
+	target.RawQuery = query.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// redirectToChallenge sends the browser back to the frontend to enter a two-factor code.
+// The challenge token goes in the fragment so it stays out of server logs and referrers.
+func (h *OIDCHandler) redirectToChallenge(w http.ResponseWriter, r *http.Request, challenge *services.MFAChallenge) {
+	target, err := url.Parse(h.frontendURL)
+	if err != nil {
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+	query := target.Query()
+	query.Set("sso", "mfa")
+	target.RawQuery = query.Encode()
+	fragment := url.Values{"mfa_token": {challenge.Token}}
+	if challenge.EnrollmentRequired {
+		fragment.Set("mfa_enroll", "1")
+	}
+	target.Fragment = fragment.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// setStateCookie binds a sign-in to the browser that started it. Lax is enough for
+// the cookie to come back on the provider's top-level redirect to the callback.
+func setStateCookie(w http.ResponseWriter, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    hashState(state),
+		Path:     "/api/auth/oidc",
+		MaxAge:   int(services.SSOStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func clearStateCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    "",
+		Path:     "/api/auth/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func stateCookieMatches(r *http.Request, state string) bool {
+	cookie, err := r.Cookie(oidcStateCookieName)
+	if err != nil || state == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(hashState(state))) == 1
+}
+
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/repos"
+	"backend/internal/services"
+	"backend/pkg/middleware"
+	"backend/pkg/ratelimiter"
+	"backend/pkg/utilities"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type OutgoingWebhookHandler struct {
+	webhookService    *services.OutgoingWebhookService
+	store             utilities.SessionStore
+	limiter           ratelimiter.RateLimiter
+	permissionChecker *utilities.PermissionChecker
+}
+
+func NewOutgoingWebhookHandler(webhookService *services.OutgoingWebhookService, store utilities.SessionStore, limiter ratelimiter.RateLimiter, permissionChecker *utilities.PermissionChecker) *OutgoingWebhookHandler {
+	return &OutgoingWebhookHandler{
+		webhookService:    webhookService,
+		store:             store,
+		limiter:           limiter,
+		permissionChecker: permissionChecker,
+	}
+}
+
+func (h *OutgoingWebhookHandler) RegisterRoutes(router *http.ServeMux) {
+	// Webhooks send workspace data off-site, so they take the workspace-wide permission
+	manageStack := []middleware.Middleware{
+		middleware.TokenAuthMiddleware(h.store),
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "outgoing_webhooks"),
+		middleware.PermissionMiddleware(h.permissionChecker, "workspace:manage-workspace"),
+	}
+
+	router.Handle("/api/workspaces/{workspaceId}/outgoing-webhooks", middleware.Chain(
+		http.HandlerFunc(h.Webhooks),
+		manageStack...,
+	))
+	router.Handle("/api/workspaces/{workspaceId}/outgoing-webhooks/{webhookId}", middleware.Chain(
+		http.HandlerFunc(h.DeleteWebhook),
+		manageStack...,
+	))
+	router.Handle("/api/workspaces/{workspaceId}/outgoing-webhooks/{webhookId}/deliveries", middleware.Chain(
+		http.HandlerFunc(h.Deliveries),
+		manageStack...,
+	))
+	router.Handle("/api/workspaces/{workspaceId}/outgoing-webhooks/{webhookId}/deliveries/{deliveryId}/redeliver", middleware.Chain(
+		http.HandlerFunc(h.Redeliver),
+		manageStack...,
+	))
+}
+
+// Webhooks lists the workspace's outgoing webhooks (GET) or creates one (POST)
+func (h *OutgoingWebhookHandler) Webhooks(w http.ResponseWriter, r *http.Request) {
+	workspaceID := r.PathValue("workspaceId")
+
+	switch r.Method {
+	case http.MethodGet:
+		webhooks, err := h.webhookService.ListWebhooks(r.Context(), workspaceID)
+		if err != nil {
+			writeOutgoingWebhookError(w, err, "Failed to list webhooks")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(webhooks)
+	case http.MethodPost:
+		userID, ok := utilities.GetUserID(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req models.CreateOutgoingWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		webhook, err := h.webhookService.CreateWebhook(r.Context(), workspaceID, userID, utilities.IsInstanceAdmin(r.Context()), req)
+		if err != nil {
+			writeOutgoingWebhookError(w, err, "Failed to create webhook")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(webhook)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *OutgoingWebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(r.Context(), r.PathValue("workspaceId"), r.PathValue("webhookId")); err != nil {
+		writeOutgoingWebhookError(w, err, "Failed to delete webhook")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries returns the webhook's delivery history, optionally filtered by ?status=
+func (h *OutgoingWebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(r.Context(), r.PathValue("workspaceId"), r.PathValue("webhookId"), r.URL.Query().Get("status"), limit)
+	if err != nil {
+		writeOutgoingWebhookError(w, err, "Failed to list deliveries")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// Redeliver queues a finished or dead-lettered delivery to be sent again
+func (h *OutgoingWebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := h.webhookService.Redeliver(r.Context(), r.PathValue("workspaceId"), r.PathValue("webhookId"), r.PathValue("deliveryId"))
+	if err != nil {
+		writeOutgoingWebhookError(w, err, "Failed to redeliver")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func writeOutgoingWebhookError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhookName),
+		errors.Is(err, services.ErrInvalidWebhookURL),
+		errors.Is(err, services.ErrInvalidWebhookEvents),
+		errors.Is(err, services.ErrInvalidTriggerWords),
+		errors.Is(err, services.ErrMessageFilterNeedsEvent),
+		errors.Is(err, services.ErrInvalidDeliveryStatus):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrTooManyOutgoingWebhooks),
+		errors.Is(err, repos.ErrWebhookDeliveryScheduled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrChannelNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repos.ErrOutgoingWebhookNotFound):
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+	case errors.Is(err, repos.ErrWebhookDeliveryNotFound):
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+	default:
+		fmt.Println(fallback+":", err)
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"backend/internal/realtime"
+	"backend/pkg/apitoken"
+	"backend/pkg/middleware"
+	"backend/pkg/ratelimiter"
+	"backend/pkg/utilities"
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type RealtimeHandler struct {
+	hub      *realtime.Hub
+	store    utilities.SessionStore
+	limiter  ratelimiter.RateLimiter
+	upgrader websocket.Upgrader
+}
+
+func NewRealtimeHandler(hub *realtime.Hub, store utilities.SessionStore, limiter ratelimiter.RateLimiter) *RealtimeHandler {
+	return &RealtimeHandler{
+		hub:     hub,
+		store:   store,
+		limiter: limiter,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     checkWebSocketOrigin,
+		},
+	}
+}
+
+func (h *RealtimeHandler) RegisterRoutes(router *http.ServeMux) {
+	stack := []middleware.Middleware{
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "realtime_connect"),
+		middleware.QueryAccessTokenMiddleware(),
+		middleware.TokenAuthMiddleware(h.store),
+	}
+
+	router.Handle("/api/ws", middleware.Chain(
+		http.HandlerFunc(h.Connect),
+		stack...,
+	))
+}
+
+// Connect upgrades the request to a WebSocket streaming workspace events.
+// Clients resuming after a reconnect pass the last event they saw as last_event_id.
+func (h *RealtimeHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := utilities.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	validateSession, ok := h.sessionValidator(r, userID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	lastEventID := r.URL.Query().Get("last_event_id")
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already written an error response
+		log.Println("WebSocket upgrade failed:", err)
+		return
+	}
+
+	h.hub.Serve(conn, userID, lastEventID, validateSession)
+}
+
+// sessionValidator closes the socket once the credential it was opened with stops
+// working: the device's session for access tokens, or the token itself for personal
+// access tokens, which have no session
+func (h *RealtimeHandler) sessionValidator(r *http.Request, userID string) (realtime.SessionValidator, bool) {
+	if _, ok := utilities.GetAPITokenScopes(r.Context()); ok {
+		verifier, ok := h.store.(utilities.APITokenVerifier)
+		if !ok {
+			return nil, false
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		return func(ctx context.Context) error {
+			claims, err := verifier.VerifyAPIToken(ctx, token)
+			if err != nil {
+				return err
+			}
+			if claims.UserID != userID || !apitoken.Allows(claims.Scopes, "events:read") {
+				return utilities.ErrInvalidAPIToken
+			}
+			return nil
+		}, true
+	}
+
+	sessionID, ok := utilities.GetSessionID(r.Context())
+	if !ok {
+		return nil, false
+	}
+	return func(ctx context.Context) error {
+		session, err := h.store.GetSession(ctx, sessionID)
+		if err != nil {
+			return err
+		}
+		if session.UserID != userID {
+			return utilities.ErrSessionNotFound
+		}
+		return nil
+	}, true
+}
+
+// checkWebSocketOrigin only allows same-origin connections, plus the dev frontend in DEV mode
+func checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if os.Getenv("DEV") != "" && origin == "http://localhost:5173" {
+		return true
+	}
+	return origin == "http://"+r.Host || origin == "https://"+r.Host
+}
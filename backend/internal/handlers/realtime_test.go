@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"backend/internal/eventbus"
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"backend/pkg/apitoken"
+	"backend/pkg/testutil"
+	"backend/pkg/utilities"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type allowAllLimiter struct{}
+
+func (allowAllLimiter) Allow(ctx context.Context, key string, window time.Duration) (bool, error) {
+	return true, nil
+}
+
+type workspaceAuthorizer map[string]bool
+
+func (a workspaceAuthorizer) VisibleWorkspaces(ctx context.Context, userID string) (map[string]bool, error) {
+	return a, nil
+}
+
+// revocableAPITokens verifies a fixed set of tokens until they are revoked
+type revocableAPITokens struct {
+	mu     sync.Mutex
+	tokens map[string]*utilities.APITokenClaims
+}
+
+func (f *revocableAPITokens) VerifyAPIToken(ctx context.Context, token string) (*utilities.APITokenClaims, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	claims, ok := f.tokens[token]
+	if !ok {
+		return nil, utilities.ErrInvalidAPIToken
+	}
+	return claims, nil
+}
+
+func (f *revocableAPITokens) revoke(token string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.tokens, token)
+}
+
+func TestRealtimeConnectWithAPIToken(t *testing.T) {
+	const eventsToken = apitoken.Prefix + "events"
+	const messagesToken = apitoken.Prefix + "messages"
+	tokens := &revocableAPITokens{tokens: map[string]*utilities.APITokenClaims{
+		eventsToken:   {TokenID: "token-1", UserID: "bot1", Scopes: []string{"events:read"}},
+		messagesToken: {TokenID: "token-2", UserID: "bot1", Scopes: []string{"messages:read"}},
+	}}
+	store := utilities.WithAPITokens(testutil.NewMemorySessionStore(), tokens)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := eventbus.NewMemoryBus(100)
+	hub := realtime.NewHub(bus, workspaceAuthorizer{"ws1": true})
+	go hub.Run(ctx)
+	for deadline := time.Now().Add(2 * time.Second); bus.Subscribers() == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("hub did not subscribe to the bus")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	handler := NewRealtimeHandler(hub, store, allowAllLimiter{})
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/ws"
+
+	t.Run("token without events scope", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Authorization": {"Bearer " + messagesToken}})
+		if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("dial = %v, %v; want 403", resp, err)
+		}
+	})
+
+	t.Run("token with events scope", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Authorization": {"Bearer " + eventsToken}})
+		if err != nil {
+			t.Fatalf("failed to dial: %v", err)
+		}
+		defer conn.Close()
+
+		// Keep publishing until the client has registered with the hub and sees one
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for {
+				hub.Publish(ctx, realtime.NewEvent(models.EventChannelCreated, "ws1", map[string]string{"name": "general"}))
+				select {
+				case <-done:
+					return
+				case <-time.After(20 * time.Millisecond):
+				}
+			}
+		}()
+
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		for {
+			var frame models.Event
+			if err := conn.ReadJSON(&frame); err != nil {
+				t.Fatalf("waiting for event: %v", err)
+			}
+			if frame.Type == models.EventChannelCreated {
+				break
+			}
+		}
+	})
+
+	t.Run("validator rechecks the token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/ws", nil)
+		req.Header.Set("Authorization", "Bearer "+eventsToken)
+		req = req.WithContext(utilities.WithAPITokenScopes(utilities.WithUserID(req.Context(), "bot1"), []string{"events:read"}))
+
+		validate, ok := handler.sessionValidator(req, "bot1")
+		if !ok {
+			t.Fatal("sessionValidator() refused a token request")
+		}
+		if err := validate(context.Background()); err != nil {
+			t.Fatalf("validate() error = %v", err)
+		}
+		tokens.revoke(eventsToken)
+		if err := validate(context.Background()); err == nil {
+			t.Fatal("validate() accepted a revoked token")
+		}
+	})
+}
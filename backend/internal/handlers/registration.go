@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"backend/internal/repos"
+	"backend/internal/services"
+	"backend/pkg/middleware"
+	"backend/pkg/passwordpolicy"
+	"backend/pkg/ratelimiter"
+	"backend/pkg/utilities"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type RegistrationHandler struct {
+	registrationService *services.RegistrationService
+	store               utilities.SessionStore
+	authLimiter         ratelimiter.RateLimiter
+	limiter             ratelimiter.RateLimiter
+	events              realtime.Publisher
+}
+
+func NewRegistrationHandler(registrationService *services.RegistrationService, store utilities.SessionStore, authLimiter ratelimiter.RateLimiter, limiter ratelimiter.RateLimiter, events realtime.Publisher) *RegistrationHandler {
+	return &RegistrationHandler{
+		registrationService: registrationService,
+		store:               store,
+		authLimiter:         authLimiter,
+		limiter:             limiter,
+		events:              events,
+	}
+}
+
+func (h *RegistrationHandler) RegisterRoutes(router *http.ServeMux) {
+	router.Handle("/api/auth/registration", middleware.Chain(
+		http.HandlerFunc(h.GetRegistrationInfo),
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "registration_info"),
+	))
+	router.Handle("/api/auth/register", middleware.Chain(
+		http.HandlerFunc(h.Register),
+		middleware.RateLimitMiddleware(h.authLimiter, time.Minute, "register"),
+	))
+
+	adminStack := []middleware.Middleware{
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "admin_dashboard"),
+		middleware.TokenAuthMiddleware(h.store),
+	}
+	router.Handle("/api/admin/registrations", middleware.Chain(
+		http.HandlerFunc(h.ListPending),
+		adminStack...,
+	))
+	router.Handle("/api/admin/registrations/{userId}/approve", middleware.Chain(
+		http.HandlerFunc(h.Approve),
+		adminStack...,
+	))
+	router.Handle("/api/admin/registrations/{userId}/reject", middleware.Chain(
+		http.HandlerFunc(h.Reject),
+		adminStack...,
+	))
+}
+
+func (h *RegistrationHandler) GetRegistrationInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	info, err := h.registrationService.Info(r.Context())
+	if err != nil {
+		fmt.Println("Registration info error:", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// Register creates an account for a visitor. It does not sign them in; pending
+// accounts must wait for approval and everyone else logs in as usual.
+func (h *RegistrationHandler) Register(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, workspaceID, err := h.registrationService.Register(r.Context(), req, clientInfo(r))
+	if err != nil {
+		var policyErr *passwordpolicy.ValidationError
+		switch {
+		case errors.As(err, &policyErr):
+			writePasswordPolicyError(w, policyErr)
+		case errors.Is(err, services.ErrRegistrationClosed):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, services.ErrInviteRequired),
+			errors.Is(err, services.ErrInvalidUsername):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, repos.ErrUsernameTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, repos.ErrInviteNotFound),
+			errors.Is(err, repos.ErrInviteUnavailable):
+			writeInviteError(w, err)
+		default:
+			fmt.Println("Registration error:", err)
+			http.Error(w, "Failed to register", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if workspaceID != "" {
+		userID := user.Id.String()
+		event := realtime.NewEvent(models.EventMemberAdded, workspaceID, map[string]string{"user_id": userID, "workspace_id": workspaceID})
+		event.SubjectUserID = userID
+		h.events.Publish(r.Context(), event)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"user":            user,
+		"workspaceId":     workspaceID,
+		"pendingApproval": user.Status == models.UserStatusPending,
+	})
+}
+
+// ListPending returns the registrations waiting for approval
+func (h *RegistrationHandler) ListPending(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !utilities.IsInstanceAdmin(r.Context()) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	users, err := h.registrationService.ListPending(r.Context())
+	if err != nil {
+		fmt.Println("Unable to list pending registrations:", err)
+		http.Error(w, "Unable to list pending registrations", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(users)
+}
+
+func (h *RegistrationHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, true)
+}
+
+func (h *RegistrationHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, false)
+}
+
+func (h *RegistrationHandler) decide(w http.ResponseWriter, r *http.Request, approve bool) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !utilities.IsInstanceAdmin(r.Context()) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	adminId, _ := utilities.GetUserID(r.Context())
+	user, err := h.registrationService.Decide(r.Context(), r.PathValue("userId"), approve, adminId, clientInfo(r))
+	if err != nil {
+		if errors.Is(err, repos.ErrUserNotFound) {
+			http.Error(w, "No pending registration for this user", http.StatusNotFound)
+			return
+		}
+		fmt.Println("Unable to decide registration:", err)
+		http.Error(w, "Unable to decide registration", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
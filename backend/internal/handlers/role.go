@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"backend/internal/models"
+	"backend/internal/realtime"
 	"backend/internal/services"
 	"backend/pkg/middleware"
 	"backend/pkg/ratelimiter"
@@ -19,14 +20,16 @@ type RoleHandler struct {
 	store             utilities.SessionStore
 	limiter           ratelimiter.RateLimiter
 	permissionChecker *utilities.PermissionChecker
+	events            realtime.Publisher
 }
 
-func NewRoleHandler(roleService *services.RoleService, store utilities.SessionStore, limiter ratelimiter.RateLimiter, permissionChecker *utilities.PermissionChecker) *RoleHandler {
+func NewRoleHandler(roleService *services.RoleService, store utilities.SessionStore, limiter ratelimiter.RateLimiter, permissionChecker *utilities.PermissionChecker, events realtime.Publisher) *RoleHandler {
 	return &RoleHandler{
 		roleService:       roleService,
 		store:             store,
 		limiter:           limiter,
 		permissionChecker: permissionChecker,
+		events:            events,
 	}
 }
 
@@ -395,6 +398,9 @@ func (h *RoleHandler) AssignRoleToUser(w http.ResponseWriter, r *http.Request, w
 		case services.ErrRoleAssignmentExists:
 			log.Printf("AssignRoleToUser: Role assignment exists error: %v", err)
 			http.Error(w, err.Error(), http.StatusConflict)
+		case services.ErrBotOutsideWorkspace:
+			log.Printf("AssignRoleToUser: Bot outside workspace error: %v", err)
+			http.Error(w, err.Error(), http.StatusForbidden)
 		default:
 			log.Printf("AssignRoleToUser: Internal server error: %v", err)
 			http.Error(w, "Failed to assign role", http.StatusInternalServerError)
@@ -403,6 +409,10 @@ func (h *RoleHandler) AssignRoleToUser(w http.ResponseWriter, r *http.Request, w
 	}
 
 	log.Printf("AssignRoleToUser: Role assigned successfully - UserID: %s, RoleID: %d, WorkspaceID: %s", req.UserID, req.RoleID, workspaceID)
+	event := realtime.NewEvent(models.EventRoleAssigned, workspaceID, userRole)
+	event.SubjectUserID = req.UserID
+	h.events.Publish(r.Context(), event)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(userRole)
@@ -455,6 +465,10 @@ func (h *RoleHandler) RemoveRoleFromUser(w http.ResponseWriter, r *http.Request,
 	}
 
 	log.Printf("RemoveRoleFromUser: Role removed successfully - UserID: %s, RoleID: %d, WorkspaceID: %s", userID, roleID, workspaceID)
+	event := realtime.NewEvent(models.EventRoleRemoved, workspaceID, map[string]any{"user_id": userID, "role_id": roleID})
+	event.SubjectUserID = userID
+	h.events.Publish(r.Context(), event)
+
 	w.WriteHeader(http.StatusNoContent)
 	log.Println("RemoveRoleFromUser: Response sent successfully")
 }
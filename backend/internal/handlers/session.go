@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"backend/pkg/middleware"
+	"backend/pkg/ratelimiter"
+	"backend/pkg/utilities"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type SessionHandler struct {
+	store   utilities.SessionStore
+	limiter ratelimiter.RateLimiter
+}
+
+func NewSessionHandler(store utilities.SessionStore, limiter ratelimiter.RateLimiter) *SessionHandler {
+	return &SessionHandler{store: store, limiter: limiter}
+}
+
+// sessionResponse is a session as shown to its owner
+type sessionResponse struct {
+	utilities.Session
+	Current bool `json:"current"`
+}
+
+func (h *SessionHandler) RegisterRoutes(router *http.ServeMux) {
+	refreshStack := []middleware.Middleware{
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "auth_session"),
+	}
+
+	router.Handle("/api/auth/refresh", middleware.Chain(
+		http.HandlerFunc(h.Refresh),
+		refreshStack...,
+	))
+	router.Handle("/api/auth/logout", middleware.Chain(
+		http.HandlerFunc(h.Logout),
+		refreshStack...,
+	))
+
+	sessionStack := []middleware.Middleware{
+		middleware.TokenAuthMiddleware(h.store),
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "user_sessions"),
+	}
+
+	router.Handle("/api/user/sessions", middleware.Chain(
+		http.HandlerFunc(h.ListSessions),
+		sessionStack...,
+	))
+	router.Handle("/api/user/sessions/revoke-others", middleware.Chain(
+		http.HandlerFunc(h.RevokeOtherSessions),
+		sessionStack...,
+	))
+	router.Handle("/api/user/sessions/{sessionId}", middleware.Chain(
+		http.HandlerFunc(h.RevokeSession),
+		sessionStack...,
+	))
+}
+
+// Refresh exchanges the refresh token cookie for a new access token and a rotated refresh token
+func (h *SessionHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cookie, err := r.Cookie(utilities.RefreshTokenCookieName)
+	if err != nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	accessToken, refreshToken, _, err := utilities.RotateRefreshToken(r.Context(), h.store, cookie.Value)
+	if err != nil {
+		if errors.Is(err, utilities.ErrRedisLookupFailed) || errors.Is(err, utilities.ErrSessionStorage) || errors.Is(err, utilities.ErrTokenGeneration) {
+			log.Println("Failed to refresh session:", err)
+			http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		if errors.Is(err, utilities.ErrRefreshTokenReused) {
+			log.Println("Refresh token reuse detected, session revoked")
+		}
+		utilities.ClearRefreshTokenCookie(w)
+		http.Error(w, `{"error": "Session expired, please log in again"}`, http.StatusUnauthorized)
+		return
+	}
+
+	utilities.SetRefreshTokenCookie(w, refreshToken)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"accessToken": accessToken})
+}
+
+// Logout signs the current device out, for both users and the admin panel.
+// The cookie is cleared even when the refresh token is no longer valid.
+func (h *SessionHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cookie, err := r.Cookie(utilities.RefreshTokenCookieName)
+	if err != nil {
+		utilities.ClearRefreshTokenCookie(w)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	claims, err := utilities.ValidateRefreshToken(r.Context(), h.store, cookie.Value)
+	if err != nil {
+		if errors.Is(err, utilities.ErrRedisLookupFailed) || errors.Is(err, utilities.ErrSessionStorage) {
+			log.Println("Failed to validate refresh token on logout:", err)
+			http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		// Nothing left to revoke
+		utilities.ClearRefreshTokenCookie(w)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := utilities.RevokeUserSession(r.Context(), h.store, claims.SessionID); err != nil {
+		log.Println("Failed to revoke session on logout:", err)
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	utilities.ClearRefreshTokenCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSessions returns every device the user is signed in on, most recently used first
+func (h *SessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := utilities.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	currentSessionID, _ := utilities.GetSessionID(r.Context())
+
+	sessions, err := h.store.ListSessions(r.Context(), userID)
+	if err != nil {
+		log.Println("Failed to list sessions:", err)
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	response := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, sessionResponse{Session: session, Current: session.ID == currentSessionID})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RevokeSession signs one of the user's devices out
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := utilities.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID := r.PathValue("sessionId")
+	session, err := h.store.GetSession(r.Context(), sessionID)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Println("Failed to look up session:", err)
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+	// Other users' sessions are reported as missing so their IDs cannot be probed
+	if err != nil || session.UserID != userID {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	if err := utilities.RevokeUserSession(r.Context(), h.store, sessionID); err != nil {
+		log.Println("Failed to revoke session:", err)
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeOtherSessions signs the user out everywhere except the device making the request
+func (h *SessionHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := utilities.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	currentSessionID, ok := utilities.GetSessionID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	revoked, err := utilities.RevokeOtherSessions(r.Context(), h.store, userID, currentSessionID)
+	if err != nil {
+		log.Println("Failed to revoke sessions:", err)
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"revoked": revoked})
+}
@@ -3,9 +3,12 @@ package handlers
 import (
 	"backend/internal/services"
 	"backend/pkg/middleware"
+	"backend/pkg/passwordpolicy"
 	"backend/pkg/ratelimiter"
 	"backend/pkg/utilities"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 )
@@ -31,6 +34,16 @@ func (h *UserHandler) RegisterRoutes(router *http.ServeMux) {
 		http.HandlerFunc(h.GetUser),
 		userStack...,
 	))
+
+	passwordStack := []middleware.Middleware{
+		middleware.TokenAuthMiddleware(h.store),
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "user_password"),
+	}
+
+	router.Handle("/api/user/password", middleware.Chain(
+		http.HandlerFunc(h.ChangePassword),
+		passwordStack...,
+	))
 }
 
 func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
@@ -50,5 +63,68 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
+// ChangePassword sets a new password after checking the current one, then signs the
+// user out everywhere except the device that made the change
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if utilities.IsInstanceAdmin(r.Context()) {
+		http.Error(w, "Admin accounts cannot change a user password", http.StatusForbidden)
+		return
+	}
+	userID, ok := utilities.GetUserID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	currentSessionID, ok := utilities.GetSessionID(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err := h.userService.ChangePassword(r.Context(), userID, req.CurrentPassword, req.NewPassword, clientInfo(r))
+	if err != nil {
+		var lockoutErr *services.LockoutError
+		var policyErr *passwordpolicy.ValidationError
+		switch {
+		case errors.As(err, &lockoutErr):
+			writeLockoutError(w, lockoutErr)
+		case errors.As(err, &policyErr):
+			writePasswordPolicyError(w, policyErr)
+		case errors.Is(err, services.ErrInvalidPassword):
+			http.Error(w, "Current password is incorrect", http.StatusForbidden)
+		case errors.Is(err, services.ErrExternalAccount):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			fmt.Println("Change password error:", err)
+			http.Error(w, "Failed to change password", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	revoked, err := utilities.RevokeOtherSessions(r.Context(), h.store, userID, currentSessionID)
+	if err != nil {
+		fmt.Println("Failed to revoke sessions after password change:", err)
+		http.Error(w, "Password changed, but other sessions could not be signed out", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"revoked": revoked})
+}
+
 func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 }
\ No newline at end of file
@@ -3,25 +3,32 @@ package handlers
 import (
 	"backend/internal/services"
 	"backend/pkg/middleware"
+	"backend/pkg/passwordpolicy"
 	"backend/pkg/ratelimiter"
 	"backend/pkg/utilities"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
-	"os"
+	"strconv"
 	"time"
 )
 type UserAuthHandler struct {
-	userService *services.UserService
-	store       utilities.SessionStore
-	limiter     ratelimiter.RateLimiter
+	userService          *services.UserService
+	mfaService           *services.MFAService
+	passwordResetService *services.PasswordResetService
+	store                utilities.SessionStore
+	limiter              ratelimiter.RateLimiter
 }
 
-func NewUserAuthHandler(userService *services.UserService, store utilities.SessionStore, limiter ratelimiter.RateLimiter) *UserAuthHandler {
+func NewUserAuthHandler(userService *services.UserService, mfaService *services.MFAService, passwordResetService *services.PasswordResetService, store utilities.SessionStore, limiter ratelimiter.RateLimiter) *UserAuthHandler {
 	return &UserAuthHandler{
-		userService: userService,
-		store:       store,
-		limiter:     limiter,
+		userService:          userService,
+		mfaService:           mfaService,
+		passwordResetService: passwordResetService,
+		store:                store,
+		limiter:              limiter,
 	}
 }
 
@@ -34,8 +41,22 @@ func (h *UserAuthHandler) RegisterRoutes(router *http.ServeMux) {
 		http.HandlerFunc(h.Login),
 		userAuthStack...,
 	))
+	router.Handle("/api/auth/user-login/mfa", middleware.Chain(
+		http.HandlerFunc(h.VerifyMFA),
+		userAuthStack...,
+	))
+	router.Handle("/api/auth/user-login/mfa/enroll", middleware.Chain(
+		http.HandlerFunc(h.EnrollMFA),
+		userAuthStack...,
+	))
+	router.Handle("/api/auth/password-reset", middleware.Chain(
+		http.HandlerFunc(h.ResetPassword),
+		userAuthStack...,
+	))
 }
 
+// Login checks the password. Users with two-factor authentication get an MFA challenge
+// token to redeem at /api/auth/user-login/mfa instead of a session.
 func (h *UserAuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -51,41 +72,219 @@ func (h *UserAuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.Login(r.Context(), credentials.Username, credentials.Password)
+	user, err := h.userService.Login(r.Context(), credentials.Username, credentials.Password, clientInfo(r))
+	if err != nil {
+		var lockoutErr *services.LockoutError
+		switch {
+		case errors.As(err, &lockoutErr):
+			writeLockoutError(w, lockoutErr)
+		case errors.Is(err, services.ErrInvalidPassword):
+			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		case errors.Is(err, services.ErrAccountPending),
+			errors.Is(err, services.ErrAccountRejected):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			fmt.Println("Login error:", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	challenge, err := h.mfaService.StartLoginChallenge(r.Context(), user.Id.String())
+	if err != nil {
+		var lockoutErr *services.LockoutError
+		if errors.As(err, &lockoutErr) {
+			writeLockoutError(w, lockoutErr)
+			return
+		}
+		fmt.Println("MFA challenge error:", err)
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+	if challenge != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"mfaRequired":           true,
+			"mfaEnrollmentRequired": challenge.EnrollmentRequired,
+			"mfaToken":              challenge.Token,
+		})
+		return
+	}
+
+	accessToken, ok := h.startSession(w, r, user.Id.String())
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"accessToken": accessToken})
+}
+
+// VerifyMFA finishes a two-step login with a TOTP or recovery code. When the challenge
+// completed a required enrollment, the new recovery codes are returned alongside the token.
+func (h *UserAuthHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		MFAToken string `json:"mfaToken"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, recoveryCodes, err := h.mfaService.CompleteLoginChallenge(r.Context(), req.MFAToken, req.Code)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	accessToken, ok := h.startSession(w, r, userID)
+	if !ok {
+		return
+	}
+
+	response := map[string]any{"accessToken": accessToken}
+	if recoveryCodes != nil {
+		response["recoveryCodes"] = recoveryCodes
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// EnrollMFA returns a new TOTP secret for a user whose login requires enrolling first
+func (h *UserAuthHandler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		MFAToken string `json:"mfaToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	enrollment, err := h.mfaService.ChallengeEnrollment(r.Context(), req.MFAToken)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// ResetPassword redeems an admin-issued reset token. Every session the user had is
+// revoked, so they sign in again with the new password.
+func (h *UserAuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.passwordResetService.Redeem(r.Context(), req.Token, req.Password, clientInfo(r))
 	if err != nil {
-		fmt.Println("Login error:", err)
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		var policyErr *passwordpolicy.ValidationError
+		switch {
+		case errors.As(err, &policyErr):
+			writePasswordPolicyError(w, policyErr)
+		case errors.Is(err, services.ErrInvalidResetToken):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrExternalAccount):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			fmt.Println("Password reset error:", err)
+			http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		}
 		return
 	}
 
-	accessToken, err := utilities.GenerateAccessToken(r.Context(), h.store, user.Id.String())
+	if _, err := utilities.RevokeAllUserSessions(r.Context(), h.store, userID); err != nil {
+		fmt.Println("Failed to revoke sessions after password reset:", err)
+		http.Error(w, "Password reset, but existing sessions could not be signed out", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// startSession signs the user in on this device, setting the refresh cookie
+func (h *UserAuthHandler) startSession(w http.ResponseWriter, r *http.Request, userID string) (string, bool) {
+	session, err := utilities.StartSession(r.Context(), h.store, userID, r.UserAgent(), utilities.ClientIP(r))
+	if err != nil {
+		fmt.Println("Session creation error:", err)
+		http.Error(w, "Failed to create session", http.StatusInternalServerError)
+		return "", false
+	}
+
+	accessToken, err := utilities.GenerateAccessToken(r.Context(), h.store, userID, session.ID)
 	if err != nil {
 		fmt.Println("Access token generation error:", err)
 		http.Error(w, "Failed to generate access token", http.StatusInternalServerError)
-		return
+		return "", false
 	}
 
-	refreshToken, err := utilities.GenerateRefreshToken(r.Context(), h.store, user.Id.String())
+	refreshToken, err := utilities.GenerateRefreshToken(r.Context(), h.store, userID, session.ID)
 	if err != nil {
 		fmt.Println("Refresh token generation error:", err)
 		http.Error(w, "Failed to generate refresh token", http.StatusInternalServerError)
-		return
+		return "", false
 	}
 
-	maxAge := 3600 * 24 * 30
-	sameSite := http.SameSiteLaxMode
-	if os.Getenv("DEV") != "" {
-		sameSite = http.SameSiteNoneMode
-	}
-	http.SetCookie(w, &http.Cookie{
-		Name:     utilities.RefreshTokenCookieName,
-		Value:    refreshToken,
-		Path:     "/",
-		MaxAge:   maxAge,
-		HttpOnly: true,
-		SameSite: sameSite,
-		Secure:   true,
-	})
+	utilities.SetRefreshTokenCookie(w, refreshToken)
+	return accessToken, true
+}
+
+// clientInfo describes the client for lockouts and security events
+func clientInfo(r *http.Request) services.ClientInfo {
+	return services.ClientInfo{IP: utilities.ClientIP(r), UserAgent: r.UserAgent()}
+}
+
+// writeLockoutError rejects a locked-out login, telling the client when to retry
+func writeLockoutError(w http.ResponseWriter, lockoutErr *services.LockoutError) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockoutErr.RetryAfter.Seconds()))))
+	http.Error(w, "Too many failed attempts. Please try again later.", http.StatusTooManyRequests)
+}
+
+// writePasswordPolicyError lists the policy violations in a form the frontend can display
+func writePasswordPolicyError(w http.ResponseWriter, validationErr *passwordpolicy.ValidationError) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"accessToken": accessToken})
-}
\ No newline at end of file
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error":      "Password does not meet the password policy",
+		"violations": validationErr.Violations,
+	})
+}
+
+func writeMFAError(w http.ResponseWriter, err error) {
+	var lockoutErr *services.LockoutError
+	switch {
+	case errors.As(err, &lockoutErr):
+		writeLockoutError(w, lockoutErr)
+	case errors.Is(err, services.ErrInvalidMFACode),
+		errors.Is(err, services.ErrInvalidMFAChallenge):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, services.ErrMFAAlreadyEnabled),
+		errors.Is(err, services.ErrMFANotEnabled),
+		errors.Is(err, services.ErrMFAEnrollmentNotFound),
+		errors.Is(err, services.ErrMFARequired):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrMFAChallengeNotEnroll):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		fmt.Println("MFA error:", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
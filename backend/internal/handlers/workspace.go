@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"backend/internal/models"
+	"backend/internal/realtime"
 	"backend/internal/repos"
 	"backend/pkg/middleware"
 	"backend/pkg/ratelimiter"
@@ -16,14 +18,16 @@ type WorkspaceHandler struct {
 	store            utilities.SessionStore
 	limiter          ratelimiter.RateLimiter
 	permissionChecker *utilities.PermissionChecker
+	events           realtime.Publisher
 }
 
-func NewWorkspaceHandler(workspaceRepo *repos.WorkspaceRepo, store utilities.SessionStore, limiter ratelimiter.RateLimiter, permissionChecker *utilities.PermissionChecker) *WorkspaceHandler {
+func NewWorkspaceHandler(workspaceRepo *repos.WorkspaceRepo, store utilities.SessionStore, limiter ratelimiter.RateLimiter, permissionChecker *utilities.PermissionChecker, events realtime.Publisher) *WorkspaceHandler {
 	return &WorkspaceHandler{
 		workspaceRepo:   workspaceRepo,
 		store:           store,
 		limiter:        limiter,
 		permissionChecker: permissionChecker,
+		events:          events,
 	}
 }
 
@@ -33,10 +37,17 @@ func (h *WorkspaceHandler) RegisterRoutes(router *http.ServeMux) {
 		middleware.RateLimitMiddleware(h.limiter, time.Minute, "workspace_read"),
 	}
 
+	// Reading a workspace lists its members and channels, so it takes a role there
+	viewStack := []middleware.Middleware{
+		middleware.TokenAuthMiddleware(h.store),
+		middleware.RateLimitMiddleware(h.limiter, time.Minute, "workspace_read"),
+		middleware.PermissionMiddleware(h.permissionChecker, "workspace:view-channels"),
+	}
+
 	modifcationStack:= []middleware.Middleware{
 		middleware.TokenAuthMiddleware(h.store),
 		middleware.RateLimitMiddleware(h.limiter, time.Minute, "workspace_modify"),
-		middleware.PermissionMiddleware(h.permissionChecker, "admin", "moderator"),
+		middleware.PermissionMiddleware(h.permissionChecker, "workspace:manage-channels", "workspace:create-channels"),
 	}
 
 	router.Handle("/api/workspaces", middleware.Chain(
@@ -45,7 +56,7 @@ func (h *WorkspaceHandler) RegisterRoutes(router *http.ServeMux) {
 	))
 	router.Handle("/api/workspaces/{workspaceId}", middleware.Chain(
 		http.HandlerFunc(h.GetWorkspace),
-		stack...,
+		viewStack...,
 	))
 	router.Handle("/api/workspaces/{workspaceId}/channels", middleware.Chain(
 		http.HandlerFunc(h.CreateChannel),
@@ -122,6 +133,7 @@ func (h *WorkspaceHandler) CreateChannel(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Failed to create channel", http.StatusInternalServerError)
 		return
 	}
+	h.events.Publish(r.Context(), realtime.NewEvent(models.EventChannelCreated, workspaceId, channel))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(channel)
 }
\ No newline at end of file
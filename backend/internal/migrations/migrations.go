@@ -0,0 +1,291 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// advisoryLockKey serialises migrations across replicas starting at the same time
+const advisoryLockKey int64 = 0x6277_6d69_6772 // "bwmigr"
+
+var (
+	ErrNothingToRollback = errors.New("no applied migrations to roll back")
+	ErrUnknownMigration  = errors.New("database has a migration this binary does not know about")
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+type Runner struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+func NewRunner(db *pgxpool.Pool) (*Runner, error) {
+	migrations, err := Load(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{db: db, migrations: migrations}, nil
+}
+
+// Load reads NNNN_name.up.sql / NNNN_name.down.sql pairs from fsys, ordered by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.Glob(fsys, "sql/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		base := path.Base(entry)
+		stem, direction, found := strings.Cut(strings.TrimSuffix(base, ".sql"), ".")
+		if !found || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name.up.sql or NNNN_name.down.sql", base)
+		}
+		versionPart, name, found := strings.Cut(stem, "_")
+		if !found {
+			return nil, fmt.Errorf("migration %s: missing name", base)
+		}
+		version, err := strconv.Atoi(versionPart)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version", base)
+		}
+
+		contents, err := fs.ReadFile(fsys, entry)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, migration.Name, name)
+		}
+		if direction == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be contiguous, expected %d but found %d", i+1, migration.Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Up applies every pending migration, then seeds reference data. It returns the
+// migrations that were applied.
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := r.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := r.checkKnown(done); err != nil {
+			return err
+		}
+
+		for _, migration := range r.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := r.apply(ctx, conn, migration.Version, migration.Name, migration.Up, true); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+
+		return Seed(ctx, conn)
+	})
+	return applied, err
+}
+
+// Down rolls back the most recent steps migrations and returns them in rollback order
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var rolledBack []Migration
+	err := r.withLock(ctx, func(conn *pgxpool.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := r.checkKnown(done); err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			return ErrNothingToRollback
+		}
+
+		for i := len(r.migrations) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+			migration := r.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if err := r.apply(ctx, conn, migration.Version, migration.Name, migration.Down, false); err != nil {
+				return err
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Status lists every known migration and whether it has been applied
+func (r *Runner) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := r.db.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	done, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(r.migrations))
+	for _, migration := range r.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := done[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// apply runs one migration direction and records it in schema_migrations atomically
+func (r *Runner) apply(ctx context.Context, conn *pgxpool.Conn, version int, name string, script string, up bool) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, script); err != nil {
+		direction := "down"
+		if up {
+			direction = "up"
+		}
+		return fmt.Errorf("migration %04d_%s (%s) failed: %w", version, name, direction, err)
+	}
+
+	if up {
+		_, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, version, name)
+	} else {
+		_, err = tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %04d_%s: %w", version, name, err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *Runner) checkKnown(done map[int]time.Time) error {
+	for version := range done {
+		if version > len(r.migrations) {
+			return fmt.Errorf("%w: version %d", ErrUnknownMigration, version)
+		}
+	}
+	return nil
+}
+
+// withLock holds a session-level advisory lock on a dedicated connection while fn runs
+func (r *Runner) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := r.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockKey); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, advisoryLockKey)
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func ensureMigrationsTable(ctx context.Context, conn *pgxpool.Conn) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`
+	if _, err := conn.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	done := make(map[int]time.Time)
+	var version int
+	var appliedAt time.Time
+	_, err = pgx.ForEachRow(rows, []any{&version, &appliedAt}, func() error {
+		done[version] = appliedAt
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+	}
+	return done, nil
+}
@@ -0,0 +1,84 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	migrations, err := Load(files)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("expected at least one embedded migration")
+	}
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("migration %d has version %d", i, migration.Version)
+		}
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			t.Errorf("migration %04d_%s has an empty up or down script", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestLoadRejectsBadLayouts(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{
+			name: "missing down",
+			files: fstest.MapFS{
+				"sql/0001_init.up.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+		{
+			name: "gap in versions",
+			files: fstest.MapFS{
+				"sql/0001_init.up.sql":   {Data: []byte("SELECT 1;")},
+				"sql/0001_init.down.sql": {Data: []byte("SELECT 1;")},
+				"sql/0003_next.up.sql":   {Data: []byte("SELECT 1;")},
+				"sql/0003_next.down.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+		{
+			name: "unknown direction",
+			files: fstest.MapFS{
+				"sql/0001_init.sideways.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+		{
+			name: "conflicting names",
+			files: fstest.MapFS{
+				"sql/0001_init.up.sql":    {Data: []byte("SELECT 1;")},
+				"sql/0001_other.down.sql": {Data: []byte("SELECT 1;")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.files); err == nil {
+				t.Error("expected Load() to fail")
+			}
+		})
+	}
+}
+
+func TestLoadOrdersByVersion(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"sql/0002_second.up.sql":   {Data: []byte("SELECT 2;")},
+		"sql/0002_second.down.sql": {Data: []byte("SELECT 2;")},
+		"sql/0001_first.up.sql":    {Data: []byte("SELECT 1;")},
+		"sql/0001_first.down.sql":  {Data: []byte("SELECT 1;")},
+	})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Name != "first" || migrations[1].Name != "second" {
+		t.Fatalf("unexpected order: %+v", migrations)
+	}
+}
@@ -0,0 +1,155 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type seedPermission struct {
+	Name        string
+	Description string
+}
+
+type seedRole struct {
+	Name        string
+	Description string
+	// Permissions lists the role's default grants; nil means every permission
+	Permissions []string
+}
+
+var defaultPermissions = []seedPermission{
+	{"workspace:manage-users", "Manage users in the workspace (invite, remove, edit user info)"},
+	{"workspace:manage-teams", "Create, edit, and delete teams"},
+	{"workspace:manage-channels", "Create, edit, and delete channels"},
+	{"workspace:manage-roles", "Create, edit, delete roles and assign permissions to roles"},
+	{"workspace:send-messages", "Send messages in channels"},
+	{"workspace:delete-any-message", "Delete any message in channels"},
+	{"workspace:delete-own-message", "Delete own messages"},
+	{"workspace:edit-any-message", "Edit any message in channels"},
+	{"workspace:edit-own-message", "Edit own messages"},
+	{"workspace:pin-messages", "Pin and unpin messages"},
+	{"workspace:manage-workspace", "Edit workspace settings, name, image, etc."},
+	{"workspace:view-channels", "View and read messages in channels"},
+	{"workspace:create-channels", "Create new channels"},
+	{"workspace:archive-channels", "Archive and unarchive channels"},
+	{"workspace:manage-reactions", "Add and remove reactions to messages"},
+	{"workspace:upload-files", "Upload files to channels"},
+}
+
+var defaultRoles = []seedRole{
+	{
+		Name:        "Owner",
+		Description: "Full access to all workspace features and settings",
+	},
+	{
+		Name:        "Admin",
+		Description: "Administrative access with user and team management capabilities",
+		Permissions: []string{
+			"workspace:manage-users",
+			"workspace:manage-teams",
+			"workspace:manage-channels",
+			"workspace:send-messages",
+			"workspace:delete-any-message",
+			"workspace:delete-own-message",
+			"workspace:edit-any-message",
+			"workspace:edit-own-message",
+			"workspace:pin-messages",
+			"workspace:view-channels",
+			"workspace:create-channels",
+			"workspace:archive-channels",
+			"workspace:manage-reactions",
+			"workspace:upload-files",
+		},
+	},
+	{
+		Name:        "Moderator",
+		Description: "Moderate messages and manage channels",
+		Permissions: []string{
+			"workspace:manage-channels",
+			"workspace:send-messages",
+			"workspace:delete-any-message",
+			"workspace:delete-own-message",
+			"workspace:edit-any-message",
+			"workspace:edit-own-message",
+			"workspace:pin-messages",
+			"workspace:view-channels",
+			"workspace:create-channels",
+			"workspace:archive-channels",
+			"workspace:manage-reactions",
+			"workspace:upload-files",
+		},
+	},
+	{
+		Name:        "Member",
+		Description: "Standard user with basic messaging capabilities",
+		Permissions: []string{
+			"workspace:send-messages",
+			"workspace:delete-own-message",
+			"workspace:edit-own-message",
+			"workspace:view-channels",
+			"workspace:manage-reactions",
+			"workspace:upload-files",
+		},
+	},
+	{
+		Name:        "Guest",
+		Description: "Limited access for temporary users",
+		Permissions: []string{
+			"workspace:send-messages",
+			"workspace:delete-own-message",
+			"workspace:edit-own-message",
+			"workspace:view-channels",
+			"workspace:manage-reactions",
+		},
+	},
+}
+
+// Seed inserts the built-in permissions and default roles. It is safe to run on
+// every start: existing rows are left alone, and a role's default grants are only
+// applied when the role is first created so admins can edit them afterwards.
+func Seed(ctx context.Context, conn *pgxpool.Conn) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, permission := range defaultPermissions {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO permissions (name, description) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING`,
+			permission.Name, permission.Description)
+		if err != nil {
+			return fmt.Errorf("failed to seed permission %s: %w", permission.Name, err)
+		}
+	}
+
+	for _, role := range defaultRoles {
+		var roleID int
+		err := tx.QueryRow(ctx,
+			`INSERT INTO roles (name, description) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING RETURNING id`,
+			role.Name, role.Description).Scan(&roleID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				// The role already exists
+				continue
+			}
+			return fmt.Errorf("failed to seed role %s: %w", role.Name, err)
+		}
+
+		grant := `
+			INSERT INTO role_permissions (role_id, permission_id)
+			SELECT $1, p.id FROM permissions p
+			WHERE $2::text[] IS NULL OR p.name = ANY($2)
+			ON CONFLICT DO NOTHING
+		`
+		if _, err := tx.Exec(ctx, grant, roleID, role.Permissions); err != nil {
+			return fmt.Errorf("failed to seed permissions for role %s: %w", role.Name, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS workspace_channel_message_replies;
+DROP TABLE IF EXISTS workspace_channel_message_reactions;
+DROP TABLE IF EXISTS workspace_channel_messages;
+DROP TABLE IF EXISTS workspace_channels;
+DROP TABLE IF EXISTS team_users;
+DROP TABLE IF EXISTS workspace_teams;
+DROP TABLE IF EXISTS teams;
+DROP TABLE IF EXISTS workspace_users;
+DROP TABLE IF EXISTS workspace_user_roles;
+DROP TABLE IF EXISTS workspaces;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS users;
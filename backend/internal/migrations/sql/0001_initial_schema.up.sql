@@ -0,0 +1,111 @@
+-- Baseline schema, formerly docker/init.sql. Every statement is idempotent so
+-- deployments that were bootstrapped from init.sql can adopt migrations safely.
+
+CREATE TABLE IF NOT EXISTS users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    username VARCHAR(255) NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    image_path VARCHAR(255)
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_username ON users (username);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT
+);
+
+CREATE TABLE IF NOT EXISTS roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id INT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission_id INT NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS workspaces (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    image_path VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS workspace_user_roles (
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id INT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    assigned_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (workspace_id, user_id, role_id)
+);
+
+CREATE TABLE IF NOT EXISTS workspace_users (
+    workspace_id UUID NOT NULL REFERENCES workspaces(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+    PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS teams (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS workspace_teams (
+    workspace_id UUID NOT NULL REFERENCES workspaces(id),
+    team_id UUID NOT NULL REFERENCES teams(id),
+    PRIMARY KEY (workspace_id, team_id)
+);
+
+CREATE TABLE IF NOT EXISTS team_users (
+    team_id UUID NOT NULL REFERENCES teams(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+    PRIMARY KEY (team_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS workspace_channels (
+    id SERIAL PRIMARY KEY,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id),
+    channel_name VARCHAR(255) NOT NULL,
+    channel_emoji VARCHAR(255) NOT NULL DEFAULT '💬'
+);
+
+CREATE TABLE IF NOT EXISTS workspace_channel_messages (
+    id SERIAL PRIMARY KEY,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id),
+    channel_id INT NOT NULL REFERENCES workspace_channels(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+    message TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_channel_messages_created_at ON workspace_channel_messages (created_at);
+
+-- Keyset pagination over a channel's history walks (created_at, id) backwards
+CREATE INDEX IF NOT EXISTS idx_workspace_channel_messages_channel_created_at_id ON workspace_channel_messages (channel_id, created_at DESC, id DESC);
+
+CREATE TABLE IF NOT EXISTS workspace_channel_message_reactions (
+    id SERIAL PRIMARY KEY,
+    message_id INT NOT NULL REFERENCES workspace_channel_messages(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+    reaction VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS workspace_channel_message_replies (
+    id SERIAL PRIMARY KEY,
+    message_id INT NOT NULL REFERENCES workspace_channel_messages(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+    reply TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
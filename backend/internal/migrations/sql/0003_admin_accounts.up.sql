@@ -0,0 +1,18 @@
+-- Named admin panel operators, replacing the shared ADMIN_PANEL_PASSWORD.
+-- Create the first one with `server admin create`.
+CREATE TABLE IF NOT EXISTS admin_accounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    username VARCHAR(50) NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    -- TOTP second factor; totp_confirmed_at stays NULL until enrollment is verified
+    totp_secret VARCHAR(64),
+    totp_confirmed_at TIMESTAMP,
+    totp_last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_by UUID REFERENCES admin_accounts(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP,
+    disabled_at TIMESTAMP
+);
+
+-- The shared admin TOTP secret belonged to the old panel password
+DELETE FROM instance_settings WHERE key = 'admin_totp_secret';
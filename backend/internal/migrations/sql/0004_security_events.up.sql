@@ -0,0 +1,15 @@
+-- Authentication events for admins to review, such as failed logins and lockouts
+CREATE TABLE IF NOT EXISTS security_events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(50) NOT NULL,
+    -- Username as submitted, which may not belong to any account
+    username VARCHAR(255),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ip VARCHAR(64),
+    user_agent TEXT,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_created_at ON security_events (created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_security_events_username ON security_events (username, created_at DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS workspace_invite_uses;
+DROP TABLE IF EXISTS workspace_invites;
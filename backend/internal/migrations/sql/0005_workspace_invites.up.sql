@@ -0,0 +1,26 @@
+-- Invitations that let members holding workspace:manage-users bring people into a
+-- workspace. Only a hash of each code is stored; the code is shown once, on creation.
+CREATE TABLE IF NOT EXISTS workspace_invites (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL UNIQUE,
+    role_id INT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    -- Exactly one of these is set: a workspace member, or an instance admin
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_by_admin UUID REFERENCES admin_accounts(id) ON DELETE SET NULL,
+    -- NULL allows unlimited uses until the invite expires
+    max_uses INT CHECK (max_uses > 0),
+    use_count INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_invites_workspace_created_at ON workspace_invites (workspace_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS workspace_invite_uses (
+    invite_id UUID NOT NULL REFERENCES workspace_invites(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (invite_id, user_id)
+);
@@ -0,0 +1,6 @@
+-- Self-registered accounts may wait for admin approval before they can sign in.
+-- Rejected accounts are kept, since they may already have joined a workspace.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'pending', 'rejected'));
+
+CREATE INDEX IF NOT EXISTS idx_users_pending ON users (created_at) WHERE status = 'pending';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_users_auth_source;
+ALTER TABLE users DROP COLUMN IF EXISTS auth_source;
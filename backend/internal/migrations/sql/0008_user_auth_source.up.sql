@@ -0,0 +1,6 @@
+-- Where a user's password is checked. Directory users sign in against LDAP, so
+-- they have no usable local password and cannot set one.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS auth_source VARCHAR(20) NOT NULL DEFAULT 'local'
+    CHECK (auth_source IN ('local', 'ldap'));
+
+CREATE INDEX IF NOT EXISTS idx_users_auth_source ON users (auth_source) WHERE auth_source <> 'local';
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS bot_accounts;
+
+-- Former bots cannot sign in without a password, so keep them locked out
+UPDATE users SET auth_source = 'local', status = 'rejected' WHERE auth_source = 'bot';
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_auth_source_check;
+ALTER TABLE users ADD CONSTRAINT users_auth_source_check
+    CHECK (auth_source IN ('local', 'ldap'));
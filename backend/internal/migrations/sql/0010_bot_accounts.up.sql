@@ -0,0 +1,19 @@
+-- Bot accounts are users without a password that act through API tokens. Each bot
+-- belongs to the workspace it was created in and can only hold roles there.
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_auth_source_check;
+ALTER TABLE users ADD CONSTRAINT users_auth_source_check
+    CHECK (auth_source IN ('local', 'ldap', 'bot'));
+
+CREATE TABLE IF NOT EXISTS bot_accounts (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    -- Exactly one of these is set: a workspace member, or an instance admin
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_by_admin UUID REFERENCES admin_accounts(id) ON DELETE SET NULL,
+    -- Deactivated bots keep their user row so their messages keep an author
+    deactivated_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_bot_accounts_workspace_created_at ON bot_accounts (workspace_id, created_at DESC);
@@ -0,0 +1,15 @@
+-- Messages without an author cannot survive user_id becoming required again
+DELETE FROM workspace_channel_message_reactions
+WHERE message_id IN (SELECT id FROM workspace_channel_messages WHERE user_id IS NULL);
+DELETE FROM workspace_channel_message_replies
+WHERE message_id IN (SELECT id FROM workspace_channel_messages WHERE user_id IS NULL);
+DELETE FROM workspace_channel_messages WHERE user_id IS NULL;
+
+ALTER TABLE workspace_channel_messages DROP CONSTRAINT IF EXISTS workspace_channel_messages_author_check;
+ALTER TABLE workspace_channel_messages
+    DROP COLUMN IF EXISTS attachments,
+    DROP COLUMN IF EXISTS display_name,
+    DROP COLUMN IF EXISTS webhook_id;
+ALTER TABLE workspace_channel_messages ALTER COLUMN user_id SET NOT NULL;
+
+DROP TABLE IF EXISTS incoming_webhooks;
@@ -0,0 +1,25 @@
+-- Incoming webhooks let external services post into a channel through a secret URL.
+-- Only the token's hash is stored; the token itself is shown once when created.
+CREATE TABLE IF NOT EXISTS incoming_webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    channel_id INT NOT NULL REFERENCES workspace_channels(id) ON DELETE CASCADE,
+    name VARCHAR(80) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    -- Exactly one of these is set: a workspace member, or an instance admin
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_by_admin UUID REFERENCES admin_accounts(id) ON DELETE SET NULL,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_incoming_webhooks_channel ON incoming_webhooks (workspace_id, channel_id, created_at DESC);
+
+-- Webhook messages have no author; they show the webhook's display name instead
+ALTER TABLE workspace_channel_messages ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE workspace_channel_messages
+    ADD COLUMN IF NOT EXISTS webhook_id UUID REFERENCES incoming_webhooks(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS display_name VARCHAR(80),
+    ADD COLUMN IF NOT EXISTS attachments JSONB;
+ALTER TABLE workspace_channel_messages ADD CONSTRAINT workspace_channel_messages_author_check
+    CHECK (user_id IS NOT NULL OR display_name IS NOT NULL);
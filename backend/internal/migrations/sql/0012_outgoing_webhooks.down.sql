@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS outgoing_webhook_deliveries;
+DROP TABLE IF EXISTS outgoing_webhooks;
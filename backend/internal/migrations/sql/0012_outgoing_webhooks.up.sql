@@ -0,0 +1,39 @@
+-- Outgoing webhooks POST signed workspace events to external URLs. The secret is kept
+-- in full because every delivery is signed with it.
+CREATE TABLE IF NOT EXISTS outgoing_webhooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workspace_id UUID NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    name VARCHAR(80) NOT NULL,
+    url TEXT NOT NULL,
+    secret VARCHAR(100) NOT NULL,
+    events TEXT[] NOT NULL,
+    -- Message filters: only this channel, and only messages starting with a trigger word
+    channel_id INT REFERENCES workspace_channels(id) ON DELETE CASCADE,
+    trigger_words TEXT[] NOT NULL DEFAULT '{}',
+    -- Exactly one of these is set: a workspace member, or an instance admin
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_by_admin UUID REFERENCES admin_accounts(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outgoing_webhooks_workspace ON outgoing_webhooks (workspace_id, created_at DESC);
+
+-- Deliveries are the worker's queue and the delivery history. Dead deliveries
+-- exhausted their retries and stay as the dead-letter log until redelivered or pruned.
+CREATE TABLE IF NOT EXISTS outgoing_webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id UUID NOT NULL REFERENCES outgoing_webhooks(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_status_code INT,
+    last_error TEXT,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outgoing_webhook_deliveries_due ON outgoing_webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_outgoing_webhook_deliveries_webhook_created_at ON outgoing_webhook_deliveries (webhook_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_outgoing_webhook_deliveries_created_at ON outgoing_webhook_deliveries (created_at) WHERE status <> 'pending';
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// AdminAccount is a named operator of the admin panel. Every admin account is an
+// instance admin: its sessions bypass workspace permission checks.
+type AdminAccount struct {
+	Id               pgtype.UUID      `json:"id"`
+	Username         string           `json:"username"`
+	PasswordHash     string           `json:"-"`
+	TOTPSecret       pgtype.Text      `json:"-"`
+	TOTPConfirmedAt  pgtype.Timestamp `json:"-"`
+	TOTPLastUsedStep int64            `json:"-"`
+	CreatedBy        pgtype.UUID      `json:"created_by"`
+	CreatedAt        pgtype.Timestamp `json:"created_at"`
+	LastLoginAt      pgtype.Timestamp `json:"last_login_at"`
+	DisabledAt       pgtype.Timestamp `json:"disabled_at"`
+}
+
+// TOTPEnabled reports whether logins must include a TOTP code
+func (a *AdminAccount) TOTPEnabled() bool {
+	return a.TOTPConfirmedAt.Valid
+}
+
+// Disabled accounts can no longer sign in
+func (a *AdminAccount) Disabled() bool {
+	return a.DisabledAt.Valid
+}
+
+type CreateAdminAccountRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
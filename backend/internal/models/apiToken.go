@@ -0,0 +1,26 @@
+package models
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// APIToken is a personal access token. Token is only filled in on the response that
+// created it.
+type APIToken struct {
+	ID          pgtype.UUID      `json:"id"`
+	UserID      pgtype.UUID      `json:"user_id"`
+	Name        string           `json:"name"`
+	Token       string           `json:"token,omitempty"`
+	TokenPrefix string           `json:"token_prefix"`
+	Scopes      []string         `json:"scopes"`
+	ExpiresAt   pgtype.Timestamp `json:"expires_at"`
+	LastUsedAt  pgtype.Timestamp `json:"last_used_at"`
+	CreatedAt   pgtype.Timestamp `json:"created_at"`
+}
+
+type CreateAPITokenRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresInDays of 0 creates a token that never expires
+	ExpiresInDays int `json:"expires_in_days"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Bot is a workspace's bot account. ID is the bot's user ID.
+type Bot struct {
+	ID          pgtype.UUID `json:"id"`
+	WorkspaceID pgtype.UUID `json:"workspace_id"`
+	Username    string      `json:"username"`
+	Description string      `json:"description"`
+	// CreatedBy is a user ID, or an admin account ID when CreatedByAdmin is set
+	CreatedBy         pgtype.UUID      `json:"created_by"`
+	CreatedByUsername pgtype.Text      `json:"created_by_username"`
+	CreatedByAdmin    bool             `json:"created_by_admin"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+}
+
+type CreateBotRequest struct {
+	Username    string `json:"username"`
+	Description string `json:"description"`
+	// RoleIDs are assigned to the bot in its workspace once it is created
+	RoleIDs []int `json:"role_ids"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event types pushed to real-time subscribers
+const (
+	EventMessageCreated = "message.created"
+	EventChannelCreated = "channel.created"
+	EventMemberAdded    = "member.added"
+	EventMemberRemoved  = "member.removed"
+	EventRoleAssigned   = "role.assigned"
+	EventRoleRemoved    = "role.removed"
+)
+
+type Event struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	WorkspaceID   string          `json:"workspace_id"`
+	SubjectUserID string          `json:"subject_user_id,omitempty"` // user a membership or role event is about
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+// UserIdentity links an account at an OpenID provider to a local user
+type UserIdentity struct {
+	Issuer    string           `json:"issuer"`
+	Subject   string           `json:"subject"`
+	UserID    pgtype.UUID      `json:"user_id"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+// SSOConfig tells the login page whether to offer single sign-on
+type SSOConfig struct {
+	Enabled bool   `json:"enabled"`
+	Name    string `json:"name,omitempty"`
+}
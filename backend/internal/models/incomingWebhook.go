@@ -0,0 +1,37 @@
+package models
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// IncomingWebhook posts into one channel through a secret URL. Token and URL are only
+// filled in on the response that created it.
+type IncomingWebhook struct {
+	ID          pgtype.UUID `json:"id"`
+	WorkspaceID pgtype.UUID `json:"workspace_id"`
+	ChannelID   int         `json:"channel_id"`
+	Name        string      `json:"name"`
+	Token       string      `json:"token,omitempty"`
+	URL         string      `json:"url,omitempty"`
+	// CreatedBy is a user ID, or an admin account ID when CreatedByAdmin is set
+	CreatedBy         pgtype.UUID      `json:"created_by"`
+	CreatedByUsername pgtype.Text      `json:"created_by_username"`
+	CreatedByAdmin    bool             `json:"created_by_admin"`
+	LastUsedAt        pgtype.Timestamp `json:"last_used_at"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+}
+
+type CreateIncomingWebhookRequest struct {
+	Name string `json:"name"`
+}
+
+// IncomingWebhookPayload is what an integration POSTs to a webhook URL. It follows
+// the shape of Slack's incoming webhooks so existing integrations work unchanged.
+type IncomingWebhookPayload struct {
+	Text string `json:"text"`
+	// DisplayName replaces the webhook's name on this message. Username is accepted
+	// as an alias for Slack compatibility.
+	DisplayName string              `json:"display_name"`
+	Username    string              `json:"username"`
+	Attachments []MessageAttachment `json:"attachments"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// WorkspaceInvite lets people join a workspace with a preset role. Code is only
+// filled in on the response that created the invite.
+type WorkspaceInvite struct {
+	ID          pgtype.UUID `json:"id"`
+	WorkspaceID pgtype.UUID `json:"workspace_id"`
+	Code        string      `json:"code,omitempty"`
+	RoleID      int         `json:"role_id"`
+	RoleName    string      `json:"role_name"`
+	// CreatedBy is a user ID, or an admin account ID when CreatedByAdmin is set
+	CreatedBy         pgtype.UUID          `json:"created_by"`
+	CreatedByUsername pgtype.Text          `json:"created_by_username"`
+	CreatedByAdmin    bool                 `json:"created_by_admin"`
+	MaxUses           pgtype.Int4          `json:"max_uses"`
+	UseCount          int                  `json:"use_count"`
+	ExpiresAt         pgtype.Timestamp     `json:"expires_at"`
+	RevokedAt         pgtype.Timestamp     `json:"revoked_at"`
+	CreatedAt         pgtype.Timestamp     `json:"created_at"`
+	Uses              []WorkspaceInviteUse `json:"uses"`
+}
+
+// WorkspaceInviteUse records who joined through an invite
+type WorkspaceInviteUse struct {
+	UserID   pgtype.UUID      `json:"user_id"`
+	Username string           `json:"username"`
+	UsedAt   pgtype.Timestamp `json:"used_at"`
+}
+
+// InvitePreview is what an invitee sees before accepting
+type InvitePreview struct {
+	WorkspaceID        pgtype.UUID      `json:"workspace_id"`
+	WorkspaceName      string           `json:"workspace_name"`
+	WorkspaceImagePath string           `json:"workspace_image_path"`
+	RoleName           string           `json:"role_name"`
+	ExpiresAt          pgtype.Timestamp `json:"expires_at"`
+}
+
+type CreateInviteRequest struct {
+	RoleID int `json:"role_id"`
+	// MaxUses of 0 allows any number of uses until the invite expires
+	MaxUses        int `json:"max_uses"`
+	ExpiresInHours int `json:"expires_in_hours"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type UserTOTP struct {
+	UserID       pgtype.UUID      `json:"user_id"`
+	Secret       string           `json:"-"`
+	ConfirmedAt  pgtype.Timestamp `json:"confirmed_at"`
+	LastUsedStep int64            `json:"-"`
+}
+
+// Confirmed reports whether enrollment finished, i.e. the second factor is enforced
+func (t *UserTOTP) Confirmed() bool {
+	return t.ConfirmedAt.Valid
+}
+
+type MFAStatus struct {
+	Enabled                bool `json:"enabled"`
+	Required               bool `json:"required"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
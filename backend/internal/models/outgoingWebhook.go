@@ -0,0 +1,69 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Delivery statuses. Dead deliveries failed every retry and make up the dead-letter log.
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusDead      = "dead"
+)
+
+// OutgoingWebhook sends the workspace's events to an external URL. Secret is only
+// filled in on the response that created it.
+type OutgoingWebhook struct {
+	ID          pgtype.UUID `json:"id"`
+	WorkspaceID pgtype.UUID `json:"workspace_id"`
+	Name        string      `json:"name"`
+	URL         string      `json:"url"`
+	Secret      string      `json:"secret,omitempty"`
+	Events      []string    `json:"events"`
+	// ChannelID and TriggerWords only filter message.created events
+	ChannelID    pgtype.Int4 `json:"channel_id"`
+	TriggerWords []string    `json:"trigger_words"`
+	// CreatedBy is a user ID, or an admin account ID when CreatedByAdmin is set
+	CreatedBy         pgtype.UUID      `json:"created_by"`
+	CreatedByUsername pgtype.Text      `json:"created_by_username"`
+	CreatedByAdmin    bool             `json:"created_by_admin"`
+	CreatedAt         pgtype.Timestamp `json:"created_at"`
+}
+
+type CreateOutgoingWebhookRequest struct {
+	Name   string   `json:"name"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// ChannelID limits message events to one channel; nil matches every channel
+	ChannelID *int `json:"channel_id"`
+	// TriggerWords limits message events to messages starting with one of them
+	TriggerWords []string `json:"trigger_words"`
+}
+
+type OutgoingWebhookDelivery struct {
+	ID             pgtype.UUID      `json:"id"`
+	WebhookID      pgtype.UUID      `json:"webhook_id"`
+	EventType      string           `json:"event_type"`
+	Payload        json.RawMessage  `json:"payload"`
+	Status         string           `json:"status"`
+	Attempts       int              `json:"attempts"`
+	NextAttemptAt  pgtype.Timestamp `json:"next_attempt_at"`
+	LastStatusCode pgtype.Int4      `json:"last_status_code"`
+	LastError      pgtype.Text      `json:"last_error"`
+	DeliveredAt    pgtype.Timestamp `json:"delivered_at"`
+	CreatedAt      pgtype.Timestamp `json:"created_at"`
+}
+
+// OutgoingWebhookPayload is the body POSTed to a webhook's URL
+type OutgoingWebhookPayload struct {
+	Type        string `json:"type"`
+	WorkspaceID string `json:"workspace_id"`
+	WebhookID   string `json:"webhook_id"`
+	// TriggerWord is the trigger word a message matched, if the webhook has any
+	TriggerWord string          `json:"trigger_word,omitempty"`
+	Data        json.RawMessage `json:"data"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
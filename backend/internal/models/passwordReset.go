@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// PasswordResetToken is shown to the admin who issued it, who passes it on to the user
+type PasswordResetToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// RegistrationInfo is shown to visitors so the frontend can offer the right sign-up form
+type RegistrationInfo struct {
+	Mode            string `json:"mode"`
+	RequireApproval bool   `json:"require_approval"`
+}
+
+type RegisterRequest struct {
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	InviteCode string `json:"invite_code"`
+}
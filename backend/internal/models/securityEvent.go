@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Security event types recorded for the admin dashboard
+const (
+	SecurityEventLoginFailed          = "login.failed"
+	SecurityEventLoginLockedOut       = "login.locked_out"
+	SecurityEventLoginBlocked         = "login.blocked"
+	SecurityEventAdminLoginFailed     = "admin_login.failed"
+	SecurityEventAdminLockedOut       = "admin_login.locked_out"
+	SecurityEventAdminLoginBlocked    = "admin_login.blocked"
+	SecurityEventPasswordChanged      = "password.changed"
+	SecurityEventPasswordResetSent    = "password.reset_issued"
+	SecurityEventPasswordReset        = "password.reset"
+	SecurityEventUserRegistered       = "registration.created"
+	SecurityEventRegistrationApproved = "registration.approved"
+	SecurityEventRegistrationRejected = "registration.rejected"
+	SecurityEventSSOLogin             = "sso.login"
+	SecurityEventSSOFailed            = "sso.failed"
+	SecurityEventSSOProvisioned       = "sso.provisioned"
+	SecurityEventSSOLinked            = "sso.linked"
+	SecurityEventLDAPProvisioned      = "ldap.provisioned"
+	SecurityEventAPITokenCreated      = "api_token.created"
+	SecurityEventAPITokenRevoked      = "api_token.revoked"
+)
+
+type SecurityEvent struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	Username  pgtype.Text     `json:"username"`
+	UserID    pgtype.UUID     `json:"user_id"`
+	IP        string          `json:"ip"`
+	UserAgent string          `json:"user_agent"`
+	Details   json.RawMessage `json:"details"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// SecurityEventFilter narrows the admin event listing. Zero values match everything.
+type SecurityEventFilter struct {
+	Type     string
+	Username string
+	// BeforeID pages backwards from the oldest event already shown
+	BeforeID int64
+	Limit    int
+}
@@ -0,0 +1,26 @@
+package models
+
+// Registration modes decide who may create an account without an admin
+const (
+	// RegistrationOpen lets anyone sign up
+	RegistrationOpen = "open"
+	// RegistrationInviteOnly requires a valid workspace invite code to sign up
+	RegistrationInviteOnly = "invite_only"
+	// RegistrationAdminOnly leaves account creation to the admin dashboard
+	RegistrationAdminOnly = "admin_only"
+)
+
+// InstanceSettings are instance-wide options managed from the admin dashboard
+type InstanceSettings struct {
+	RequireMFA       bool   `json:"require_mfa"`
+	RegistrationMode string `json:"registration_mode"`
+	// RequireSignupApproval holds self-registered accounts until an admin approves them
+	RequireSignupApproval bool `json:"require_signup_approval"`
+}
+
+// UpdateInstanceSettingsRequest changes only the fields that are present
+type UpdateInstanceSettingsRequest struct {
+	RequireMFA            *bool   `json:"require_mfa"`
+	RegistrationMode      *string `json:"registration_mode"`
+	RequireSignupApproval *bool   `json:"require_signup_approval"`
+}
@@ -4,10 +4,28 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// User statuses. Only active users can sign in; self-registered users wait in
+// pending until an admin approves them when the instance requires approval.
+const (
+	UserStatusActive   = "active"
+	UserStatusPending  = "pending"
+	UserStatusRejected = "rejected"
+)
+
+// Where a user's password is checked. Bots have none and act through API tokens.
+const (
+	AuthSourceLocal = "local"
+	AuthSourceLDAP  = "ldap"
+	AuthSourceBot   = "bot"
+)
+
 type User struct {
 	Id       pgtype.UUID `json:"id"`
 	Username string `json:"username"`
 	PasswordHash string `json:"password_hash"`
 	ImagePath    pgtype.Text `json:"image_path"`
 	Permissions  []string `json:"permissions"`
+	Status       string `json:"status"`
+	AuthSource   string `json:"auth_source"`
+	IsBot        bool `json:"is_bot"`
 }
\ No newline at end of file
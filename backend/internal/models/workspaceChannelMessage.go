@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type WorkspaceChannelMessage struct {
+	ID          int         `json:"id"`
+	WorkspaceID pgtype.UUID `json:"workspace_id"`
+	ChannelID   int         `json:"channel_id"`
+	// UserID is null for messages posted through an incoming webhook
+	UserID pgtype.UUID `json:"user_id"`
+	// Username is the webhook's display name for webhook messages
+	Username    string              `json:"username"`
+	IsBot       bool                `json:"is_bot"`
+	WebhookID   pgtype.UUID         `json:"webhook_id"`
+	Message     string              `json:"message"`
+	Attachments []MessageAttachment `json:"attachments,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+// MessageAttachment is a block of rich content under a message, posted by integrations
+type MessageAttachment struct {
+	Title     string                   `json:"title,omitempty"`
+	TitleLink string                   `json:"title_link,omitempty"`
+	Text      string                   `json:"text,omitempty"`
+	Color     string                   `json:"color,omitempty"`
+	Fields    []MessageAttachmentField `json:"fields,omitempty"`
+}
+
+type MessageAttachmentField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short,omitempty"`
+}
+
+// MessageCursor marks a position in a channel's history for keyset pagination
+type MessageCursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+type MessagePage struct {
+	Messages   []WorkspaceChannelMessage `json:"messages"`
+	NextCursor string                    `json:"next_cursor,omitempty"`
+}
+
+// Request DTOs
+type CreateMessageRequest struct {
+	Message string `json:"message"`
+}
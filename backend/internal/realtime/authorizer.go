@@ -0,0 +1,41 @@
+package realtime
+
+import (
+	"backend/internal/repos"
+	"backend/pkg/utilities"
+	"context"
+)
+
+// WorkspaceAuthorizer lets users receive events from the workspaces they
+// belong to and can view channels in
+type WorkspaceAuthorizer struct {
+	workspaceRepo     *repos.WorkspaceRepo
+	permissionChecker *utilities.PermissionChecker
+}
+
+func NewWorkspaceAuthorizer(workspaceRepo *repos.WorkspaceRepo, permissionChecker *utilities.PermissionChecker) *WorkspaceAuthorizer {
+	return &WorkspaceAuthorizer{
+		workspaceRepo:     workspaceRepo,
+		permissionChecker: permissionChecker,
+	}
+}
+
+func (a *WorkspaceAuthorizer) VisibleWorkspaces(ctx context.Context, userID string) (map[string]bool, error) {
+	workspaces, err := a.workspaceRepo.GetUserWorkspaces(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make(map[string]bool, len(workspaces))
+	for _, workspace := range workspaces {
+		canView, err := a.permissionChecker.CheckUserPermission(ctx, userID, workspace.Id.String(), "workspace:view-channels")
+		if err != nil {
+			return nil, err
+		}
+		if canView {
+			visible[workspace.Id.String()] = true
+		}
+	}
+
+	return visible, nil
+}
@@ -0,0 +1,247 @@
+package realtime
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = (pongWait * 9) / 10
+	maxMessageSize = 4096
+	sendBufferSize = 256
+)
+
+// controlMessage is a gateway frame that is not a workspace event
+type controlMessage struct {
+	Type string `json:"type"`
+}
+
+type pendingEvent struct {
+	event models.Event
+	data  []byte
+}
+
+// SessionValidator reports whether the connection's session is still active
+type SessionValidator func(ctx context.Context) error
+
+// Client is a single WebSocket subscriber
+type Client struct {
+	hub             *Hub
+	conn            *websocket.Conn
+	userID          string
+	validateSession SessionValidator
+
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu         sync.RWMutex
+	workspaces map[string]bool
+
+	resumeMu sync.Mutex
+	resuming bool
+	pending  []pendingEvent
+}
+
+// Serve authorizes the connection, registers it with the hub and blocks until it closes
+func (h *Hub) Serve(conn *websocket.Conn, userID string, lastEventID string, validateSession SessionValidator) {
+	client := &Client{
+		hub:             h,
+		conn:            conn,
+		userID:          userID,
+		validateSession: validateSession,
+		send:            make(chan []byte, sendBufferSize),
+		done:            make(chan struct{}),
+	}
+
+	if err := client.refreshAuthorization(context.Background()); err != nil {
+		log.Printf("realtime: failed to authorize user %s: %v", userID, err)
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "authorization failed"),
+			time.Now().Add(writeWait))
+		conn.Close()
+		return
+	}
+
+	go client.writePump()
+
+	h.register(context.Background(), client, lastEventID)
+	defer h.unregister(client)
+
+	client.readPump()
+}
+
+func (c *Client) refreshAuthorization(ctx context.Context) error {
+	workspaces, err := c.hub.authorizer.VisibleWorkspaces(ctx, c.userID)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.workspaces = workspaces
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Client) canSee(event models.Event) bool {
+	if event.SubjectUserID != "" && event.SubjectUserID == c.userID {
+		return true
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.workspaces[event.WorkspaceID]
+}
+
+// deliver handles a live event from the hub
+func (c *Client) deliver(event models.Event, data []byte) {
+	if event.SubjectUserID == c.userID && affectsAuthorization(event.Type) {
+		// Membership or roles changed, so re-check what this user can see
+		go func() {
+			if err := c.refreshAuthorization(context.Background()); err != nil {
+				log.Printf("realtime: failed to refresh authorization for user %s: %v", c.userID, err)
+			}
+		}()
+	}
+
+	c.resumeMu.Lock()
+	if c.resuming {
+		c.pending = append(c.pending, pendingEvent{event: event, data: data})
+		c.resumeMu.Unlock()
+		return
+	}
+	c.resumeMu.Unlock()
+
+	c.push(event, data)
+}
+
+// push queues an event for the client if it is allowed to see it
+func (c *Client) push(event models.Event, data []byte) {
+	if !c.canSee(event) {
+		return
+	}
+	c.enqueue(data)
+}
+
+// finishResume flushes live events held back during replay, skipping replayed ones
+func (c *Client) finishResume(replayed map[string]bool) {
+	c.resumeMu.Lock()
+	defer c.resumeMu.Unlock()
+
+	for _, p := range c.pending {
+		if !replayed[p.event.ID] {
+			c.push(p.event, p.data)
+		}
+	}
+	c.pending = nil
+	c.resuming = false
+}
+
+func (c *Client) sendControl(msg controlMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	c.enqueue(data)
+}
+
+// enqueue never blocks the hub; a client that cannot keep up is disconnected
+func (c *Client) enqueue(data []byte) {
+	select {
+	case <-c.done:
+	case c.send <- data:
+	default:
+		log.Printf("realtime: dropping slow client for user %s", c.userID)
+		c.close()
+	}
+}
+
+func (c *Client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+func (c *Client) readPump() {
+	defer func() {
+		c.close()
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		var msg controlMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		if msg.Type == "ping" {
+			c.sendControl(controlMessage{Type: "pong"})
+		}
+	}
+}
+
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.close()
+				return
+			}
+		case <-ticker.C:
+			if c.validateSession != nil {
+				if err := c.validateSession(context.Background()); err != nil {
+					c.conn.WriteControl(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "session expired"),
+						time.Now().Add(writeWait))
+					c.close()
+					return
+				}
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+				time.Now().Add(writeWait))
+			return
+		}
+	}
+}
+
+func affectsAuthorization(eventType string) bool {
+	switch eventType {
+	case models.EventMemberAdded, models.EventMemberRemoved, models.EventRoleAssigned, models.EventRoleRemoved:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package realtime
+
+import (
+	"backend/internal/eventbus"
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// maxReplay caps how many missed events a resuming client is sent before it must resync.
+	// It stays below sendBufferSize so a replay cannot overflow the client's queue.
+	maxReplay = 200
+	// resubscribeDelay is how long Run waits before retrying a failed bus subscription
+	resubscribeDelay = time.Second
+)
+
+// Publisher is implemented by anything handlers can emit workspace events through
+type Publisher interface {
+	Publish(ctx context.Context, event models.Event)
+}
+
+// Publishers passes every event to each of its publishers in turn
+type Publishers []Publisher
+
+func (p Publishers) Publish(ctx context.Context, event models.Event) {
+	for _, publisher := range p {
+		publisher.Publish(ctx, event)
+	}
+}
+
+// Authorizer decides which workspaces' events a user may receive
+type Authorizer interface {
+	VisibleWorkspaces(ctx context.Context, userID string) (map[string]bool, error)
+}
+
+// Hub fans workspace events out to the WebSocket clients connected to this replica.
+// Events travel through the bus, so clients on every replica receive them.
+type Hub struct {
+	bus        eventbus.Bus
+	authorizer Authorizer
+
+	mu      sync.Mutex
+	clients map[*Client]struct{}
+}
+
+func NewHub(bus eventbus.Bus, authorizer Authorizer) *Hub {
+	return &Hub{
+		bus:        bus,
+		authorizer: authorizer,
+		clients:    make(map[*Client]struct{}),
+	}
+}
+
+// NewEvent builds an event with a JSON-encoded payload
+func NewEvent(eventType string, workspaceID string, payload any) models.Event {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("realtime: failed to encode %s payload: %v", eventType, err)
+		data = []byte("null")
+	}
+	return models.Event{
+		Type:        eventType,
+		WorkspaceID: workspaceID,
+		Payload:     data,
+		CreatedAt:   time.Now().UTC(),
+	}
+}
+
+// Publish sends the event to the bus. Failures are logged rather than returned
+// so a bus outage never fails the request that caused the event.
+func (h *Hub) Publish(ctx context.Context, event models.Event) {
+	if _, err := h.bus.Publish(ctx, event); err != nil {
+		log.Printf("realtime: failed to publish %s event: %v", event.Type, err)
+	}
+}
+
+// Run consumes the bus and delivers events to local clients until ctx is cancelled
+func (h *Hub) Run(ctx context.Context) {
+	for {
+		events, err := h.bus.Subscribe(ctx)
+		if err != nil {
+			log.Printf("realtime: failed to subscribe to event bus: %v", err)
+		} else {
+			for event := range events {
+				h.broadcast(event)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
+
+func (h *Hub) broadcast(event models.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("realtime: failed to encode event %s: %v", event.ID, err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients {
+		client.deliver(event, data)
+	}
+}
+
+// register adds a client and replays anything it missed since lastEventID.
+// Live events arriving during the replay are held back and de-duplicated.
+func (h *Hub) register(ctx context.Context, client *Client, lastEventID string) {
+	client.resuming = lastEventID != ""
+
+	h.mu.Lock()
+	h.clients[client] = struct{}{}
+	h.mu.Unlock()
+
+	if lastEventID != "" {
+		replayed := make(map[string]bool)
+		missed, ok, err := h.bus.Since(ctx, lastEventID, maxReplay)
+		if err != nil {
+			log.Printf("realtime: failed to replay events after %s: %v", lastEventID, err)
+		}
+		if err != nil || !ok || len(missed) >= maxReplay {
+			client.sendControl(controlMessage{Type: "resync"})
+		} else {
+			for _, event := range missed {
+				data, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				replayed[event.ID] = true
+				client.push(event, data)
+			}
+		}
+		client.finishResume(replayed)
+	}
+
+	client.sendControl(controlMessage{Type: "ready"})
+}
+
+func (h *Hub) unregister(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, client)
+}
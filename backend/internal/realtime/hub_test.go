@@ -0,0 +1,151 @@
+package realtime
+
+import (
+	"backend/internal/eventbus"
+	"backend/internal/models"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type fakeAuthorizer struct {
+	visible map[string]map[string]bool
+}
+
+func (a *fakeAuthorizer) VisibleWorkspaces(ctx context.Context, userID string) (map[string]bool, error) {
+	return a.visible[userID], nil
+}
+
+// startReplica runs a hub on the shared bus behind its own test server, like one backend container
+func startReplica(t *testing.T, ctx context.Context, bus *eventbus.MemoryBus, authorizer Authorizer) (*Hub, *httptest.Server) {
+	t.Helper()
+	hub := NewHub(bus, authorizer)
+	subscribers := bus.Subscribers()
+	go hub.Run(ctx)
+	for deadline := time.Now().Add(2 * time.Second); bus.Subscribers() == subscribers; {
+		if time.Now().After(deadline) {
+			t.Fatal("hub did not subscribe to the bus")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		hub.Serve(conn, r.URL.Query().Get("user"), r.URL.Query().Get("last_event_id"), nil)
+	}))
+	t.Cleanup(server.Close)
+
+	return hub, server
+}
+
+func dial(t *testing.T, server *httptest.Server, query string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/?" + query
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readUntil returns the first frame of the wanted type, failing after a timeout
+func readUntil(t *testing.T, conn *websocket.Conn, wantType string) models.Event {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var frame models.Event
+		if err := conn.ReadJSON(&frame); err != nil {
+			t.Fatalf("waiting for %q: %v", wantType, err)
+		}
+		if frame.Type == wantType {
+			return frame
+		}
+	}
+}
+
+// expectSilence fails if an event frame arrives within the wait window
+func expectSilence(t *testing.T, conn *websocket.Conn, wait time.Duration) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(wait))
+	var frame models.Event
+	if err := conn.ReadJSON(&frame); err == nil {
+		t.Errorf("expected no frame, got %q", frame.Type)
+	}
+}
+
+func TestHubDeliversAcrossReplicas(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.NewMemoryBus(100)
+	authorizer := &fakeAuthorizer{visible: map[string]map[string]bool{
+		"alice": {"workspace-a": true},
+		"bob":   {"workspace-b": true},
+	}}
+	hubA, _ := startReplica(t, ctx, bus, authorizer)
+	_, serverB := startReplica(t, ctx, bus, authorizer)
+
+	alice := dial(t, serverB, "user=alice")
+	readUntil(t, alice, "ready")
+	bob := dial(t, serverB, "user=bob")
+	readUntil(t, bob, "ready")
+
+	hubA.Publish(ctx, NewEvent(models.EventMessageCreated, "workspace-a", map[string]string{"message": "hi"}))
+
+	event := readUntil(t, alice, models.EventMessageCreated)
+	if event.WorkspaceID != "workspace-a" || event.ID == "" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	expectSilence(t, bob, 200*time.Millisecond)
+}
+
+func TestHubResumesFromLastEventID(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.NewMemoryBus(100)
+	authorizer := &fakeAuthorizer{visible: map[string]map[string]bool{
+		"alice": {"workspace-a": true},
+	}}
+	hub, server := startReplica(t, ctx, bus, authorizer)
+
+	firstID, err := bus.Publish(ctx, NewEvent(models.EventChannelCreated, "workspace-a", nil))
+	if err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	hub.Publish(ctx, NewEvent(models.EventMessageCreated, "workspace-a", nil))
+
+	alice := dial(t, server, "user=alice&last_event_id="+firstID)
+	missed := readUntil(t, alice, models.EventMessageCreated)
+	if missed.ID == firstID {
+		t.Errorf("replayed the event the client had already seen")
+	}
+	readUntil(t, alice, "ready")
+
+	stale := dial(t, server, "user=alice&last_event_id=unknown")
+	readUntil(t, stale, "resync")
+}
+
+func TestHubAnswersClientPing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, server := startReplica(t, ctx, eventbus.NewMemoryBus(10), &fakeAuthorizer{})
+
+	conn := dial(t, server, "user=alice")
+	readUntil(t, conn, "ready")
+	if err := conn.WriteJSON(map[string]string{"type": "ping"}); err != nil {
+		t.Fatalf("failed to send ping: %v", err)
+	}
+	readUntil(t, conn, "pong")
+}
@@ -0,0 +1,178 @@
+package repos
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrAdminAccountNotFound = errors.New("admin account not found")
+	ErrAdminUsernameTaken   = errors.New("admin username is already taken")
+)
+
+const adminAccountColumns = `
+	id, username, password_hash, totp_secret, totp_confirmed_at, totp_last_used_step,
+	created_by, created_at, last_login_at, disabled_at
+`
+
+type AdminAccountRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAdminAccountRepo(db *pgxpool.Pool) *AdminAccountRepo {
+	return &AdminAccountRepo{db: db}
+}
+
+// CreateAdminAccount stores a new admin. createdBy is empty for accounts made by the bootstrap command.
+func (r *AdminAccountRepo) CreateAdminAccount(ctx context.Context, username string, passwordHash string, createdBy string) (*models.AdminAccount, error) {
+	query := `
+		INSERT INTO admin_accounts (username, password_hash, created_by)
+		VALUES ($1, $2, NULLIF($3, '')::uuid)
+		RETURNING ` + adminAccountColumns
+
+	account, err := scanAdminAccount(r.db.QueryRow(ctx, query, username, passwordHash, createdBy))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrAdminUsernameTaken
+		}
+		return nil, fmt.Errorf("failed to create admin account: %w", err)
+	}
+	return account, nil
+}
+
+func (r *AdminAccountRepo) GetAdminAccountByUsername(ctx context.Context, username string) (*models.AdminAccount, error) {
+	query := `SELECT ` + adminAccountColumns + ` FROM admin_accounts WHERE username = $1`
+	account, err := scanAdminAccount(r.db.QueryRow(ctx, query, username))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAdminAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin account: %w", err)
+	}
+	return account, nil
+}
+
+func (r *AdminAccountRepo) GetAdminAccountByID(ctx context.Context, id string) (*models.AdminAccount, error) {
+	query := `SELECT ` + adminAccountColumns + ` FROM admin_accounts WHERE id = $1`
+	account, err := scanAdminAccount(r.db.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAdminAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin account: %w", err)
+	}
+	return account, nil
+}
+
+func (r *AdminAccountRepo) ListAdminAccounts(ctx context.Context) ([]*models.AdminAccount, error) {
+	query := `SELECT ` + adminAccountColumns + ` FROM admin_accounts ORDER BY created_at`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list admin accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []*models.AdminAccount{}
+	for rows.Next() {
+		account, err := scanAdminAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan admin account: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// DisableAdminAccount stops an admin from signing in. It refuses to disable the last active admin.
+func (r *AdminAccountRepo) DisableAdminAccount(ctx context.Context, id string) (bool, error) {
+	query := `
+		UPDATE admin_accounts
+		SET disabled_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND disabled_at IS NULL
+		  AND (SELECT COUNT(*) FROM admin_accounts WHERE disabled_at IS NULL) > 1
+	`
+	tag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to disable admin account: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *AdminAccountRepo) RecordLogin(ctx context.Context, id string) error {
+	_, err := r.db.Exec(ctx, `UPDATE admin_accounts SET last_login_at = CURRENT_TIMESTAMP WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to record admin login: %w", err)
+	}
+	return nil
+}
+
+// SavePendingTOTP starts or restarts TOTP enrollment. A confirmed secret is never overwritten.
+func (r *AdminAccountRepo) SavePendingTOTP(ctx context.Context, id string, secret string) (bool, error) {
+	query := `
+		UPDATE admin_accounts
+		SET totp_secret = $2, totp_last_used_step = 0
+		WHERE id = $1 AND totp_confirmed_at IS NULL
+	`
+	tag, err := r.db.Exec(ctx, query, id, secret)
+	if err != nil {
+		return false, fmt.Errorf("failed to save admin TOTP enrollment: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *AdminAccountRepo) ConfirmTOTP(ctx context.Context, id string, step int64) (bool, error) {
+	query := `
+		UPDATE admin_accounts
+		SET totp_confirmed_at = CURRENT_TIMESTAMP, totp_last_used_step = $2
+		WHERE id = $1 AND totp_secret IS NOT NULL AND totp_confirmed_at IS NULL
+	`
+	tag, err := r.db.Exec(ctx, query, id, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to confirm admin TOTP enrollment: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// UseTOTPStep records a step as used, reporting false if it (or a later one) was already accepted
+func (r *AdminAccountRepo) UseTOTPStep(ctx context.Context, id string, step int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE admin_accounts SET totp_last_used_step = $2
+		WHERE id = $1 AND totp_last_used_step < $2
+	`, id, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record admin TOTP use: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ResetTOTP removes an admin's second factor so they can enroll again
+func (r *AdminAccountRepo) ResetTOTP(ctx context.Context, id string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE admin_accounts
+		SET totp_secret = NULL, totp_confirmed_at = NULL, totp_last_used_step = 0
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to reset admin TOTP: %w", err)
+	}
+	return nil
+}
+
+func scanAdminAccount(row pgx.Row) (*models.AdminAccount, error) {
+	var account models.AdminAccount
+	err := row.Scan(
+		&account.Id, &account.Username, &account.PasswordHash, &account.TOTPSecret, &account.TOTPConfirmedAt,
+		&account.TOTPLastUsedStep, &account.CreatedBy, &account.CreatedAt, &account.LastLoginAt, &account.DisabledAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
@@ -0,0 +1,124 @@
+package repos
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrAPITokenNotFound = errors.New("API token not found")
+
+const apiTokenColumns = `id, user_id, name, token_prefix, scopes, expires_at, last_used_at, created_at`
+
+type APITokenRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAPITokenRepo(db *pgxpool.Pool) *APITokenRepo {
+	return &APITokenRepo{db: db}
+}
+
+// CreateToken stores a token by its hash. expiresInDays of 0 never expires.
+func (r *APITokenRepo) CreateToken(ctx context.Context, userID string, name string, tokenPrefix string, tokenHash string, scopes []string, expiresInDays int) (*models.APIToken, error) {
+	query := `
+		INSERT INTO api_tokens (user_id, name, token_prefix, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, CASE WHEN $6::int > 0 THEN CURRENT_TIMESTAMP + make_interval(days => $6::int) END)
+		RETURNING ` + apiTokenColumns
+
+	token, err := scanAPIToken(r.db.QueryRow(ctx, query, userID, name, tokenPrefix, tokenHash, scopes, expiresInDays))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API token: %w", err)
+	}
+	return token, nil
+}
+
+// ListTokens returns the user's tokens, newest first, including expired ones
+func (r *APITokenRepo) ListTokens(ctx context.Context, userID string) ([]*models.APIToken, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+apiTokenColumns+`
+		FROM api_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []*models.APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (r *APITokenRepo) CountTokens(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM api_tokens WHERE user_id = $1`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count API tokens: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteToken revokes one of the user's tokens
+func (r *APITokenRepo) DeleteToken(ctx context.Context, userID string, tokenID string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM api_tokens WHERE id = $1 AND user_id = $2`, tokenID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete API token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// GetActiveTokenByHash finds an unexpired token belonging to an active user
+func (r *APITokenRepo) GetActiveTokenByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	query := `
+		SELECT t.id, t.user_id, t.name, t.token_prefix, t.scopes, t.expires_at, t.last_used_at, t.created_at
+		FROM api_tokens t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.token_hash = $1
+		  AND (t.expires_at IS NULL OR t.expires_at > CURRENT_TIMESTAMP)
+		  AND u.status = 'active'
+	`
+	token, err := scanAPIToken(r.db.QueryRow(ctx, query, tokenHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPITokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API token: %w", err)
+	}
+	return token, nil
+}
+
+// TouchToken records that the token was used. Writes are skipped when it was
+// already used within the last minute, so busy tokens do not write on every request.
+func (r *APITokenRepo) TouchToken(ctx context.Context, tokenID string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE api_tokens SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')
+	`, tokenID)
+	if err != nil {
+		return fmt.Errorf("failed to update API token last use: %w", err)
+	}
+	return nil
+}
+
+func scanAPIToken(row pgx.Row) (*models.APIToken, error) {
+	var token models.APIToken
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenPrefix, &token.Scopes, &token.ExpiresAt, &token.LastUsedAt, &token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
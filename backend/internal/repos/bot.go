@@ -0,0 +1,203 @@
+package repos
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrBotNotFound = errors.New("bot not found")
+
+const botColumns = `
+	b.user_id, b.workspace_id, bu.username, b.description,
+	COALESCE(b.created_by, b.created_by_admin), COALESCE(u.username, a.username), b.created_by_admin IS NOT NULL,
+	b.created_at
+`
+
+const botJoins = `
+	JOIN users bu ON bu.id = b.user_id
+	LEFT JOIN users u ON u.id = b.created_by
+	LEFT JOIN admin_accounts a ON a.id = b.created_by_admin
+`
+
+type BotRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewBotRepo(db *pgxpool.Pool) *BotRepo {
+	return &BotRepo{db: db}
+}
+
+// CreateBot creates the bot's user, makes it a member of the workspace and assigns
+// it the roles in one transaction, returning the assignments made. It was created by
+// either a workspace member or an instance admin; the other creator ID is left empty.
+func (r *BotRepo) CreateBot(ctx context.Context, workspaceID string, username string, passwordHash string, description string, createdBy string, createdByAdmin string, roleIDs []int) (*models.Bot, []*models.WorkspaceUserRole, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var userID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO users (username, password_hash, status, auth_source)
+		VALUES ($1, $2, 'active', 'bot')
+		RETURNING id
+	`, username, passwordHash).Scan(&userID)
+	if isUniqueViolation(err) {
+		return nil, nil, ErrUsernameTaken
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create bot user: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO bot_accounts (user_id, workspace_id, description, created_by, created_by_admin)
+		VALUES ($1, $2, $3, NULLIF($4, '')::uuid, NULLIF($5, '')::uuid)
+	`, userID, workspaceID, description, createdBy, createdByAdmin)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create bot: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO workspace_users (user_id, workspace_id) VALUES ($1, $2)`, userID, workspaceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to add bot to workspace: %w", err)
+	}
+
+	assignments := make([]*models.WorkspaceUserRole, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		var assignment models.WorkspaceUserRole
+		err := tx.QueryRow(ctx, `
+			INSERT INTO workspace_user_roles (workspace_id, user_id, role_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (workspace_id, user_id, role_id) DO NOTHING
+			RETURNING workspace_id, user_id, role_id, assigned_at
+		`, workspaceID, userID, roleID).Scan(&assignment.WorkspaceID, &assignment.UserID, &assignment.RoleID, &assignment.AssignedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The same role was listed twice
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to assign role to bot: %w", err)
+		}
+		assignments = append(assignments, &assignment)
+	}
+
+	bot, err := scanBot(tx.QueryRow(ctx, `SELECT `+botColumns+` FROM bot_accounts b`+botJoins+` WHERE b.user_id = $1`, userID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load bot: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return bot, assignments, nil
+}
+
+// ListBots returns the workspace's active bots, newest first
+func (r *BotRepo) ListBots(ctx context.Context, workspaceID string) ([]*models.Bot, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+botColumns+`
+		FROM bot_accounts b`+botJoins+`
+		WHERE b.workspace_id = $1 AND b.deactivated_at IS NULL
+		ORDER BY b.created_at DESC
+	`, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bots: %w", err)
+	}
+	defer rows.Close()
+
+	bots := []*models.Bot{}
+	for rows.Next() {
+		bot, err := scanBot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bot: %w", err)
+		}
+		bots = append(bots, bot)
+	}
+	return bots, rows.Err()
+}
+
+// GetBot finds an active bot of the workspace
+func (r *BotRepo) GetBot(ctx context.Context, workspaceID string, botID string) (*models.Bot, error) {
+	bot, err := scanBot(r.db.QueryRow(ctx, `
+		SELECT `+botColumns+`
+		FROM bot_accounts b`+botJoins+`
+		WHERE b.workspace_id = $1 AND b.user_id = $2 AND b.deactivated_at IS NULL
+	`, workspaceID, botID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBotNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bot: %w", err)
+	}
+	return bot, nil
+}
+
+// GetBotWorkspaceID returns the workspace a bot belongs to, or "" when the user is
+// not a bot
+func (r *BotRepo) GetBotWorkspaceID(ctx context.Context, userID string) (string, error) {
+	var workspaceID string
+	err := r.db.QueryRow(ctx, `SELECT workspace_id FROM bot_accounts WHERE user_id = $1`, userID).Scan(&workspaceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get bot workspace: %w", err)
+	}
+	return workspaceID, nil
+}
+
+// DeactivateBot removes the bot from its workspace and deletes its tokens. The user
+// row stays so the bot's messages keep their author.
+func (r *BotRepo) DeactivateBot(ctx context.Context, workspaceID string, botID string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE bot_accounts SET deactivated_at = CURRENT_TIMESTAMP
+		WHERE workspace_id = $1 AND user_id = $2 AND deactivated_at IS NULL
+	`, workspaceID, botID)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate bot: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrBotNotFound
+	}
+
+	statements := []string{
+		`DELETE FROM api_tokens WHERE user_id = $1`,
+		`DELETE FROM workspace_user_roles WHERE user_id = $1`,
+		`DELETE FROM workspace_users WHERE user_id = $1`,
+		`UPDATE users SET status = 'rejected' WHERE id = $1`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(ctx, statement, botID); err != nil {
+			return fmt.Errorf("failed to deactivate bot: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func scanBot(row pgx.Row) (*models.Bot, error) {
+	var bot models.Bot
+	err := row.Scan(
+		&bot.ID, &bot.WorkspaceID, &bot.Username, &bot.Description,
+		&bot.CreatedBy, &bot.CreatedByUsername, &bot.CreatedByAdmin,
+		&bot.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &bot, nil
+}
@@ -0,0 +1,98 @@
+package repos
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrIdentityNotFound = errors.New("no user is linked to this identity")
+	ErrIdentityLinked   = errors.New("this identity is already linked to a user")
+)
+
+type IdentityRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewIdentityRepo(db *pgxpool.Pool) *IdentityRepo {
+	return &IdentityRepo{db: db}
+}
+
+// GetUserIDByIdentity finds the local user linked to the provider account
+func (r *IdentityRepo) GetUserIDByIdentity(ctx context.Context, issuer string, subject string) (string, error) {
+	var userID pgtype.UUID
+	err := r.db.QueryRow(ctx, `
+		SELECT user_id FROM user_identities WHERE issuer = $1 AND subject = $2
+	`, issuer, subject).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrIdentityNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up identity: %w", err)
+	}
+	return userID.String(), nil
+}
+
+// LinkIdentity links the provider account to an existing user
+func (r *IdentityRepo) LinkIdentity(ctx context.Context, issuer string, subject string, userID string) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO user_identities (issuer, subject, user_id) VALUES ($1, $2, $3)
+	`, issuer, subject, userID)
+	if isUniqueViolation(err) {
+		return ErrIdentityLinked
+	}
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	return nil
+}
+
+// CreateUserWithIdentity provisions an active user for a provider account that has
+// no local user yet, creating the user and the link together
+func (r *IdentityRepo) CreateUserWithIdentity(ctx context.Context, username string, passwordHash string, issuer string, subject string) (*models.User, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	user := &models.User{Status: models.UserStatusActive}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO users (username, password_hash, status)
+		VALUES ($1, $2, $3)
+		RETURNING id, username
+	`, username, passwordHash, models.UserStatusActive).Scan(&user.Id, &user.Username)
+	if isUniqueViolation(err) {
+		return nil, ErrUsernameTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO user_identities (issuer, subject, user_id) VALUES ($1, $2, $3)
+	`, issuer, subject, user.Id)
+	if isUniqueViolation(err) {
+		return nil, ErrIdentityLinked
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return user, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,147 @@
+package repos
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrIncomingWebhookNotFound = errors.New("incoming webhook not found")
+
+const incomingWebhookColumns = `
+	h.id, h.workspace_id, h.channel_id, h.name,
+	COALESCE(h.created_by, h.created_by_admin), COALESCE(u.username, a.username), h.created_by_admin IS NOT NULL,
+	h.last_used_at, h.created_at
+`
+
+const incomingWebhookJoins = `
+	LEFT JOIN users u ON u.id = h.created_by
+	LEFT JOIN admin_accounts a ON a.id = h.created_by_admin
+`
+
+type IncomingWebhookRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewIncomingWebhookRepo(db *pgxpool.Pool) *IncomingWebhookRepo {
+	return &IncomingWebhookRepo{db: db}
+}
+
+// CreateWebhook stores a webhook by its token's hash. It was created by either a
+// workspace member or an instance admin; the other creator ID is left empty.
+func (r *IncomingWebhookRepo) CreateWebhook(ctx context.Context, workspaceID string, channelID int, name string, tokenHash string, createdBy string, createdByAdmin string) (*models.IncomingWebhook, error) {
+	query := `
+		WITH h AS (
+			INSERT INTO incoming_webhooks (workspace_id, channel_id, name, token_hash, created_by, created_by_admin)
+			VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, NULLIF($6, '')::uuid)
+			RETURNING *
+		)
+		SELECT ` + incomingWebhookColumns + ` FROM h` + incomingWebhookJoins
+
+	webhook, err := scanIncomingWebhook(r.db.QueryRow(ctx, query, workspaceID, channelID, name, tokenHash, createdBy, createdByAdmin))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create incoming webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// ListWebhooks returns the channel's webhooks, newest first
+func (r *IncomingWebhookRepo) ListWebhooks(ctx context.Context, workspaceID string, channelID int) ([]*models.IncomingWebhook, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+incomingWebhookColumns+`
+		FROM incoming_webhooks h`+incomingWebhookJoins+`
+		WHERE h.workspace_id = $1 AND h.channel_id = $2
+		ORDER BY h.created_at DESC
+	`, workspaceID, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list incoming webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []*models.IncomingWebhook{}
+	for rows.Next() {
+		webhook, err := scanIncomingWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan incoming webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (r *IncomingWebhookRepo) CountWebhooks(ctx context.Context, workspaceID string, channelID int) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM incoming_webhooks WHERE workspace_id = $1 AND channel_id = $2
+	`, workspaceID, channelID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count incoming webhooks: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteWebhook removes one of the channel's webhooks. Messages it posted stay.
+func (r *IncomingWebhookRepo) DeleteWebhook(ctx context.Context, workspaceID string, channelID int, webhookID string) error {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM incoming_webhooks WHERE id = $1 AND workspace_id = $2 AND channel_id = $3
+	`, webhookID, workspaceID, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to delete incoming webhook: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrIncomingWebhookNotFound
+	}
+	return nil
+}
+
+// GetWebhookByTokenHash finds the webhook a secret URL belongs to
+func (r *IncomingWebhookRepo) GetWebhookByTokenHash(ctx context.Context, tokenHash string) (*models.IncomingWebhook, error) {
+	webhook, err := scanIncomingWebhook(r.db.QueryRow(ctx, `
+		SELECT `+incomingWebhookColumns+`
+		FROM incoming_webhooks h`+incomingWebhookJoins+`
+		WHERE h.token_hash = $1
+	`, tokenHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrIncomingWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incoming webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// TouchWebhook records that the webhook was used. Writes are skipped when it was
+// already used within the last minute, so busy webhooks do not write on every post.
+func (r *IncomingWebhookRepo) TouchWebhook(ctx context.Context, webhookID string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE incoming_webhooks SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')
+	`, webhookID)
+	if err != nil {
+		return fmt.Errorf("failed to update incoming webhook last use: %w", err)
+	}
+	return nil
+}
+
+func scanIncomingWebhook(row pgx.Row) (*models.IncomingWebhook, error) {
+	var webhook models.IncomingWebhook
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.WorkspaceID,
+		&webhook.ChannelID,
+		&webhook.Name,
+		&webhook.CreatedBy,
+		&webhook.CreatedByUsername,
+		&webhook.CreatedByAdmin,
+		&webhook.LastUsedAt,
+		&webhook.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
@@ -0,0 +1,213 @@
+package repos
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrInviteNotFound    = errors.New("invite not found")
+	ErrInviteUnavailable = errors.New("invite has expired, been revoked, or been used up")
+	ErrAlreadyMember     = errors.New("user is already a member of the workspace")
+)
+
+const inviteColumns = `
+	i.id, i.workspace_id, i.role_id, r.name,
+	COALESCE(i.created_by, i.created_by_admin), COALESCE(u.username, a.username), i.created_by_admin IS NOT NULL,
+	i.max_uses, i.use_count, i.expires_at, i.revoked_at, i.created_at
+`
+
+const inviteJoins = `
+	JOIN roles r ON r.id = i.role_id
+	LEFT JOIN users u ON u.id = i.created_by
+	LEFT JOIN admin_accounts a ON a.id = i.created_by_admin
+`
+
+type InviteRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewInviteRepo(db *pgxpool.Pool) *InviteRepo {
+	return &InviteRepo{db: db}
+}
+
+// CreateInvite stores an invite created by either a workspace member or an instance
+// admin; the other creator ID is left empty. maxUses of 0 means unlimited.
+func (r *InviteRepo) CreateInvite(ctx context.Context, workspaceID string, codeHash string, roleID int, createdBy string, createdByAdmin string, maxUses int, expiresInHours int) (*models.WorkspaceInvite, error) {
+	query := `
+		WITH i AS (
+			INSERT INTO workspace_invites (workspace_id, code_hash, role_id, created_by, created_by_admin, max_uses, expires_at)
+			VALUES ($1, $2, $3, NULLIF($4, '')::uuid, NULLIF($5, '')::uuid, NULLIF($6, 0), CURRENT_TIMESTAMP + make_interval(hours => $7))
+			RETURNING *
+		)
+		SELECT ` + inviteColumns + ` FROM i` + inviteJoins
+
+	invite, err := scanInvite(r.db.QueryRow(ctx, query, workspaceID, codeHash, roleID, createdBy, createdByAdmin, maxUses, expiresInHours))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invite: %w", err)
+	}
+	invite.Uses = []models.WorkspaceInviteUse{}
+	return invite, nil
+}
+
+// ListInvites returns the workspace's invites, newest first, with everyone who joined through each
+func (r *InviteRepo) ListInvites(ctx context.Context, workspaceID string) ([]*models.WorkspaceInvite, error) {
+	query := `
+		SELECT ` + inviteColumns + `,
+		       COALESCE((
+		           SELECT json_agg(json_build_object('user_id', iu.user_id, 'username', uu.username, 'used_at', iu.used_at) ORDER BY iu.used_at)
+		           FROM workspace_invite_uses iu
+		           JOIN users uu ON uu.id = iu.user_id
+		           WHERE iu.invite_id = i.id
+		       ), '[]'::json)
+		FROM workspace_invites i` + inviteJoins + `
+		WHERE i.workspace_id = $1
+		ORDER BY i.created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invites: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []*models.WorkspaceInvite{}
+	for rows.Next() {
+		var invite models.WorkspaceInvite
+		var usesJSON []byte
+		err := rows.Scan(
+			&invite.ID, &invite.WorkspaceID, &invite.RoleID, &invite.RoleName,
+			&invite.CreatedBy, &invite.CreatedByUsername, &invite.CreatedByAdmin,
+			&invite.MaxUses, &invite.UseCount, &invite.ExpiresAt, &invite.RevokedAt, &invite.CreatedAt,
+			&usesJSON,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invite: %w", err)
+		}
+		if err := json.Unmarshal(usesJSON, &invite.Uses); err != nil {
+			return nil, fmt.Errorf("failed to parse invite uses: %w", err)
+		}
+		invites = append(invites, &invite)
+	}
+	return invites, rows.Err()
+}
+
+// RevokeInvite stops an invite from being redeemed. Revoking it again is a no-op.
+func (r *InviteRepo) RevokeInvite(ctx context.Context, workspaceID string, inviteID string) error {
+	query := `
+		UPDATE workspace_invites
+		SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP)
+		WHERE id = $1 AND workspace_id = $2
+	`
+	tag, err := r.db.Exec(ctx, query, inviteID, workspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke invite: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInviteNotFound
+	}
+	return nil
+}
+
+// GetInvitePreview describes a redeemable invite. Invites that can no longer be
+// used report ErrInviteUnavailable.
+func (r *InviteRepo) GetInvitePreview(ctx context.Context, codeHash string) (*models.InvitePreview, error) {
+	query := `
+		SELECT w.id, w.name, w.image_path, r.name, i.expires_at, ` + inviteUsableExpr + `
+		FROM workspace_invites i
+		JOIN workspaces w ON w.id = i.workspace_id
+		JOIN roles r ON r.id = i.role_id
+		WHERE i.code_hash = $1
+	`
+
+	var preview models.InvitePreview
+	var usable bool
+	err := r.db.QueryRow(ctx, query, codeHash).Scan(
+		&preview.WorkspaceID, &preview.WorkspaceName, &preview.WorkspaceImagePath,
+		&preview.RoleName, &preview.ExpiresAt, &usable,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInviteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invite: %w", err)
+	}
+	if !usable {
+		return nil, ErrInviteUnavailable
+	}
+	return &preview, nil
+}
+
+// RedeemInvite adds the user to the invite's workspace with its role and counts the use.
+// The invite row is locked so concurrent redemptions cannot exceed its use limit.
+// It returns the workspace joined.
+func (r *InviteRepo) RedeemInvite(ctx context.Context, codeHash string, userID string) (string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT i.id, i.workspace_id::text, i.role_id, ` + inviteUsableExpr + `
+		FROM workspace_invites i
+		WHERE i.code_hash = $1
+		FOR UPDATE
+	`
+	var inviteID string
+	var workspaceID string
+	var roleID int
+	var usable bool
+	err = tx.QueryRow(ctx, query, codeHash).Scan(&inviteID, &workspaceID, &roleID, &usable)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrInviteNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get invite: %w", err)
+	}
+	if !usable {
+		return "", ErrInviteUnavailable
+	}
+
+	added, err := addWorkspaceMember(ctx, tx, userID, workspaceID, roleID)
+	if err != nil {
+		return "", err
+	}
+	if !added {
+		return workspaceID, ErrAlreadyMember
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE workspace_invites SET use_count = use_count + 1 WHERE id = $1`, inviteID); err != nil {
+		return "", fmt.Errorf("failed to count invite use: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO workspace_invite_uses (invite_id, user_id) VALUES ($1, $2)`, inviteID, userID); err != nil {
+		return "", fmt.Errorf("failed to record invite use: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return workspaceID, nil
+}
+
+// inviteUsableExpr is true while invite i can still be redeemed
+const inviteUsableExpr = `(i.revoked_at IS NULL AND i.expires_at > CURRENT_TIMESTAMP AND (i.max_uses IS NULL OR i.use_count < i.max_uses))`
+
+func scanInvite(row pgx.Row) (*models.WorkspaceInvite, error) {
+	var invite models.WorkspaceInvite
+	err := row.Scan(
+		&invite.ID, &invite.WorkspaceID, &invite.RoleID, &invite.RoleName,
+		&invite.CreatedBy, &invite.CreatedByUsername, &invite.CreatedByAdmin,
+		&invite.MaxUses, &invite.UseCount, &invite.ExpiresAt, &invite.RevokedAt, &invite.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
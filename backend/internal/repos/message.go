@@ -0,0 +1,160 @@
+package repos
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MessageRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewMessageRepo(db *pgxpool.Pool) *MessageRepo {
+	return &MessageRepo{db: db}
+}
+
+// ChannelExists reports whether the channel belongs to the given workspace
+func (r *MessageRepo) ChannelExists(ctx context.Context, workspaceID string, channelID int) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM workspace_channels
+			WHERE id = $1 AND workspace_id = $2
+		)
+	`
+
+	var exists bool
+	if err := r.db.QueryRow(ctx, query, channelID, workspaceID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check channel: %w", err)
+	}
+
+	return exists, nil
+}
+
+// messageColumns reads a message from m joined with its author u. Webhook messages
+// have no author and show their display name instead.
+const messageColumns = `
+	m.id, m.workspace_id, m.channel_id, m.user_id,
+	COALESCE(m.display_name, u.username), COALESCE(u.auth_source = 'bot', TRUE),
+	m.webhook_id, m.message, m.attachments, m.created_at
+`
+
+// CreateMessage stores a new message and returns it together with the author's username
+func (r *MessageRepo) CreateMessage(ctx context.Context, workspaceID string, channelID int, userID string, message string) (*models.WorkspaceChannelMessage, error) {
+	query := `
+		WITH m AS (
+			INSERT INTO workspace_channel_messages (workspace_id, channel_id, user_id, message)
+			VALUES ($1, $2, $3, $4)
+			RETURNING *
+		)
+		SELECT ` + messageColumns + `
+		FROM m
+		LEFT JOIN users u ON m.user_id = u.id
+	`
+
+	m, err := scanMessage(r.db.QueryRow(ctx, query, workspaceID, channelID, userID, message))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create message: %w", err)
+	}
+
+	return m, nil
+}
+
+// CreateWebhookMessage stores a message posted through an incoming webhook under the
+// given display name
+func (r *MessageRepo) CreateWebhookMessage(ctx context.Context, workspaceID string, channelID int, webhookID string, displayName string, message string, attachments []models.MessageAttachment) (*models.WorkspaceChannelMessage, error) {
+	var encodedAttachments []byte
+	if len(attachments) > 0 {
+		encoded, err := json.Marshal(attachments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode attachments: %w", err)
+		}
+		encodedAttachments = encoded
+	}
+
+	query := `
+		WITH m AS (
+			INSERT INTO workspace_channel_messages (workspace_id, channel_id, webhook_id, display_name, message, attachments)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING *
+		)
+		SELECT ` + messageColumns + `
+		FROM m
+		LEFT JOIN users u ON m.user_id = u.id
+	`
+
+	m, err := scanMessage(r.db.QueryRow(ctx, query, workspaceID, channelID, webhookID, displayName, message, encodedAttachments))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook message: %w", err)
+	}
+
+	return m, nil
+}
+
+// GetChannelMessages returns up to limit messages older than the cursor, newest first.
+// A nil cursor starts from the most recent message.
+func (r *MessageRepo) GetChannelMessages(ctx context.Context, workspaceID string, channelID int, before *models.MessageCursor, limit int) ([]models.WorkspaceChannelMessage, error) {
+	query := `
+		SELECT ` + messageColumns + `
+		FROM workspace_channel_messages m
+		LEFT JOIN users u ON m.user_id = u.id
+		WHERE m.workspace_id = $1 AND m.channel_id = $2
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT $3
+	`
+	args := []any{workspaceID, channelID, limit}
+
+	if before != nil {
+		query = `
+			SELECT ` + messageColumns + `
+			FROM workspace_channel_messages m
+			LEFT JOIN users u ON m.user_id = u.id
+			WHERE m.workspace_id = $1 AND m.channel_id = $2
+			  AND (m.created_at, m.id) < ($4, $5)
+			ORDER BY m.created_at DESC, m.id DESC
+			LIMIT $3
+		`
+		args = append(args, before.CreatedAt, before.ID)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []models.WorkspaceChannelMessage{}
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		messages = append(messages, *m)
+	}
+
+	return messages, rows.Err()
+}
+
+func scanMessage(row pgx.Row) (*models.WorkspaceChannelMessage, error) {
+	var m models.WorkspaceChannelMessage
+	err := row.Scan(
+		&m.ID,
+		&m.WorkspaceID,
+		&m.ChannelID,
+		&m.UserID,
+		&m.Username,
+		&m.IsBot,
+		&m.WebhookID,
+		&m.Message,
+		&m.Attachments,
+		&m.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
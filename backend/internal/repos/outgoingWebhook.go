@@ -0,0 +1,302 @@
+package repos
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrOutgoingWebhookNotFound  = errors.New("outgoing webhook not found")
+	ErrWebhookDeliveryNotFound  = errors.New("webhook delivery not found")
+	ErrWebhookDeliveryScheduled = errors.New("webhook delivery is already scheduled")
+)
+
+const outgoingWebhookColumns = `
+	h.id, h.workspace_id, h.name, h.url, h.events, h.channel_id, h.trigger_words,
+	COALESCE(h.created_by, h.created_by_admin), COALESCE(u.username, a.username), h.created_by_admin IS NOT NULL,
+	h.created_at
+`
+
+const outgoingWebhookJoins = `
+	LEFT JOIN users u ON u.id = h.created_by
+	LEFT JOIN admin_accounts a ON a.id = h.created_by_admin
+`
+
+const webhookDeliveryColumns = `
+	id, webhook_id, event_type, payload, status, attempts, next_attempt_at,
+	last_status_code, last_error, delivered_at, created_at
+`
+
+// ClaimedDelivery is a delivery the worker is about to attempt, with where it goes
+type ClaimedDelivery struct {
+	ID        string
+	WebhookID string
+	EventType string
+	Payload   []byte
+	// Attempts includes the attempt being made
+	Attempts int
+	URL      string
+	Secret   string
+}
+
+type OutgoingWebhookRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewOutgoingWebhookRepo(db *pgxpool.Pool) *OutgoingWebhookRepo {
+	return &OutgoingWebhookRepo{db: db}
+}
+
+// CreateWebhook stores a webhook. It was created by either a workspace member or an
+// instance admin; the other creator ID is left empty. A nil channelID matches every channel.
+func (r *OutgoingWebhookRepo) CreateWebhook(ctx context.Context, workspaceID string, name string, url string, secret string, events []string, channelID *int, triggerWords []string, createdBy string, createdByAdmin string) (*models.OutgoingWebhook, error) {
+	query := `
+		WITH h AS (
+			INSERT INTO outgoing_webhooks (workspace_id, name, url, secret, events, channel_id, trigger_words, created_by, created_by_admin)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, '')::uuid, NULLIF($9, '')::uuid)
+			RETURNING *
+		)
+		SELECT ` + outgoingWebhookColumns + ` FROM h` + outgoingWebhookJoins
+
+	webhook, err := scanOutgoingWebhook(r.db.QueryRow(ctx, query, workspaceID, name, url, secret, events, channelID, triggerWords, createdBy, createdByAdmin))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create outgoing webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// ListWebhooks returns the workspace's webhooks, newest first
+func (r *OutgoingWebhookRepo) ListWebhooks(ctx context.Context, workspaceID string) ([]*models.OutgoingWebhook, error) {
+	return r.listWebhooks(ctx, `
+		SELECT `+outgoingWebhookColumns+`
+		FROM outgoing_webhooks h`+outgoingWebhookJoins+`
+		WHERE h.workspace_id = $1
+		ORDER BY h.created_at DESC
+	`, workspaceID)
+}
+
+// ListWebhooksForEvent returns the workspace's webhooks subscribed to the event type
+func (r *OutgoingWebhookRepo) ListWebhooksForEvent(ctx context.Context, workspaceID string, eventType string) ([]*models.OutgoingWebhook, error) {
+	return r.listWebhooks(ctx, `
+		SELECT `+outgoingWebhookColumns+`
+		FROM outgoing_webhooks h`+outgoingWebhookJoins+`
+		WHERE h.workspace_id = $1 AND $2 = ANY(h.events)
+	`, workspaceID, eventType)
+}
+
+func (r *OutgoingWebhookRepo) listWebhooks(ctx context.Context, query string, args ...any) ([]*models.OutgoingWebhook, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outgoing webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []*models.OutgoingWebhook{}
+	for rows.Next() {
+		webhook, err := scanOutgoingWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outgoing webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (r *OutgoingWebhookRepo) CountWebhooks(ctx context.Context, workspaceID string) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM outgoing_webhooks WHERE workspace_id = $1`, workspaceID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count outgoing webhooks: %w", err)
+	}
+	return count, nil
+}
+
+// GetWebhook finds one of the workspace's webhooks
+func (r *OutgoingWebhookRepo) GetWebhook(ctx context.Context, workspaceID string, webhookID string) (*models.OutgoingWebhook, error) {
+	webhook, err := scanOutgoingWebhook(r.db.QueryRow(ctx, `
+		SELECT `+outgoingWebhookColumns+`
+		FROM outgoing_webhooks h`+outgoingWebhookJoins+`
+		WHERE h.workspace_id = $1 AND h.id = $2
+	`, workspaceID, webhookID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrOutgoingWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outgoing webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes one of the workspace's webhooks along with its delivery history
+func (r *OutgoingWebhookRepo) DeleteWebhook(ctx context.Context, workspaceID string, webhookID string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM outgoing_webhooks WHERE id = $1 AND workspace_id = $2`, webhookID, workspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete outgoing webhook: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOutgoingWebhookNotFound
+	}
+	return nil
+}
+
+// EnqueueDelivery queues a payload to be sent to the webhook right away
+func (r *OutgoingWebhookRepo) EnqueueDelivery(ctx context.Context, webhookID string, eventType string, payload []byte) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO outgoing_webhook_deliveries (webhook_id, event_type, payload)
+		VALUES ($1, $2, $3)
+	`, webhookID, eventType, payload)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ClaimDeliveries takes up to limit due deliveries and counts an attempt for each.
+// They are hidden from other claims for lease, after which a delivery whose outcome
+// was never recorded, say because its worker stopped, is claimed again.
+func (r *OutgoingWebhookRepo) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]ClaimedDelivery, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH due AS (
+			SELECT id FROM outgoing_webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE outgoing_webhook_deliveries d
+		SET attempts = d.attempts + 1,
+		    next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2::float8)
+		FROM due, outgoing_webhooks h
+		WHERE d.id = due.id AND h.id = d.webhook_id
+		RETURNING d.id, d.webhook_id, d.event_type, d.payload, d.attempts, h.url, h.secret
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []ClaimedDelivery{}
+	for rows.Next() {
+		var d ClaimedDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventType, &d.Payload, &d.Attempts, &d.URL, &d.Secret); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RecordAttempt stores the outcome of a delivery's latest attempt. Pending deliveries
+// are tried again after retryIn; statusCode is 0 when no response was received.
+func (r *OutgoingWebhookRepo) RecordAttempt(ctx context.Context, deliveryID string, status string, statusCode int, lastError string, retryIn time.Duration) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE outgoing_webhook_deliveries
+		SET status = $2,
+		    last_status_code = NULLIF($3::int, 0),
+		    last_error = NULLIF($4, ''),
+		    next_attempt_at = CASE WHEN $2 = 'pending' THEN CURRENT_TIMESTAMP + make_interval(secs => $5::float8) END,
+		    delivered_at = CASE WHEN $2 = 'delivered' THEN CURRENT_TIMESTAMP END
+		WHERE id = $1
+	`, deliveryID, status, statusCode, lastError, retryIn.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries returns the webhook's most recent deliveries, newest first. An empty
+// status returns deliveries in any status.
+func (r *OutgoingWebhookRepo) ListDeliveries(ctx context.Context, webhookID string, status string, limit int) ([]*models.OutgoingWebhookDelivery, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM outgoing_webhook_deliveries
+		WHERE webhook_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`, webhookID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.OutgoingWebhookDelivery{}
+	for rows.Next() {
+		var d models.OutgoingWebhookDelivery
+		err := rows.Scan(
+			&d.ID, &d.WebhookID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Redeliver schedules a delivered or dead delivery to be sent again right away, with
+// a fresh set of retries
+func (r *OutgoingWebhookRepo) Redeliver(ctx context.Context, webhookID string, deliveryID string) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE outgoing_webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP, delivered_at = NULL
+		WHERE id = $1 AND webhook_id = $2 AND status <> 'pending'
+	`, deliveryID, webhookID)
+	if err != nil {
+		return fmt.Errorf("failed to redeliver webhook delivery: %w", err)
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	var exists bool
+	err = r.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM outgoing_webhook_deliveries WHERE id = $1 AND webhook_id = $2)
+	`, deliveryID, webhookID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check webhook delivery: %w", err)
+	}
+	if exists {
+		return ErrWebhookDeliveryScheduled
+	}
+	return ErrWebhookDeliveryNotFound
+}
+
+// PruneDeliveries deletes finished deliveries older than maxAge
+func (r *OutgoingWebhookRepo) PruneDeliveries(ctx context.Context, maxAge time.Duration) (int64, error) {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM outgoing_webhook_deliveries
+		WHERE status <> 'pending' AND created_at < CURRENT_TIMESTAMP - make_interval(secs => $1::float8)
+	`, maxAge.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune webhook deliveries: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanOutgoingWebhook(row pgx.Row) (*models.OutgoingWebhook, error) {
+	var webhook models.OutgoingWebhook
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.WorkspaceID,
+		&webhook.Name,
+		&webhook.URL,
+		&webhook.Events,
+		&webhook.ChannelID,
+		&webhook.TriggerWords,
+		&webhook.CreatedBy,
+		&webhook.CreatedByUsername,
+		&webhook.CreatedByAdmin,
+		&webhook.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
@@ -153,6 +153,19 @@ func (r *RoleRepo) GetRoleByID(ctx context.Context, roleID int) (*models.Role, e
 	return &role, nil
 }
 
+// GetRoleIDByName looks up a role by its name
+func (r *RoleRepo) GetRoleIDByName(ctx context.Context, name string) (int, error) {
+	var roleID int
+	err := r.db.QueryRow(ctx, `SELECT id FROM roles WHERE name = $1`, name).Scan(&roleID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrRoleNotFound
+		}
+		return 0, fmt.Errorf("failed to query role: %w", err)
+	}
+	return roleID, nil
+}
+
 // CreateRole creates a new role with the specified permissions
 func (r *RoleRepo) CreateRole(ctx context.Context, name string, description *string, permissionIDs []int) (*models.Role, error) {
 	tx, err := r.db.Begin(ctx)
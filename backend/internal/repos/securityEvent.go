@@ -0,0 +1,57 @@
+package repos
+
+import (
+	"backend/internal/models"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SecurityEventRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSecurityEventRepo(db *pgxpool.Pool) *SecurityEventRepo {
+	return &SecurityEventRepo{db: db}
+}
+
+func (r *SecurityEventRepo) CreateSecurityEvent(ctx context.Context, event *models.SecurityEvent) error {
+	query := `
+		INSERT INTO security_events (type, username, user_id, ip, user_agent, details)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.Exec(ctx, query, event.Type, event.Username, event.UserID, event.IP, event.UserAgent, event.Details)
+	if err != nil {
+		return fmt.Errorf("failed to record security event: %w", err)
+	}
+	return nil
+}
+
+// ListSecurityEvents returns the newest events matching the filter first
+func (r *SecurityEventRepo) ListSecurityEvents(ctx context.Context, filter models.SecurityEventFilter) ([]*models.SecurityEvent, error) {
+	query := `
+		SELECT id, type, username, user_id, COALESCE(ip, ''), COALESCE(user_agent, ''), details, created_at
+		FROM security_events
+		WHERE ($1 = '' OR type = $1)
+		  AND ($2 = '' OR username = $2)
+		  AND ($3 = 0 OR id < $3)
+		ORDER BY id DESC
+		LIMIT $4
+	`
+	rows, err := r.db.Query(ctx, query, filter.Type, filter.Username, filter.BeforeID, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list security events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.SecurityEvent{}
+	for rows.Next() {
+		var event models.SecurityEvent
+		if err := rows.Scan(&event.ID, &event.Type, &event.Username, &event.UserID, &event.IP, &event.UserAgent, &event.Details, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan security event: %w", err)
+		}
+		events = append(events, &event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,43 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SettingsRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewSettingsRepo(db *pgxpool.Pool) *SettingsRepo {
+	return &SettingsRepo{db: db}
+}
+
+// GetSetting returns a setting's value and whether it has been set
+func (r *SettingsRepo) GetSetting(ctx context.Context, key string) (string, bool, error) {
+	var value string
+	err := r.db.QueryRow(ctx, `SELECT value FROM instance_settings WHERE key = $1`, key).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get setting %s: %w", key, err)
+	}
+	return value, true, nil
+}
+
+func (r *SettingsRepo) SetSetting(ctx context.Context, key string, value string) error {
+	query := `
+		INSERT INTO instance_settings (key, value, updated_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = CURRENT_TIMESTAMP
+	`
+	if _, err := r.db.Exec(ctx, query, key, value); err != nil {
+		return fmt.Errorf("failed to set setting %s: %w", key, err)
+	}
+	return nil
+}
@@ -3,12 +3,20 @@ package repos
 import (
 	"backend/internal/models"
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrUsernameTaken = errors.New("username is already taken")
+)
+
 type UserRepo struct {
 	db *pgxpool.Pool
 }
@@ -17,23 +25,32 @@ func NewUserRepo(db *pgxpool.Pool) *UserRepo {
 	return &UserRepo{db: db}
 }
 
-func (r *UserRepo) CreateUser(ctx context.Context, username string, passwordHash string) (*models.User, error) {
+func (r *UserRepo) CreateUser(ctx context.Context, username string, passwordHash string, status string, authSource string) (*models.User, error) {
 	var query string = `
-	INSERT INTO users (username, password_hash)
-	VALUES ($1, $2)
-	RETURNING id, username
+	INSERT INTO users (username, password_hash, status, auth_source)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, username, status, auth_source
 	`
 
 	var id pgtype.UUID
 	var returnedUsername string
-	err := r.db.QueryRow(ctx, query, username, passwordHash).Scan(&id, &returnedUsername)
+	var returnedStatus string
+	var returnedAuthSource string
+	err := r.db.QueryRow(ctx, query, username, passwordHash, status, authSource).Scan(&id, &returnedUsername, &returnedStatus, &returnedAuthSource)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrUsernameTaken
+		}
 		return nil, err
 	}
 
 	user := &models.User{
-		Id:       id,
-		Username: returnedUsername,
+		Id:         id,
+		Username:   returnedUsername,
+		Status:     returnedStatus,
+		AuthSource: returnedAuthSource,
+		IsBot:      returnedAuthSource == models.AuthSourceBot,
 	}
 
 	return user, nil
@@ -41,12 +58,13 @@ func (r *UserRepo) CreateUser(ctx context.Context, username string, passwordHash
 
 func (r *UserRepo) GetAllUsers(ctx context.Context) ([]*models.User, error) {
 	var query string = `
-	SELECT DISTINCT u.id, u.username, u.image_path, 
-	       COALESCE(ARRAY_AGG(p.name) FILTER (WHERE p.name IS NOT NULL), '{}') as permissions
+	SELECT u.id, u.username, u.image_path, u.status, u.auth_source,
+	       COALESCE(ARRAY_AGG(DISTINCT p.name) FILTER (WHERE p.name IS NOT NULL), '{}') as permissions
 	FROM users u
-	LEFT JOIN user_permissions up ON u.id = up.user_id
-	LEFT JOIN permissions p ON up.permission_id = p.id
-	GROUP BY u.id, u.username, u.image_path
+	LEFT JOIN workspace_user_roles wur ON u.id = wur.user_id
+	LEFT JOIN role_permissions rp ON wur.role_id = rp.role_id
+	LEFT JOIN permissions p ON rp.permission_id = p.id
+	GROUP BY u.id, u.username, u.image_path, u.status, u.auth_source
 	`
 
 	rows, err := r.db.Query(ctx, query)
@@ -59,10 +77,11 @@ func (r *UserRepo) GetAllUsers(ctx context.Context) ([]*models.User, error) {
 	for rows.Next() {
 		var user models.User
 		var permissions []string
-		err = rows.Scan(&user.Id, &user.Username, &user.ImagePath, &permissions)
+		err = rows.Scan(&user.Id, &user.Username, &user.ImagePath, &user.Status, &user.AuthSource, &permissions)
 		if err != nil {
 			return nil, err
 		}
+		user.IsBot = user.AuthSource == models.AuthSourceBot
 		
 		user.Permissions = permissions
 		users = append(users, &user)
@@ -74,22 +93,25 @@ func (r *UserRepo) GetAllUsers(ctx context.Context) ([]*models.User, error) {
 func (r *UserRepo) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	fmt.Println("GetUserByUsername called with username:", username)
 	var query string = `
-	SELECT u.id, u.username, u.password_hash, u.image_path,
-	       COALESCE(ARRAY_AGG(p.name) FILTER (WHERE p.name IS NOT NULL), '{}') as permissions
+	SELECT u.id, u.username, u.password_hash, u.image_path, u.status, u.auth_source,
+	       COALESCE(ARRAY_AGG(DISTINCT p.name) FILTER (WHERE p.name IS NOT NULL), '{}') as permissions
 	FROM users u
-	LEFT JOIN user_permissions up ON u.id = up.user_id
-	LEFT JOIN permissions p ON up.permission_id = p.id
+	LEFT JOIN workspace_user_roles wur ON u.id = wur.user_id
+	LEFT JOIN role_permissions rp ON wur.role_id = rp.role_id
+	LEFT JOIN permissions p ON rp.permission_id = p.id
 	WHERE u.username = $1
-	GROUP BY u.id, u.username, u.password_hash, u.image_path
+	GROUP BY u.id, u.username, u.password_hash, u.image_path, u.status, u.auth_source
 	`
 
 	var id pgtype.UUID
 	var returnedUsername string
 	var passwordHash string
 	var imagePath pgtype.Text
+	var status string
+	var authSource string
 	var permissions []string
 	
-	err := r.db.QueryRow(ctx, query, username).Scan(&id, &returnedUsername, &passwordHash, &imagePath, &permissions)
+	err := r.db.QueryRow(ctx, query, username).Scan(&id, &returnedUsername, &passwordHash, &imagePath, &status, &authSource, &permissions)
 	if err != nil {
 		return nil, err
 	}
@@ -100,6 +122,9 @@ func (r *UserRepo) GetUserByUsername(ctx context.Context, username string) (*mod
 		PasswordHash: passwordHash,
 		ImagePath:    imagePath,
 		Permissions:  permissions,
+		Status:       status,
+		AuthSource:   authSource,
+		IsBot:        authSource == models.AuthSourceBot,
 	}
 
 	return user, nil
@@ -107,21 +132,24 @@ func (r *UserRepo) GetUserByUsername(ctx context.Context, username string) (*mod
 
 func (r *UserRepo) GetUserByID(ctx context.Context, userID string) (*models.User, error) {
 	var userQuery string = `
-	SELECT u.id, u.username, u.image_path,
-	       COALESCE(ARRAY_AGG(p.name) FILTER (WHERE p.name IS NOT NULL), '{}') as permissions
+	SELECT u.id, u.username, u.image_path, u.status, u.auth_source,
+	       COALESCE(ARRAY_AGG(DISTINCT p.name) FILTER (WHERE p.name IS NOT NULL), '{}') as permissions
 	FROM users u
-	LEFT JOIN user_permissions up ON u.id = up.user_id
-	LEFT JOIN permissions p ON up.permission_id = p.id
+	LEFT JOIN workspace_user_roles wur ON u.id = wur.user_id
+	LEFT JOIN role_permissions rp ON wur.role_id = rp.role_id
+	LEFT JOIN permissions p ON rp.permission_id = p.id
 	WHERE u.id = $1
-	GROUP BY u.id, u.username, u.image_path
+	GROUP BY u.id, u.username, u.image_path, u.status, u.auth_source
 	`
 
 	var id pgtype.UUID
 	var returnedUsername string
 	var imagePath pgtype.Text
+	var status string
+	var authSource string
 	var permissions []string
 	
-	err := r.db.QueryRow(ctx, userQuery, userID).Scan(&id, &returnedUsername, &imagePath, &permissions)
+	err := r.db.QueryRow(ctx, userQuery, userID).Scan(&id, &returnedUsername, &imagePath, &status, &authSource, &permissions)
 	if err != nil {
 		return nil, err
 	}
@@ -131,17 +159,118 @@ func (r *UserRepo) GetUserByID(ctx context.Context, userID string) (*models.User
 		Username:    returnedUsername,
 		ImagePath:   imagePath,
 		Permissions: permissions,
+		Status:      status,
+		AuthSource:  authSource,
+		IsBot:       authSource == models.AuthSourceBot,
 	}
 
 	return user, nil
 }
 
+// GetPasswordHash returns the bcrypt hash a user signs in with
+func (r *UserRepo) GetPasswordHash(ctx context.Context, userID string) (string, error) {
+	var passwordHash string
+	err := r.db.QueryRow(ctx, `SELECT password_hash FROM users WHERE id = $1`, userID).Scan(&passwordHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrUserNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get password hash: %w", err)
+	}
+	return passwordHash, nil
+}
+
+func (r *UserRepo) UpdatePassword(ctx context.Context, userID string, passwordHash string) error {
+	tag, err := r.db.Exec(ctx, `UPDATE users SET password_hash = $2 WHERE id = $1`, userID, passwordHash)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ListPendingUsers returns self-registered users awaiting approval, oldest first
+func (r *UserRepo) ListPendingUsers(ctx context.Context) ([]*models.User, error) {
+	query := `
+	SELECT id, username, image_path, status
+	FROM users
+	WHERE status = 'pending'
+	ORDER BY created_at
+	`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		user := models.User{Permissions: []string{}}
+		if err := rows.Scan(&user.Id, &user.Username, &user.ImagePath, &user.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan pending user: %w", err)
+		}
+		users = append(users, &user)
+	}
+	return users, rows.Err()
+}
+
+// DecidePendingUser moves a pending user to the given status. Users who are not
+// pending report ErrUserNotFound, so a registration is only decided once.
+func (r *UserRepo) DecidePendingUser(ctx context.Context, userID string, status string) error {
+	query := `UPDATE users SET status = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND status = 'pending'`
+	tag, err := r.db.Exec(ctx, query, userID, status)
+	if err != nil {
+		return fmt.Errorf("failed to update user status: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// DeleteUser removes a user that has not been used yet, such as a registration
+// whose workspace invite could not be redeemed
+func (r *UserRepo) DeleteUser(ctx context.Context, userID string) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	return nil
+}
+
+// ListUsernamesBySource returns the usernames of active users whose password is
+// checked by the given source
+func (r *UserRepo) ListUsernamesBySource(ctx context.Context, authSource string) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+	SELECT username FROM users WHERE auth_source = $1 AND status = 'active' ORDER BY username
+	`, authSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	usernames := []string{}
+	for rows.Next() {
+		var username string
+		if err := rows.Scan(&username); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		usernames = append(usernames, username)
+	}
+	return usernames, rows.Err()
+}
+
+// GetUserPermissions resolves the user's permissions in one workspace through the
+// roles they were assigned there. Only members of the workspace hold permissions in it.
 func (r *UserRepo) GetUserPermissions(ctx context.Context, userID string, workspaceId string) ([]string, error) {
 	var query string = `
-	SELECT p.name 
-	FROM user_permissions up
-	JOIN permissions p ON up.permission_id = p.id
-	WHERE up.user_id = $1 AND up.workspace_id = $2
+	SELECT DISTINCT p.name
+	FROM workspace_user_roles wur
+	JOIN workspace_users wu ON wu.workspace_id = wur.workspace_id AND wu.user_id = wur.user_id
+	JOIN role_permissions rp ON rp.role_id = wur.role_id
+	JOIN permissions p ON p.id = rp.permission_id
+	WHERE wur.user_id = $1 AND wur.workspace_id = $2
 	`
 
 	rows, err := r.db.Query(ctx, query, userID, workspaceId)
@@ -163,12 +292,15 @@ func (r *UserRepo) GetUserPermissions(ctx context.Context, userID string, worksp
 	return permissions, nil
 }
 
+// GetAllUserPermissions returns the union of the user's role permissions across every
+// workspace. It is informational only; authorization must use GetUserPermissions.
 func (r *UserRepo) GetAllUserPermissions(ctx context.Context, userID string) ([]string, error) {
 	var query string = `
-	SELECT DISTINCT p.name 
-	FROM user_permissions up
-	JOIN permissions p ON up.permission_id = p.id
-	WHERE up.user_id = $1
+	SELECT DISTINCT p.name
+	FROM workspace_user_roles wur
+	JOIN role_permissions rp ON rp.role_id = wur.role_id
+	JOIN permissions p ON p.id = rp.permission_id
+	WHERE wur.user_id = $1
 	`
 
 	rows, err := r.db.Query(ctx, query, userID)
@@ -4,6 +4,7 @@ import (
 	"backend/internal/models"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -12,6 +13,9 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrRoleNotFound is returned when a role referenced by name does not exist
+var ErrRoleNotFound = errors.New("role not found")
+
 type WorkspaceRepo struct {
 	db *pgxpool.Pool
 }
@@ -47,7 +51,7 @@ func (repo *WorkspaceRepo) GetWorkspace(ctx context.Context, workspaceID string)
 
     query := `
         SELECT w.id, w.name, w.image_path,
-               u.id, u.username, u.image_path,
+               u.id, u.username, u.image_path, u.auth_source = 'bot',
                c.id, c.channel_name, c.workspace_id, c.channel_emoji
         FROM workspaces w
         LEFT JOIN workspace_users wu ON w.id = wu.workspace_id
@@ -72,6 +76,7 @@ func (repo *WorkspaceRepo) GetWorkspace(ctx context.Context, workspaceID string)
         var userID pgtype.UUID
         var userName pgtype.Text
         var userImagePath pgtype.Text
+        var userIsBot pgtype.Bool
 
         // Add channel variables to match the query
         var channelID sql.NullInt32
@@ -86,6 +91,7 @@ func (repo *WorkspaceRepo) GetWorkspace(ctx context.Context, workspaceID string)
             &userID,
             &userName,
             &userImagePath,
+            &userIsBot,
             &channelID,          // Scan channel ID
             &channelName,        // Scan channel name
             &channelWorkspaceID, // Scan channel workspace ID
@@ -113,7 +119,8 @@ func (repo *WorkspaceRepo) GetWorkspace(ctx context.Context, workspaceID string)
             
             if _, exists := userMap[userKey]; !exists {
                 currentUser := models.User{
-                    Id: userID,
+                    Id:    userID,
+                    IsBot: userIsBot.Bool,
                 }
                 if userName.Valid {
                     currentUser.Username = userName.String
@@ -192,26 +199,60 @@ func (repo *WorkspaceRepo) GetAllWorkspaces(ctx context.Context) ([]*models.Work
 	return workspaces, nil
 }
 
-func (repo *WorkspaceRepo) AddUserToWorkspace(ctx context.Context, userID string, workspaceID string) error {
-    query := `
+// AddUserToWorkspace makes the user a member of the workspace and assigns them the
+// named role in the same transaction. Existing members are left untouched.
+// It reports whether the user was newly added.
+func (repo *WorkspaceRepo) AddUserToWorkspace(ctx context.Context, userID string, workspaceID string, roleName string) (bool, error) {
+    tx, err := repo.db.Begin(ctx)
+    if err != nil {
+        return false, fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    defer tx.Rollback(ctx)
+
+    var roleID int
+    err = tx.QueryRow(ctx, `SELECT id FROM roles WHERE name = $1`, roleName).Scan(&roleID)
+    if err != nil {
+        if errors.Is(err, pgx.ErrNoRows) {
+            return false, ErrRoleNotFound
+        }
+        return false, fmt.Errorf("failed to look up role %q: %w", roleName, err)
+    }
+
+    added, err := addWorkspaceMember(ctx, tx, userID, workspaceID, roleID)
+    if err != nil {
+        return false, err
+    }
+
+    if err := tx.Commit(ctx); err != nil {
+        return false, fmt.Errorf("failed to commit transaction: %w", err)
+    }
+    return added, nil
+}
+
+// addWorkspaceMember inserts the membership and its initial role inside tx
+func addWorkspaceMember(ctx context.Context, tx pgx.Tx, userID string, workspaceID string, roleID int) (bool, error) {
+    memberQuery := `
         INSERT INTO workspace_users (user_id, workspace_id)
         VALUES ($1, $2)
-        ON CONFLICT (user_id, workspace_id) DO NOTHING
+        ON CONFLICT (workspace_id, user_id) DO NOTHING
     `
-    _, err := repo.db.Exec(ctx, query, userID, workspaceID)
+    result, err := tx.Exec(ctx, memberQuery, userID, workspaceID)
     if err != nil {
-        return err
+        return false, fmt.Errorf("failed to add workspace member: %w", err)
+    }
+    if result.RowsAffected() == 0 {
+        return false, nil
     }
 
-    userPermissionQuery := `
-        INSERT INTO user_permissions (user_id, workspace_id, permission)
-        VALUES ($1, $2, 'user')
+    roleQuery := `
+        INSERT INTO workspace_user_roles (workspace_id, user_id, role_id)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (workspace_id, user_id, role_id) DO NOTHING
     `
-    _, err = repo.db.Exec(ctx, userPermissionQuery, userID, workspaceID)
-    if err != nil {
-        return err
+    if _, err := tx.Exec(ctx, roleQuery, workspaceID, userID, roleID); err != nil {
+        return false, fmt.Errorf("failed to assign role to new member: %w", err)
     }
-    return nil
+    return true, nil
 }
 
 func (repo *WorkspaceRepo) GetUserWorkspaces(ctx context.Context, userId string) ([]*models.Workspace, error) {
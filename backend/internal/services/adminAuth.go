@@ -0,0 +1,294 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repos"
+	"backend/pkg/passwordpolicy"
+	"backend/pkg/ratelimiter"
+	"backend/pkg/totp"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// minAdminPasswordLength applies to admin accounts, which control the whole instance
+const minAdminPasswordLength = 12
+
+var (
+	ErrInvalidAdminCredentials    = errors.New("invalid admin credentials")
+	ErrAdminTOTPCodeRequired      = errors.New("two-factor code required")
+	ErrAdminTOTPAlreadyConfigured = errors.New("admin two-factor authentication is already configured")
+	ErrAdminTOTPSetupNotFound     = errors.New("no admin two-factor setup in progress")
+	ErrInvalidAdminUsername       = errors.New("admin username must be between 1 and 50 characters")
+	ErrCannotDisableSelf          = errors.New("admins cannot disable their own account")
+	ErrLastAdminAccount           = errors.New("the last active admin account cannot be disabled")
+)
+
+// AdminAuthService authenticates admin accounts and manages them. Accounts may enroll a
+// TOTP secret once through the setup endpoints, after which logins must include a code.
+type AdminAuthService struct {
+	adminRepo      *repos.AdminAccountRepo
+	passwordPolicy *passwordpolicy.Policy
+	lockout        ratelimiter.Lockout
+	securityEvents *SecurityEventService
+	issuer         string
+}
+
+func NewAdminAuthService(adminRepo *repos.AdminAccountRepo, passwordPolicy *passwordpolicy.Policy, lockout ratelimiter.Lockout, securityEvents *SecurityEventService, issuer string) *AdminAuthService {
+	if issuer == "" {
+		issuer = "BarbedWork"
+	}
+	return &AdminAuthService{
+		adminRepo:      adminRepo,
+		passwordPolicy: passwordPolicy.WithMinLength(minAdminPasswordLength),
+		lockout:        lockout,
+		securityEvents: securityEvents,
+		issuer:         issuer,
+	}
+}
+
+// Authenticate checks an admin's password and TOTP code for a client.
+// Every failure counts towards both the client's and the username's lockout.
+func (s *AdminAuthService) Authenticate(ctx context.Context, client ClientInfo, username string, password string, code string) (*models.AdminAccount, error) {
+	if err := s.checkLockout(ctx, client, username); err != nil {
+		return nil, err
+	}
+
+	account, err := s.checkPassword(ctx, username, password)
+	if err != nil {
+		return nil, s.recordFailure(ctx, client, username, err)
+	}
+
+	if account.TOTPEnabled() {
+		// A correct password without a code is the first half of a login, not a failure
+		if strings.TrimSpace(code) == "" {
+			return nil, ErrAdminTOTPCodeRequired
+		}
+		if err := s.verifyTOTP(ctx, account, code); err != nil {
+			return nil, s.recordFailure(ctx, client, username, err)
+		}
+	}
+
+	for _, key := range adminLockoutKeys(client, username) {
+		if err := s.lockout.Reset(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.adminRepo.RecordLogin(ctx, account.Id.String()); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func (s *AdminAuthService) checkPassword(ctx context.Context, username string, password string) (*models.AdminAccount, error) {
+	account, err := s.adminRepo.GetAdminAccountByUsername(ctx, username)
+	if errors.Is(err, repos.ErrAdminAccountNotFound) {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidAdminCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(password)) != nil || account.Disabled() {
+		return nil, ErrInvalidAdminCredentials
+	}
+	return account, nil
+}
+
+func (s *AdminAuthService) verifyTOTP(ctx context.Context, account *models.AdminAccount, code string) error {
+	step, ok := totp.Validate(account.TOTPSecret.String, code, time.Now())
+	if !ok {
+		return ErrInvalidAdminCredentials
+	}
+	// Accept each time step once so an observed code cannot be replayed
+	used, err := s.adminRepo.UseTOTPStep(ctx, account.Id.String(), step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidAdminCredentials
+	}
+	return nil
+}
+
+// BeginTOTPSetup generates a secret for the admin to add to their authenticator app.
+// It is refused once the account has a confirmed secret, so enrollment only happens once.
+func (s *AdminAuthService) BeginTOTPSetup(ctx context.Context, client ClientInfo, username string, password string) (*models.MFAEnrollment, error) {
+	account, err := s.checkSetupAllowed(ctx, client, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	saved, err := s.adminRepo.SavePendingTOTP(ctx, account.Id.String(), secret)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrAdminTOTPAlreadyConfigured
+	}
+
+	return &models.MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.issuer+" Admin", account.Username, secret),
+	}, nil
+}
+
+// ConfirmTOTPSetup enables the pending secret once the admin proves their app produces valid codes
+func (s *AdminAuthService) ConfirmTOTPSetup(ctx context.Context, client ClientInfo, username string, password string, code string) error {
+	account, err := s.checkSetupAllowed(ctx, client, username, password)
+	if err != nil {
+		return err
+	}
+	if !account.TOTPSecret.Valid {
+		return ErrAdminTOTPSetupNotFound
+	}
+
+	step, ok := totp.Validate(account.TOTPSecret.String, code, time.Now())
+	if !ok {
+		return s.recordFailure(ctx, client, username, ErrInvalidAdminCredentials)
+	}
+
+	confirmed, err := s.adminRepo.ConfirmTOTP(ctx, account.Id.String(), step)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return ErrAdminTOTPAlreadyConfigured
+	}
+	return nil
+}
+
+func (s *AdminAuthService) checkSetupAllowed(ctx context.Context, client ClientInfo, username string, password string) (*models.AdminAccount, error) {
+	if err := s.checkLockout(ctx, client, username); err != nil {
+		return nil, err
+	}
+	account, err := s.checkPassword(ctx, username, password)
+	if err != nil {
+		return nil, s.recordFailure(ctx, client, username, err)
+	}
+	if account.TOTPEnabled() {
+		return nil, ErrAdminTOTPAlreadyConfigured
+	}
+	return account, nil
+}
+
+// CreateAccount adds an admin. createdBy is the acting admin, or empty for the bootstrap command.
+// Passwords that break the policy return a *passwordpolicy.ValidationError.
+func (s *AdminAuthService) CreateAccount(ctx context.Context, username string, password string, createdBy string) (*models.AdminAccount, error) {
+	username = strings.TrimSpace(username)
+	if username == "" || len(username) > 50 {
+		return nil, ErrInvalidAdminUsername
+	}
+	if err := s.passwordPolicy.Validate(password, username); err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	return s.adminRepo.CreateAdminAccount(ctx, username, string(hash), createdBy)
+}
+
+func (s *AdminAuthService) ListAccounts(ctx context.Context) ([]*models.AdminAccount, error) {
+	return s.adminRepo.ListAdminAccounts(ctx)
+}
+
+// GetActiveAccount returns the account behind an admin session, failing once it is disabled
+func (s *AdminAuthService) GetActiveAccount(ctx context.Context, id string) (*models.AdminAccount, error) {
+	account, err := s.adminRepo.GetAdminAccountByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if account.Disabled() {
+		return nil, repos.ErrAdminAccountNotFound
+	}
+	return account, nil
+}
+
+// DisableAccount stops another admin from signing in
+func (s *AdminAuthService) DisableAccount(ctx context.Context, id string, actingAdminID string) error {
+	if id == actingAdminID {
+		return ErrCannotDisableSelf
+	}
+	if _, err := s.adminRepo.GetAdminAccountByID(ctx, id); err != nil {
+		return err
+	}
+
+	disabled, err := s.adminRepo.DisableAdminAccount(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !disabled {
+		return ErrLastAdminAccount
+	}
+	return nil
+}
+
+// ResetTOTP removes an admin's second factor, for the bootstrap command when a device is lost
+func (s *AdminAuthService) ResetTOTP(ctx context.Context, username string) error {
+	account, err := s.adminRepo.GetAdminAccountByUsername(ctx, username)
+	if err != nil {
+		return err
+	}
+	return s.adminRepo.ResetTOTP(ctx, account.Id.String())
+}
+
+// adminLockoutKeys locks out admin logins per client address, since admin usernames are
+// few and guessable, and per username, so rotating addresses against one account is still throttled
+func adminLockoutKeys(client ClientInfo, username string) []string {
+	return []string{
+		"admin:" + client.IP,
+		"admin_user:" + strings.ToLower(strings.TrimSpace(username)),
+	}
+}
+
+func (s *AdminAuthService) checkLockout(ctx context.Context, client ClientInfo, username string) error {
+	for _, key := range adminLockoutKeys(client, username) {
+		err := checkLockout(ctx, s.lockout, key)
+		var lockoutErr *LockoutError
+		if errors.As(err, &lockoutErr) {
+			s.securityEvents.Record(ctx, models.SecurityEventAdminLoginBlocked, username, "", client, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordFailure counts a failed attempt, returning a LockoutError if it triggered one.
+// Errors other than bad credentials are passed through without counting.
+func (s *AdminAuthService) recordFailure(ctx context.Context, client ClientInfo, username string, cause error) error {
+	if !errors.Is(cause, ErrInvalidAdminCredentials) {
+		return cause
+	}
+	s.securityEvents.Record(ctx, models.SecurityEventAdminLoginFailed, username, "", client, nil)
+
+	// Both counters take the failure; the longer of any lockouts it triggers is reported
+	var lockoutErr *LockoutError
+	for _, key := range adminLockoutKeys(client, username) {
+		keyErr, err := recordFailedAttempt(ctx, s.lockout, key)
+		if err != nil {
+			return err
+		}
+		if keyErr != nil && (lockoutErr == nil || keyErr.RetryAfter > lockoutErr.RetryAfter) {
+			lockoutErr = keyErr
+		}
+	}
+	if lockoutErr != nil {
+		s.securityEvents.Record(ctx, models.SecurityEventAdminLockedOut, username, "", client, map[string]any{
+			"retry_after_seconds": int(lockoutErr.RetryAfter.Seconds()),
+		})
+		return lockoutErr
+	}
+	return cause
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repos"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnknownAccount is returned by an Authenticator that has no account for the
+// username, letting the next one try
+var ErrUnknownAccount = errors.New("no account with that username")
+
+// Authenticator checks a username and password against one source of accounts. It
+// returns ErrUnknownAccount for usernames it does not handle and ErrInvalidPassword
+// for accounts it does handle when the password is wrong.
+type Authenticator interface {
+	Authenticate(ctx context.Context, username string, password string) (*models.User, error)
+}
+
+// passwordUserStore is the part of repos.UserRepo that PasswordAuthenticator reads
+type passwordUserStore interface {
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+}
+
+// PasswordAuthenticator checks users against the bcrypt hashes stored in the database.
+// Only local accounts sign in with a password; bots act through API tokens alone.
+type PasswordAuthenticator struct {
+	userRepo passwordUserStore
+}
+
+func NewPasswordAuthenticator(userRepo *repos.UserRepo) *PasswordAuthenticator {
+	return &PasswordAuthenticator{userRepo: userRepo}
+}
+
+func (a *PasswordAuthenticator) Authenticate(ctx context.Context, username string, password string) (*models.User, error) {
+	user, err := a.userRepo.GetUserByUsername(ctx, username)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if user != nil && user.AuthSource != models.AuthSourceLocal {
+		return nil, ErrUnknownAccount
+	}
+
+	passwordHash := dummyPasswordHash
+	if user != nil {
+		passwordHash = []byte(user.PasswordHash)
+	}
+	// Unknown usernames still pay for a comparison so timing does not reveal them
+	passwordErr := bcrypt.CompareHashAndPassword(passwordHash, []byte(password))
+	if user == nil {
+		return nil, ErrUnknownAccount
+	}
+	if passwordErr != nil {
+		return nil, ErrInvalidPassword
+	}
+	return user, nil
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repos"
+	"context"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidBotDescription = errors.New("bot description must be at most 255 characters")
+
+type BotService struct {
+	botRepo         *repos.BotRepo
+	userRepo        permissionLookup
+	roleService     roleLookup
+	apiTokenService *APITokenService
+}
+
+func NewBotService(botRepo *repos.BotRepo, userRepo *repos.UserRepo, roleService *RoleService, apiTokenService *APITokenService) *BotService {
+	return &BotService{botRepo: botRepo, userRepo: userRepo, roleService: roleService, apiTokenService: apiTokenService}
+}
+
+// CreateBot creates a bot in the workspace and assigns it the requested roles. As
+// with invites, members may only hand out roles whose permissions they hold in the
+// workspace themselves; instance admins may use any role. The bot and its role
+// assignments are created together, so a failure leaves neither behind.
+func (s *BotService) CreateBot(ctx context.Context, workspaceID string, creatorID string, creatorIsAdmin bool, req models.CreateBotRequest) (*models.Bot, []*models.WorkspaceUserRole, error) {
+	username := strings.TrimSpace(req.Username)
+	if err := ValidateUsername(username); err != nil {
+		return nil, nil, err
+	}
+	description := strings.TrimSpace(req.Description)
+	if len(description) > 255 {
+		return nil, nil, ErrInvalidBotDescription
+	}
+
+	roles := make([]*models.Role, 0, len(req.RoleIDs))
+	roleIDs := make([]int, 0, len(req.RoleIDs))
+	for _, roleID := range req.RoleIDs {
+		role, err := s.roleService.GetRoleByID(ctx, roleID)
+		if err != nil {
+			return nil, nil, err
+		}
+		roles = append(roles, role)
+		roleIDs = append(roleIDs, role.ID)
+	}
+
+	if err := checkGrantableRoles(ctx, s.userRepo, workspaceID, creatorID, creatorIsAdmin, roles...); err != nil {
+		return nil, nil, err
+	}
+	createdBy, createdByAdmin := creatorIDs(creatorID, creatorIsAdmin)
+
+	// Bots authenticate with API tokens only
+	passwordHash, err := unusablePasswordHash()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s.botRepo.CreateBot(ctx, workspaceID, username, passwordHash, description, createdBy, createdByAdmin, roleIDs)
+}
+
+func (s *BotService) ListBots(ctx context.Context, workspaceID string) ([]*models.Bot, error) {
+	return s.botRepo.ListBots(ctx, workspaceID)
+}
+
+// DeactivateBot removes the bot from the workspace and revokes its tokens
+func (s *BotService) DeactivateBot(ctx context.Context, workspaceID string, botID string) error {
+	return s.botRepo.DeactivateBot(ctx, workspaceID, botID)
+}
+
+// CreateToken creates an API token the bot acts with
+func (s *BotService) CreateToken(ctx context.Context, workspaceID string, botID string, req models.CreateAPITokenRequest, client ClientInfo) (*models.APIToken, error) {
+	if _, err := s.botRepo.GetBot(ctx, workspaceID, botID); err != nil {
+		return nil, err
+	}
+	return s.apiTokenService.CreateToken(ctx, botID, req, client)
+}
+
+func (s *BotService) ListTokens(ctx context.Context, workspaceID string, botID string) ([]*models.APIToken, error) {
+	if _, err := s.botRepo.GetBot(ctx, workspaceID, botID); err != nil {
+		return nil, err
+	}
+	return s.apiTokenService.ListTokens(ctx, botID)
+}
+
+func (s *BotService) RevokeToken(ctx context.Context, workspaceID string, botID string, tokenID string, client ClientInfo) error {
+	if _, err := s.botRepo.GetBot(ctx, workspaceID, botID); err != nil {
+		return err
+	}
+	return s.apiTokenService.RevokeToken(ctx, botID, tokenID, client)
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCreateBotRejectsRolesTheCreatorLacks(t *testing.T) {
+	service := &BotService{
+		roleService: rolesByID{memberRole.ID: memberRole, adminRole.ID: adminRole},
+		userRepo:    granterPermissions,
+	}
+
+	req := models.CreateBotRequest{Username: "helper", RoleIDs: []int{memberRole.ID, adminRole.ID}}
+	if _, _, err := service.CreateBot(context.Background(), "ws1", "alice", false, req); !errors.Is(err, ErrRoleNotGrantable) {
+		t.Fatalf("CreateBot() = %v, want %v", err, ErrRoleNotGrantable)
+	}
+}
+
+type usersByName map[string]*models.User
+
+func (u usersByName) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	user, ok := u[username]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return user, nil
+}
+
+func TestPasswordAuthenticatorRejectsBots(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := &PasswordAuthenticator{userRepo: usersByName{
+		"alice":  {Username: "alice", PasswordHash: string(hash), AuthSource: models.AuthSourceLocal},
+		"helper": {Username: "helper", PasswordHash: string(hash), AuthSource: models.AuthSourceBot, IsBot: true},
+	}}
+
+	if _, err := authenticator.Authenticate(context.Background(), "alice", "correct horse"); err != nil {
+		t.Fatalf("local user rejected: %v", err)
+	}
+	// Even the bot's real password does not sign it in; it only acts through API tokens
+	if _, err := authenticator.Authenticate(context.Background(), "helper", "correct horse"); !errors.Is(err, ErrUnknownAccount) {
+		t.Errorf("bot password login error = %v, want ErrUnknownAccount", err)
+	}
+}
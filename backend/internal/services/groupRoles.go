@@ -0,0 +1,139 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"backend/internal/repos"
+	"backend/pkg/grouproles"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// GroupRoleChange is a membership or role change made while syncing groups.
+// Change is one of models.EventMemberAdded, EventRoleAssigned or EventRoleRemoved.
+type GroupRoleChange struct {
+	WorkspaceID string
+	RoleID      int
+	Change      string
+}
+
+// PublishGroupRoleChanges tells the user's clients about the memberships and roles a
+// sign-in changed while syncing groups
+func PublishGroupRoleChanges(ctx context.Context, events realtime.Publisher, userID string, changes []GroupRoleChange) {
+	for _, change := range changes {
+		var payload any = map[string]any{"user_id": userID, "role_id": change.RoleID}
+		if change.Change == models.EventMemberAdded {
+			payload = map[string]string{"user_id": userID, "workspace_id": change.WorkspaceID}
+		}
+		event := realtime.NewEvent(change.Change, change.WorkspaceID, payload)
+		event.SubjectUserID = userID
+		events.Publish(ctx, event)
+	}
+}
+
+// GroupRoleSync keeps workspace memberships and roles in line with the groups a user
+// belongs to in an external identity source
+type GroupRoleSync struct {
+	workspaceRepo *repos.WorkspaceRepo
+	roleRepo      *repos.RoleRepo
+}
+
+func NewGroupRoleSync(workspaceRepo *repos.WorkspaceRepo, roleRepo *repos.RoleRepo) *GroupRoleSync {
+	return &GroupRoleSync{workspaceRepo: workspaceRepo, roleRepo: roleRepo}
+}
+
+// Sync makes the user's workspace roles match their mapped groups. Mapped roles are
+// granted, joining the workspace if needed, and mapped roles for groups the user has
+// left are withdrawn. Roles no mapping mentions are left alone.
+func (s *GroupRoleSync) Sync(ctx context.Context, userID string, mappings []grouproles.GroupRole, groups []string) ([]GroupRoleChange, error) {
+	granted, withdrawn := grouproles.Split(mappings, groups)
+
+	type workspaceRole struct{ workspaceID, role string }
+	wanted := make(map[workspaceRole]bool, len(granted))
+	for _, mapping := range granted {
+		wanted[workspaceRole{mapping.WorkspaceID, mapping.Role}] = true
+	}
+
+	// The user's current roles, fetched once per workspace when needed
+	current := map[string]map[int]bool{}
+	rolesIn := func(workspaceID string) (map[int]bool, error) {
+		if roles, ok := current[workspaceID]; ok {
+			return roles, nil
+		}
+		assignments, err := s.roleRepo.GetWorkspaceUserRoles(ctx, workspaceID)
+		if err != nil {
+			return nil, err
+		}
+		roles := map[int]bool{}
+		for _, assignment := range assignments {
+			if assignment.UserID.String() == userID {
+				roles[assignment.RoleID] = true
+			}
+		}
+		current[workspaceID] = roles
+		return roles, nil
+	}
+
+	var changes []GroupRoleChange
+	var errs []error
+	for _, mapping := range granted {
+		roleID, err := s.roleRepo.GetRoleIDByName(ctx, mapping.Role)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("group %q role %q: %w", mapping.Group, mapping.Role, err))
+			continue
+		}
+		added, err := s.workspaceRepo.AddUserToWorkspace(ctx, userID, mapping.WorkspaceID, mapping.Role)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("group %q workspace %q: %w", mapping.Group, mapping.WorkspaceID, err))
+			continue
+		}
+		if added {
+			delete(current, mapping.WorkspaceID)
+			changes = append(changes, GroupRoleChange{WorkspaceID: mapping.WorkspaceID, RoleID: roleID, Change: models.EventMemberAdded})
+			continue
+		}
+
+		roles, err := rolesIn(mapping.WorkspaceID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if roles[roleID] {
+			continue
+		}
+		if _, err := s.roleRepo.AssignRoleToUser(ctx, mapping.WorkspaceID, userID, roleID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		roles[roleID] = true
+		changes = append(changes, GroupRoleChange{WorkspaceID: mapping.WorkspaceID, RoleID: roleID, Change: models.EventRoleAssigned})
+	}
+
+	for _, mapping := range withdrawn {
+		// Another group the user is in may grant the same role
+		if wanted[workspaceRole{mapping.WorkspaceID, mapping.Role}] {
+			continue
+		}
+		roleID, err := s.roleRepo.GetRoleIDByName(ctx, mapping.Role)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("group %q role %q: %w", mapping.Group, mapping.Role, err))
+			continue
+		}
+		roles, err := rolesIn(mapping.WorkspaceID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !roles[roleID] {
+			continue
+		}
+		if err := s.roleRepo.RemoveRoleFromUser(ctx, mapping.WorkspaceID, userID, roleID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(roles, roleID)
+		changes = append(changes, GroupRoleChange{WorkspaceID: mapping.WorkspaceID, RoleID: roleID, Change: models.EventRoleRemoved})
+	}
+	return changes, errors.Join(errs...)
+}
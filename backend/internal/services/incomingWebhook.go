@@ -0,0 +1,220 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repos"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// IncomingWebhookPath is where webhook tokens are posted to, relative to the API's base URL
+const IncomingWebhookPath = "/api/webhooks/incoming/"
+
+const (
+	incomingWebhookTokenPrefix    = "bwh_"
+	maxIncomingWebhooksPerChannel = 20
+	maxWebhookNameLength          = 80
+	maxWebhookAttachments         = 10
+	maxAttachmentFields           = 20
+	maxAttachmentTitleLength      = 256
+	maxAttachmentFieldLength      = 1000
+)
+
+var (
+	ErrInvalidWebhookName        = fmt.Errorf("webhook name must be 1 to %d characters", maxWebhookNameLength)
+	ErrTooManyIncomingWebhooks   = errors.New("too many webhooks in this channel; delete one before creating another")
+	ErrInvalidWebhookDisplayName = fmt.Errorf("display name must be at most %d characters", maxWebhookNameLength)
+	ErrTooManyAttachments        = fmt.Errorf("a message can have at most %d attachments", maxWebhookAttachments)
+	ErrInvalidAttachment         = errors.New("invalid attachment")
+)
+
+var attachmentColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+type IncomingWebhookService struct {
+	webhookRepo *repos.IncomingWebhookRepo
+	messageRepo *repos.MessageRepo
+}
+
+func NewIncomingWebhookService(webhookRepo *repos.IncomingWebhookRepo, messageRepo *repos.MessageRepo) *IncomingWebhookService {
+	return &IncomingWebhookService{webhookRepo: webhookRepo, messageRepo: messageRepo}
+}
+
+// CreateWebhook creates a webhook for the channel and returns it with its token and
+// URL, which cannot be retrieved again
+func (s *IncomingWebhookService) CreateWebhook(ctx context.Context, workspaceID string, channelID int, creatorID string, creatorIsAdmin bool, req models.CreateIncomingWebhookRequest) (*models.IncomingWebhook, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxWebhookNameLength {
+		return nil, ErrInvalidWebhookName
+	}
+	if err := s.ensureChannel(ctx, workspaceID, channelID); err != nil {
+		return nil, err
+	}
+
+	count, err := s.webhookRepo.CountWebhooks(ctx, workspaceID, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxIncomingWebhooksPerChannel {
+		return nil, ErrTooManyIncomingWebhooks
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	token := incomingWebhookTokenPrefix + secret
+
+	createdBy, createdByAdmin := creatorIDs(creatorID, creatorIsAdmin)
+	webhook, err := s.webhookRepo.CreateWebhook(ctx, workspaceID, channelID, name, hashIncomingWebhookToken(token), createdBy, createdByAdmin)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Token = token
+	webhook.URL = IncomingWebhookPath + token
+	return webhook, nil
+}
+
+func (s *IncomingWebhookService) ListWebhooks(ctx context.Context, workspaceID string, channelID int) ([]*models.IncomingWebhook, error) {
+	if err := s.ensureChannel(ctx, workspaceID, channelID); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.ListWebhooks(ctx, workspaceID, channelID)
+}
+
+// DeleteWebhook stops the webhook's URL from working. Messages it posted stay.
+func (s *IncomingWebhookService) DeleteWebhook(ctx context.Context, workspaceID string, channelID int, webhookID string) error {
+	return s.webhookRepo.DeleteWebhook(ctx, workspaceID, channelID, webhookID)
+}
+
+// Authenticate finds the webhook a token belongs to. Unknown tokens return
+// repos.ErrIncomingWebhookNotFound.
+func (s *IncomingWebhookService) Authenticate(ctx context.Context, token string) (*models.IncomingWebhook, error) {
+	if !strings.HasPrefix(token, incomingWebhookTokenPrefix) {
+		return nil, repos.ErrIncomingWebhookNotFound
+	}
+	return s.webhookRepo.GetWebhookByTokenHash(ctx, hashIncomingWebhookToken(token))
+}
+
+// Post validates a payload and stores it as a message in the webhook's channel. The
+// message shows the payload's display name, or the webhook's name when it has none.
+func (s *IncomingWebhookService) Post(ctx context.Context, webhook *models.IncomingWebhook, payload models.IncomingWebhookPayload) (*models.WorkspaceChannelMessage, error) {
+	text := strings.TrimSpace(payload.Text)
+	if text == "" && len(payload.Attachments) == 0 {
+		return nil, ErrEmptyMessage
+	}
+	if utf8.RuneCountInString(text) > MaxMessageLength {
+		return nil, ErrMessageTooLong
+	}
+
+	displayName := strings.TrimSpace(payload.DisplayName)
+	if displayName == "" {
+		displayName = strings.TrimSpace(payload.Username)
+	}
+	if displayName == "" {
+		displayName = webhook.Name
+	}
+	if utf8.RuneCountInString(displayName) > maxWebhookNameLength {
+		return nil, ErrInvalidWebhookDisplayName
+	}
+
+	attachments, err := normalizeAttachments(payload.Attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := s.messageRepo.CreateWebhookMessage(ctx, webhook.WorkspaceID.String(), webhook.ChannelID, webhook.ID.String(), displayName, text, attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.webhookRepo.TouchWebhook(ctx, webhook.ID.String()); err != nil {
+		fmt.Println("Failed to record incoming webhook use:", err)
+	}
+	return message, nil
+}
+
+func (s *IncomingWebhookService) ensureChannel(ctx context.Context, workspaceID string, channelID int) error {
+	exists, err := s.messageRepo.ChannelExists(ctx, workspaceID, channelID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrChannelNotFound
+	}
+	return nil
+}
+
+// normalizeAttachments trims the attachments' text and checks each stays within limits.
+// Colors are hex codes or one of Slack's good, warning and danger.
+func normalizeAttachments(attachments []models.MessageAttachment) ([]models.MessageAttachment, error) {
+	if len(attachments) > maxWebhookAttachments {
+		return nil, ErrTooManyAttachments
+	}
+
+	normalized := make([]models.MessageAttachment, 0, len(attachments))
+	for i, attachment := range attachments {
+		invalid := func(reason string) error {
+			return fmt.Errorf("%w %d: %s", ErrInvalidAttachment, i+1, reason)
+		}
+
+		attachment.Title = strings.TrimSpace(attachment.Title)
+		attachment.TitleLink = strings.TrimSpace(attachment.TitleLink)
+		attachment.Text = strings.TrimSpace(attachment.Text)
+		attachment.Color = strings.TrimSpace(attachment.Color)
+
+		if attachment.Title == "" && attachment.Text == "" && len(attachment.Fields) == 0 {
+			return nil, invalid("needs a title, text or fields")
+		}
+		if utf8.RuneCountInString(attachment.Title) > maxAttachmentTitleLength {
+			return nil, invalid(fmt.Sprintf("title must be at most %d characters", maxAttachmentTitleLength))
+		}
+		if utf8.RuneCountInString(attachment.Text) > MaxMessageLength {
+			return nil, invalid(fmt.Sprintf("text must be at most %d characters", MaxMessageLength))
+		}
+		if attachment.TitleLink != "" {
+			link, err := url.Parse(attachment.TitleLink)
+			if err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+				return nil, invalid("title_link must be an http or https URL")
+			}
+		}
+		switch attachment.Color {
+		case "", "good", "warning", "danger":
+		default:
+			if !attachmentColorPattern.MatchString(attachment.Color) {
+				return nil, invalid("color must be a hex code, good, warning or danger")
+			}
+		}
+
+		if len(attachment.Fields) > maxAttachmentFields {
+			return nil, invalid(fmt.Sprintf("at most %d fields are allowed", maxAttachmentFields))
+		}
+		fields := make([]models.MessageAttachmentField, 0, len(attachment.Fields))
+		for _, field := range attachment.Fields {
+			field.Title = strings.TrimSpace(field.Title)
+			field.Value = strings.TrimSpace(field.Value)
+			if field.Title == "" && field.Value == "" {
+				continue
+			}
+			if utf8.RuneCountInString(field.Title) > maxAttachmentTitleLength || utf8.RuneCountInString(field.Value) > maxAttachmentFieldLength {
+				return nil, invalid(fmt.Sprintf("field titles must be at most %d characters and values at most %d", maxAttachmentTitleLength, maxAttachmentFieldLength))
+			}
+			fields = append(fields, field)
+		}
+		attachment.Fields = fields
+
+		normalized = append(normalized, attachment)
+	}
+	return normalized, nil
+}
+
+func hashIncomingWebhookToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
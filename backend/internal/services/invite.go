@@ -0,0 +1,111 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repos"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const (
+	defaultInviteExpiryHours = 7 * 24
+	maxInviteExpiryHours     = 30 * 24
+	maxInviteUses            = 1000
+)
+
+var (
+	ErrInvalidInviteExpiry  = errors.New("invite expiry must be between 1 hour and 30 days")
+	ErrInvalidInviteMaxUses = errors.New("invite max uses must be between 0 (unlimited) and 1000")
+)
+
+type InviteService struct {
+	inviteRepo  *repos.InviteRepo
+	roleService roleLookup
+	userRepo    permissionLookup
+}
+
+func NewInviteService(inviteRepo *repos.InviteRepo, roleService *RoleService, userRepo *repos.UserRepo) *InviteService {
+	return &InviteService{inviteRepo: inviteRepo, roleService: roleService, userRepo: userRepo}
+}
+
+// CreateInvite creates an invite to the workspace and returns it with its code. Members
+// may only hand out roles whose permissions they hold themselves in the workspace, so an
+// invite cannot be used to escalate privileges; instance admins may use any role.
+func (s *InviteService) CreateInvite(ctx context.Context, workspaceID string, inviterID string, inviterIsAdmin bool, req models.CreateInviteRequest) (*models.WorkspaceInvite, error) {
+	if req.ExpiresInHours == 0 {
+		req.ExpiresInHours = defaultInviteExpiryHours
+	}
+	if req.ExpiresInHours < 1 || req.ExpiresInHours > maxInviteExpiryHours {
+		return nil, ErrInvalidInviteExpiry
+	}
+	if req.MaxUses < 0 || req.MaxUses > maxInviteUses {
+		return nil, ErrInvalidInviteMaxUses
+	}
+
+	role, err := s.roleService.GetRoleByID(ctx, req.RoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkGrantableRoles(ctx, s.userRepo, workspaceID, inviterID, inviterIsAdmin, role); err != nil {
+		return nil, err
+	}
+	createdBy, createdByAdmin := creatorIDs(inviterID, inviterIsAdmin)
+
+	code, err := generateInviteCode()
+	if err != nil {
+		return nil, err
+	}
+
+	invite, err := s.inviteRepo.CreateInvite(ctx, workspaceID, hashInviteCode(code), role.ID, createdBy, createdByAdmin, req.MaxUses, req.ExpiresInHours)
+	if err != nil {
+		return nil, err
+	}
+	invite.Code = code
+	return invite, nil
+}
+
+func (s *InviteService) ListInvites(ctx context.Context, workspaceID string) ([]*models.WorkspaceInvite, error) {
+	return s.inviteRepo.ListInvites(ctx, workspaceID)
+}
+
+func (s *InviteService) RevokeInvite(ctx context.Context, workspaceID string, inviteID string) error {
+	return s.inviteRepo.RevokeInvite(ctx, workspaceID, inviteID)
+}
+
+// PreviewInvite shows an invitee which workspace and role an invite code is for
+func (s *InviteService) PreviewInvite(ctx context.Context, code string) (*models.InvitePreview, error) {
+	if strings.TrimSpace(code) == "" {
+		return nil, repos.ErrInviteNotFound
+	}
+	return s.inviteRepo.GetInvitePreview(ctx, hashInviteCode(code))
+}
+
+// RedeemInvite makes the user a member of the invite's workspace and returns its ID
+func (s *InviteService) RedeemInvite(ctx context.Context, code string, userID string) (string, error) {
+	if strings.TrimSpace(code) == "" {
+		return "", repos.ErrInviteNotFound
+	}
+	return s.inviteRepo.RedeemInvite(ctx, hashInviteCode(code), userID)
+}
+
+// generateInviteCode returns a 12 character code, short enough to type in by hand
+func generateInviteCode() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	return strings.ToLower(encoding.EncodeToString(buf))[:12], nil
+}
+
+// hashInviteCode ignores case and surrounding whitespace so typed codes still match
+func hashInviteCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/realtime"
+	"backend/internal/repos"
+	"backend/pkg/grouproles"
+	"backend/pkg/ldap"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LDAPAuthenticator signs users in against a directory server. Directory users get
+// a local account on their first sign-in, and their directory groups grant workspace
+// roles through the configured mappings. Role changes are published so the user's
+// clients see them without reconnecting.
+type LDAPAuthenticator struct {
+	directory      *ldap.Directory
+	mappings       []grouproles.GroupRole
+	userRepo       *repos.UserRepo
+	groupRoleSync  *GroupRoleSync
+	events         realtime.Publisher
+	securityEvents *SecurityEventService
+}
+
+func NewLDAPAuthenticator(directory *ldap.Directory, mappings []grouproles.GroupRole, userRepo *repos.UserRepo, groupRoleSync *GroupRoleSync, events realtime.Publisher, securityEvents *SecurityEventService) *LDAPAuthenticator {
+	return &LDAPAuthenticator{
+		directory:      directory,
+		mappings:       mappings,
+		userRepo:       userRepo,
+		groupRoleSync:  groupRoleSync,
+		events:         events,
+		securityEvents: securityEvents,
+	}
+}
+
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, username string, password string) (*models.User, error) {
+	account, err := a.directory.Authenticate(ctx, username, password)
+	switch {
+	case errors.Is(err, ldap.ErrAccountNotFound), errors.Is(err, ldap.ErrAmbiguousAccount):
+		return nil, ErrUnknownAccount
+	case errors.Is(err, ldap.ErrInvalidCredentials):
+		return nil, ErrInvalidPassword
+	case err != nil:
+		return nil, fmt.Errorf("failed to authenticate against the directory: %w", err)
+	}
+
+	user, err := a.findOrProvisionUser(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == models.UserStatusActive {
+		PublishGroupRoleChanges(ctx, a.events, user.Id.String(), a.syncGroups(ctx, user, account.Groups))
+	}
+	return user, nil
+}
+
+func (a *LDAPAuthenticator) findOrProvisionUser(ctx context.Context, account *ldap.Account) (*models.User, error) {
+	user, err := a.userRepo.GetUserByUsername(ctx, account.Username)
+	if err == nil {
+		if user.AuthSource != models.AuthSourceLDAP {
+			// Never let the directory take over a local account that happens to share a name
+			fmt.Println("LDAP account conflicts with local user:", account.Username)
+			return nil, ErrUnknownAccount
+		}
+		return user, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if err := ValidateUsername(account.Username); err != nil {
+		fmt.Println("LDAP account has an unusable username:", account.Username)
+		return nil, ErrUnknownAccount
+	}
+
+	// Directory users sign in through the directory only
+	passwordHash, err := unusablePasswordHash()
+	if err != nil {
+		return nil, err
+	}
+	user, err = a.userRepo.CreateUser(ctx, account.Username, passwordHash, models.UserStatusActive, models.AuthSourceLDAP)
+	if errors.Is(err, repos.ErrUsernameTaken) {
+		// A concurrent sign-in provisioned the account first
+		return a.findOrProvisionUser(ctx, account)
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.securityEvents.Record(ctx, models.SecurityEventLDAPProvisioned, user.Username, user.Id.String(), ClientInfo{}, map[string]any{
+		"dn": account.DN,
+	})
+	return user, nil
+}
+
+// syncGroups applies the user's directory groups and returns the changes it made
+func (a *LDAPAuthenticator) syncGroups(ctx context.Context, user *models.User, groups []string) []GroupRoleChange {
+	if len(a.mappings) == 0 {
+		return nil
+	}
+	// A bad mapping should not lock people out, so errors are only logged
+	changes, err := a.groupRoleSync.Sync(ctx, user.Id.String(), a.mappings, groups)
+	if err != nil {
+		fmt.Println("LDAP group role sync error for", user.Username+":", err)
+	}
+	return changes
+}
+
+// SyncAll refreshes the group roles of every directory user, so membership changes
+// in the directory apply without waiting for the user to sign in again. Users no
+// longer in the directory lose their mapped roles.
+func (a *LDAPAuthenticator) SyncAll(ctx context.Context) error {
+	if len(a.mappings) == 0 {
+		return nil
+	}
+	usernames, err := a.userRepo.ListUsernamesBySource(ctx, models.AuthSourceLDAP)
+	if err != nil {
+		return err
+	}
+	for _, username := range usernames {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		user, err := a.userRepo.GetUserByUsername(ctx, username)
+		if err != nil {
+			fmt.Println("LDAP sync error loading", username+":", err)
+			continue
+		}
+		account, err := a.directory.LookupAccount(ctx, username)
+		if errors.Is(err, ldap.ErrAccountNotFound) {
+			PublishGroupRoleChanges(ctx, a.events, user.Id.String(), a.syncGroups(ctx, user, nil))
+			continue
+		}
+		if err != nil {
+			// The directory being unreachable must not strip everyone's roles
+			return fmt.Errorf("failed to look up %q in the directory: %w", username, err)
+		}
+		PublishGroupRoleChanges(ctx, a.events, user.Id.String(), a.syncGroups(ctx, user, account.Groups))
+	}
+	return nil
+}
+
+// Run calls SyncAll every interval until ctx is cancelled
+func (a *LDAPAuthenticator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.SyncAll(ctx); err != nil {
+				fmt.Println("LDAP group sync error:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"backend/pkg/ratelimiter"
+	"context"
+	"fmt"
+	"time"
+)
+
+// LockoutError is returned while too many failed attempts block further tries
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("too many failed attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// checkLockout returns a LockoutError while the key is locked out
+func checkLockout(ctx context.Context, lockout ratelimiter.Lockout, key string) error {
+	retryAfter, err := lockout.Check(ctx, key)
+	if err != nil {
+		return err
+	}
+	if retryAfter > 0 {
+		return &LockoutError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// recordFailedAttempt counts a failure for the key, returning the LockoutError it triggered, if any
+func recordFailedAttempt(ctx context.Context, lockout ratelimiter.Lockout, key string) (*LockoutError, error) {
+	retryAfter, err := lockout.Fail(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if retryAfter > 0 {
+		return &LockoutError{RetryAfter: retryAfter}, nil
+	}
+	return nil, nil
+}
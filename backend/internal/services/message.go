@@ -0,0 +1,139 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repos"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	DefaultMessagePageSize = 50
+	MaxMessagePageSize     = 100
+	MaxMessageLength       = 4000
+)
+
+var (
+	ErrEmptyMessage     = errors.New("message cannot be empty")
+	ErrMessageTooLong   = fmt.Errorf("message must be at most %d characters", MaxMessageLength)
+	ErrChannelNotFound  = errors.New("channel not found")
+	ErrInvalidChannelID = errors.New("invalid channel ID")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+)
+
+type MessageService struct {
+	messageRepo *repos.MessageRepo
+}
+
+func NewMessageService(messageRepo *repos.MessageRepo) *MessageService {
+	return &MessageService{messageRepo: messageRepo}
+}
+
+// PostMessage validates and stores a message in a workspace channel
+func (s *MessageService) PostMessage(ctx context.Context, workspaceID string, channelID int, userID string, message string) (*models.WorkspaceChannelMessage, error) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return nil, ErrEmptyMessage
+	}
+	if utf8.RuneCountInString(message) > MaxMessageLength {
+		return nil, ErrMessageTooLong
+	}
+
+	if err := s.ensureChannel(ctx, workspaceID, channelID); err != nil {
+		return nil, err
+	}
+
+	return s.messageRepo.CreateMessage(ctx, workspaceID, channelID, userID, message)
+}
+
+// GetMessages returns one page of channel history, newest first.
+// cursor is the opaque next_cursor of a previous page, or empty for the latest messages.
+func (s *MessageService) GetMessages(ctx context.Context, workspaceID string, channelID int, cursor string, limit int) (*models.MessagePage, error) {
+	if limit <= 0 {
+		limit = DefaultMessagePageSize
+	}
+	if limit > MaxMessagePageSize {
+		limit = MaxMessagePageSize
+	}
+
+	var before *models.MessageCursor
+	if cursor != "" {
+		decoded, err := DecodeMessageCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		before = decoded
+	}
+
+	if err := s.ensureChannel(ctx, workspaceID, channelID); err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra row to find out whether another page exists
+	messages, err := s.messageRepo.GetChannelMessages(ctx, workspaceID, channelID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.MessagePage{Messages: messages}
+	if len(messages) > limit {
+		page.Messages = messages[:limit]
+		last := page.Messages[limit-1]
+		page.NextCursor = EncodeMessageCursor(models.MessageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	return page, nil
+}
+
+func (s *MessageService) ensureChannel(ctx context.Context, workspaceID string, channelID int) error {
+	if channelID <= 0 {
+		return ErrInvalidChannelID
+	}
+
+	exists, err := s.messageRepo.ChannelExists(ctx, workspaceID, channelID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrChannelNotFound
+	}
+
+	return nil
+}
+
+// EncodeMessageCursor turns a cursor into an opaque URL-safe string
+func EncodeMessageCursor(c models.MessageCursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + strconv.Itoa(c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeMessageCursor parses a cursor produced by EncodeMessageCursor
+func DecodeMessageCursor(cursor string) (*models.MessageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAtPart, idPart, found := strings.Cut(string(raw), "|")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtPart)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	id, err := strconv.Atoi(idPart)
+	if err != nil || id <= 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &models.MessageCursor{CreatedAt: createdAt, ID: id}, nil
+}
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repos"
+	"backend/pkg/ratelimiter"
+	"backend/pkg/totp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	recoveryCodeCount = 10
+	// mfaChallengeTTL is how long a user has to enter their code after the password step
+	mfaChallengeTTL = 5 * time.Minute
+	// maxMFAChallengeAttempts invalidates a challenge after this many wrong codes
+	maxMFAChallengeAttempts = 5
+)
+
+var (
+	ErrMFAAlreadyEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled         = errors.New("two-factor authentication is not enabled")
+	ErrMFAEnrollmentNotFound = errors.New("no two-factor enrollment in progress")
+	ErrMFARequired           = errors.New("two-factor authentication is required on this instance")
+	ErrInvalidMFACode        = errors.New("invalid two-factor code")
+	ErrInvalidMFAChallenge   = errors.New("login challenge is invalid or expired")
+	ErrMFAChallengeNotEnroll = errors.New("login challenge does not allow enrollment")
+)
+
+// MFAChallenge is issued after a correct password when a second step is needed
+type MFAChallenge struct {
+	Token string
+	// EnrollmentRequired is set when the instance requires 2FA and the user has none yet
+	EnrollmentRequired bool
+}
+
+type MFAService struct {
+	mfaRepo         *repos.MFARepo
+	userRepo        *repos.UserRepo
+	settingsService *SettingsService
+	lockout         ratelimiter.Lockout
+	redis           *redis.Client
+	issuer          string
+}
+
+func NewMFAService(mfaRepo *repos.MFARepo, userRepo *repos.UserRepo, settingsService *SettingsService, lockout ratelimiter.Lockout, redis *redis.Client, issuer string) *MFAService {
+	if issuer == "" {
+		issuer = "BarbedWork"
+	}
+	return &MFAService{mfaRepo: mfaRepo, userRepo: userRepo, settingsService: settingsService, lockout: lockout, redis: redis, issuer: issuer}
+}
+
+func (s *MFAService) Status(ctx context.Context, userID string) (*models.MFAStatus, error) {
+	required, err := s.settingsService.RequireMFA(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := &models.MFAStatus{Required: required}
+
+	enabled, err := s.IsEnabled(ctx, userID)
+	if err != nil || !enabled {
+		return status, err
+	}
+	status.Enabled = true
+
+	status.RecoveryCodesRemaining, err = s.mfaRepo.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (s *MFAService) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	enrollment, err := s.mfaRepo.GetTOTP(ctx, userID)
+	if errors.Is(err, repos.ErrTOTPNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return enrollment.Confirmed(), nil
+}
+
+// BeginEnrollment generates a new secret for the user to add to their authenticator app
+func (s *MFAService) BeginEnrollment(ctx context.Context, userID string) (*models.MFAEnrollment, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := s.mfaRepo.SavePendingTOTP(ctx, userID, secret)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	return &models.MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(s.issuer, user.Username, secret),
+	}, nil
+}
+
+// ConfirmEnrollment enables 2FA once the user proves their app produces valid codes.
+// It returns the recovery codes, which are only ever shown this once.
+func (s *MFAService) ConfirmEnrollment(ctx context.Context, userID string, code string) ([]string, error) {
+	enrollment, err := s.mfaRepo.GetTOTP(ctx, userID)
+	if errors.Is(err, repos.ErrTOTPNotFound) {
+		return nil, ErrMFAEnrollmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if enrollment.Confirmed() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(enrollment.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.ConfirmTOTP(ctx, userID, step, hashes); err != nil {
+		if errors.Is(err, repos.ErrTOTPNotFound) {
+			return nil, ErrMFAEnrollmentNotFound
+		}
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify accepts either a current TOTP code or an unused recovery code
+func (s *MFAService) Verify(ctx context.Context, userID string, code string) error {
+	enrollment, err := s.mfaRepo.GetTOTP(ctx, userID)
+	if errors.Is(err, repos.ErrTOTPNotFound) {
+		return ErrMFANotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	if !enrollment.Confirmed() {
+		return ErrMFANotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(enrollment.Secret, code, time.Now())
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		// Each time step is accepted once so an observed code cannot be replayed
+		used, err := s.mfaRepo.UseTOTPStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := s.mfaRepo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// Disable turns 2FA off after checking a current code
+func (s *MFAService) Disable(ctx context.Context, userID string, code string) error {
+	required, err := s.settingsService.RequireMFA(ctx)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrMFARequired
+	}
+
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.mfaRepo.DeleteMFA(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces every recovery code after checking a current code
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID string, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Reset removes a user's second factor without a code, for admins helping locked-out users
+func (s *MFAService) Reset(ctx context.Context, userID string) error {
+	return s.mfaRepo.DeleteMFA(ctx, userID)
+}
+
+// StartLoginChallenge decides whether a user who passed the password check needs a
+// second step. It returns nil when the user can be signed in straight away, and a
+// LockoutError while too many wrong codes block the user's logins.
+func (s *MFAService) StartLoginChallenge(ctx context.Context, userID string) (*MFAChallenge, error) {
+	if err := checkLockout(ctx, s.lockout, mfaLockoutKey(userID)); err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.IsEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	enroll := false
+	if !enabled {
+		required, err := s.settingsService.RequireMFA(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !required {
+			return nil, nil
+		}
+		enroll = true
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	key := mfaChallengeKey(token)
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, key, "user_id", userID, "enroll", enroll, "attempts", 0)
+	pipe.Expire(ctx, key, mfaChallengeTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return &MFAChallenge{Token: token, EnrollmentRequired: enroll}, nil
+}
+
+// ChallengeEnrollment starts enrollment for a user whose login requires it
+func (s *MFAService) ChallengeEnrollment(ctx context.Context, token string) (*models.MFAEnrollment, error) {
+	userID, enroll, err := s.getChallenge(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !enroll {
+		return nil, ErrMFAChallengeNotEnroll
+	}
+	return s.BeginEnrollment(ctx, userID)
+}
+
+// CompleteLoginChallenge checks the code for a challenge and consumes it. It returns the
+// user to sign in, plus recovery codes when the challenge finished a forced enrollment.
+// Wrong codes count towards a lockout on the user as well as the challenge, so starting
+// new challenges does not give an attacker who knows the password more guesses.
+func (s *MFAService) CompleteLoginChallenge(ctx context.Context, token string, code string) (string, []string, error) {
+	userID, enroll, err := s.getChallenge(ctx, token)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := checkLockout(ctx, s.lockout, mfaLockoutKey(userID)); err != nil {
+		return "", nil, err
+	}
+
+	var recoveryCodes []string
+	if enroll {
+		recoveryCodes, err = s.ConfirmEnrollment(ctx, userID, code)
+	} else {
+		err = s.Verify(ctx, userID, code)
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			s.failChallenge(ctx, token)
+			lockoutErr, lockoutFailure := recordFailedAttempt(ctx, s.lockout, mfaLockoutKey(userID))
+			if lockoutFailure != nil {
+				return "", nil, lockoutFailure
+			}
+			if lockoutErr != nil {
+				return "", nil, lockoutErr
+			}
+		}
+		return "", nil, err
+	}
+
+	// Only one request may redeem the challenge
+	deleted, err := s.redis.Del(ctx, mfaChallengeKey(token)).Result()
+	if err != nil {
+		return "", nil, err
+	}
+	if deleted == 0 {
+		return "", nil, ErrInvalidMFAChallenge
+	}
+	if err := s.lockout.Reset(ctx, mfaLockoutKey(userID)); err != nil {
+		return "", nil, err
+	}
+
+	return userID, recoveryCodes, nil
+}
+
+func (s *MFAService) getChallenge(ctx context.Context, token string) (string, bool, error) {
+	if token == "" {
+		return "", false, ErrInvalidMFAChallenge
+	}
+	fields, err := s.redis.HGetAll(ctx, mfaChallengeKey(token)).Result()
+	if err != nil {
+		return "", false, err
+	}
+	if fields["user_id"] == "" {
+		return "", false, ErrInvalidMFAChallenge
+	}
+	return fields["user_id"], fields["enroll"] == "1", nil
+}
+
+// failMFAChallengeScript counts a wrong code against a challenge and deletes it once
+// ARGV[1] attempts are used up. A challenge that has expired or been redeemed is left
+// alone, since incrementing it would recreate the hash without a TTL.
+var failMFAChallengeScript = redis.NewScript(`
+	if redis.call("EXISTS", KEYS[1]) == 0 then
+		return 0
+	end
+	local attempts = redis.call("HINCRBY", KEYS[1], "attempts", 1)
+	if attempts >= tonumber(ARGV[1]) then
+		redis.call("DEL", KEYS[1])
+	end
+	return attempts
+`)
+
+func (s *MFAService) failChallenge(ctx context.Context, token string) {
+	failMFAChallengeScript.Run(ctx, s.redis, []string{mfaChallengeKey(token)}, maxMFAChallengeAttempts)
+}
+
+func mfaChallengeKey(token string) string {
+	return "mfa_challenge:" + token
+}
+
+// mfaLockoutKey is kept apart from the password lockout so a correct password does not
+// reset the count of wrong codes
+func mfaLockoutKey(userID string) string {
+	return "mfa:" + userID
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// generateRecoveryCodes returns codes formatted for the user and their hashes for storage
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(encoding.EncodeToString(buf))[:10]
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode normalises case and separators so codes can be typed loosely
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repos"
+	"backend/pkg/webhook"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	outgoingWebhookSecretPrefix     = "whsec_"
+	maxOutgoingWebhooksPerWorkspace = 20
+	maxOutgoingWebhookURLLength     = 2048
+	maxTriggerWords                 = 20
+	maxTriggerWordLength            = 50
+	DefaultWebhookDeliveryPageSize  = 50
+	MaxWebhookDeliveryPageSize      = 100
+	// webhookDeliveryLease outlasts any attempt, so claimed deliveries are not sent twice
+	webhookDeliveryLease = 5 * time.Minute
+	// webhookDeliveryRetention is how long finished deliveries stay in the history
+	webhookDeliveryRetention = 30 * 24 * time.Hour
+)
+
+// OutgoingWebhookEvents are the event types webhooks can subscribe to
+var OutgoingWebhookEvents = []string{models.EventMessageCreated, models.EventMemberAdded}
+
+var (
+	ErrInvalidWebhookURL       = errors.New("webhook URL must be an http or https URL")
+	ErrInvalidWebhookEvents    = fmt.Errorf("webhook events must be one or more of %s", strings.Join(OutgoingWebhookEvents, ", "))
+	ErrInvalidTriggerWords     = fmt.Errorf("at most %d trigger words of up to %d characters are allowed", maxTriggerWords, maxTriggerWordLength)
+	ErrMessageFilterNeedsEvent = fmt.Errorf("a channel or trigger words need the %s event", models.EventMessageCreated)
+	ErrTooManyOutgoingWebhooks = errors.New("too many outgoing webhooks in this workspace; delete one before creating another")
+	ErrInvalidDeliveryStatus   = errors.New("delivery status must be pending, delivered or dead")
+)
+
+type OutgoingWebhookService struct {
+	webhookRepo *repos.OutgoingWebhookRepo
+	messageRepo *repos.MessageRepo
+	worker      *webhook.Worker
+}
+
+// NewOutgoingWebhookService sends deliveries with sender, retrying them as policy says
+func NewOutgoingWebhookService(webhookRepo *repos.OutgoingWebhookRepo, messageRepo *repos.MessageRepo, sender *webhook.Sender, policy webhook.RetryPolicy) *OutgoingWebhookService {
+	s := &OutgoingWebhookService{webhookRepo: webhookRepo, messageRepo: messageRepo}
+	s.worker = webhook.NewWorker(deliveryQueue{webhookRepo: webhookRepo}, sender, policy)
+	return s
+}
+
+// CreateWebhook subscribes a URL to the workspace's events and returns the webhook
+// with the secret its deliveries are signed with, which cannot be retrieved again
+func (s *OutgoingWebhookService) CreateWebhook(ctx context.Context, workspaceID string, creatorID string, creatorIsAdmin bool, req models.CreateOutgoingWebhookRequest) (*models.OutgoingWebhook, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxWebhookNameLength {
+		return nil, ErrInvalidWebhookName
+	}
+
+	target := strings.TrimSpace(req.URL)
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(target) > maxOutgoingWebhookURLLength {
+		return nil, ErrInvalidWebhookURL
+	}
+
+	events := []string{}
+	for _, event := range req.Events {
+		if !slices.Contains(OutgoingWebhookEvents, event) {
+			return nil, ErrInvalidWebhookEvents
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return nil, ErrInvalidWebhookEvents
+	}
+
+	triggerWords := []string{}
+	for _, word := range req.TriggerWords {
+		word = strings.TrimSpace(word)
+		if word == "" || strings.ContainsFunc(word, unicode.IsSpace) ||
+			utf8.RuneCountInString(word) > maxTriggerWordLength {
+			return nil, ErrInvalidTriggerWords
+		}
+		if !slices.ContainsFunc(triggerWords, func(existing string) bool { return strings.EqualFold(existing, word) }) {
+			triggerWords = append(triggerWords, word)
+		}
+	}
+	if len(triggerWords) > maxTriggerWords {
+		return nil, ErrInvalidTriggerWords
+	}
+
+	if (req.ChannelID != nil || len(triggerWords) > 0) && !slices.Contains(events, models.EventMessageCreated) {
+		return nil, ErrMessageFilterNeedsEvent
+	}
+	if req.ChannelID != nil {
+		exists, err := s.messageRepo.ChannelExists(ctx, workspaceID, *req.ChannelID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrChannelNotFound
+		}
+	}
+
+	count, err := s.webhookRepo.CountWebhooks(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxOutgoingWebhooksPerWorkspace {
+		return nil, ErrTooManyOutgoingWebhooks
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	secret = outgoingWebhookSecretPrefix + secret
+
+	createdBy, createdByAdmin := creatorIDs(creatorID, creatorIsAdmin)
+	created, err := s.webhookRepo.CreateWebhook(ctx, workspaceID, name, target, secret, events, req.ChannelID, triggerWords, createdBy, createdByAdmin)
+	if err != nil {
+		return nil, err
+	}
+	created.Secret = secret
+	return created, nil
+}
+
+func (s *OutgoingWebhookService) ListWebhooks(ctx context.Context, workspaceID string) ([]*models.OutgoingWebhook, error) {
+	return s.webhookRepo.ListWebhooks(ctx, workspaceID)
+}
+
+// DeleteWebhook unsubscribes the webhook, dropping its pending deliveries and history
+func (s *OutgoingWebhookService) DeleteWebhook(ctx context.Context, workspaceID string, webhookID string) error {
+	return s.webhookRepo.DeleteWebhook(ctx, workspaceID, webhookID)
+}
+
+// ListDeliveries returns the webhook's delivery history, newest first. Filtering by
+// the dead status lists its dead-letter log.
+func (s *OutgoingWebhookService) ListDeliveries(ctx context.Context, workspaceID string, webhookID string, status string, limit int) ([]*models.OutgoingWebhookDelivery, error) {
+	switch status {
+	case "", models.DeliveryStatusPending, models.DeliveryStatusDelivered, models.DeliveryStatusDead:
+	default:
+		return nil, ErrInvalidDeliveryStatus
+	}
+	if limit <= 0 {
+		limit = DefaultWebhookDeliveryPageSize
+	}
+	if limit > MaxWebhookDeliveryPageSize {
+		limit = MaxWebhookDeliveryPageSize
+	}
+
+	if _, err := s.webhookRepo.GetWebhook(ctx, workspaceID, webhookID); err != nil {
+		return nil, err
+	}
+	return s.webhookRepo.ListDeliveries(ctx, webhookID, status, limit)
+}
+
+// Redeliver sends a delivered or dead-lettered delivery again
+func (s *OutgoingWebhookService) Redeliver(ctx context.Context, workspaceID string, webhookID string, deliveryID string) error {
+	if _, err := s.webhookRepo.GetWebhook(ctx, workspaceID, webhookID); err != nil {
+		return err
+	}
+	return s.webhookRepo.Redeliver(ctx, webhookID, deliveryID)
+}
+
+// Publish queues a delivery of the event for every webhook whose filters it matches.
+// Messages from bots and incoming webhooks are skipped so integrations cannot set off
+// each other in a loop. It implements realtime.Publisher.
+func (s *OutgoingWebhookService) Publish(ctx context.Context, event models.Event) {
+	if !slices.Contains(OutgoingWebhookEvents, event.Type) {
+		return
+	}
+
+	var message models.WorkspaceChannelMessage
+	if event.Type == models.EventMessageCreated {
+		if err := json.Unmarshal(event.Payload, &message); err != nil {
+			fmt.Println("Failed to decode message for outgoing webhooks:", err)
+			return
+		}
+		if message.IsBot {
+			return
+		}
+	}
+
+	webhooks, err := s.webhookRepo.ListWebhooksForEvent(ctx, event.WorkspaceID, event.Type)
+	if err != nil {
+		fmt.Println("Failed to find outgoing webhooks:", err)
+		return
+	}
+
+	for _, subscriber := range webhooks {
+		payload := models.OutgoingWebhookPayload{
+			Type:        event.Type,
+			WorkspaceID: event.WorkspaceID,
+			WebhookID:   subscriber.ID.String(),
+			Data:        event.Payload,
+			CreatedAt:   event.CreatedAt,
+		}
+		if event.Type == models.EventMessageCreated {
+			if subscriber.ChannelID.Valid && int(subscriber.ChannelID.Int32) != message.ChannelID {
+				continue
+			}
+			if len(subscriber.TriggerWords) > 0 {
+				word, ok := webhook.MatchTriggerWord(message.Message, subscriber.TriggerWords)
+				if !ok {
+					continue
+				}
+				payload.TriggerWord = word
+			}
+		}
+
+		body, err := json.Marshal(payload)
+		if err != nil {
+			fmt.Println("Failed to encode outgoing webhook payload:", err)
+			continue
+		}
+		if err := s.webhookRepo.EnqueueDelivery(ctx, subscriber.ID.String(), event.Type, body); err != nil {
+			fmt.Println("Failed to queue outgoing webhook delivery:", err)
+		}
+	}
+}
+
+// Run sends queued deliveries every interval and prunes old history hourly until ctx
+// is cancelled. Every replica can run it; deliveries are claimed by one at a time.
+func (s *OutgoingWebhookService) Run(ctx context.Context, interval time.Duration) {
+	go s.worker.Run(ctx, interval)
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.webhookRepo.PruneDeliveries(ctx, webhookDeliveryRetention); err != nil {
+				fmt.Println("Webhook delivery pruning error:", err)
+			}
+		}
+	}
+}
+
+// deliveryQueue feeds the worker from the deliveries table
+type deliveryQueue struct {
+	webhookRepo *repos.OutgoingWebhookRepo
+}
+
+func (q deliveryQueue) Claim(ctx context.Context, limit int) ([]webhook.Delivery, error) {
+	claimed, err := q.webhookRepo.ClaimDeliveries(ctx, limit, webhookDeliveryLease)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]webhook.Delivery, 0, len(claimed))
+	for _, d := range claimed {
+		deliveries = append(deliveries, webhook.Delivery{
+			ID:        d.ID,
+			WebhookID: d.WebhookID,
+			Event:     d.EventType,
+			URL:       d.URL,
+			Secret:    d.Secret,
+			Payload:   d.Payload,
+			Attempt:   d.Attempts,
+		})
+	}
+	return deliveries, nil
+}
+
+func (q deliveryQueue) Record(ctx context.Context, delivery webhook.Delivery, outcome webhook.Outcome) error {
+	status := models.DeliveryStatusPending
+	switch {
+	case outcome.Delivered:
+		status = models.DeliveryStatusDelivered
+	case outcome.Dead:
+		status = models.DeliveryStatusDead
+	}
+	return q.webhookRepo.RecordAttempt(ctx, delivery.ID, status, outcome.StatusCode, outcome.Error, outcome.RetryIn)
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repos"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// passwordResetTTL is how long an admin-issued reset link stays valid. Links are
+// handed over out of band, so this leaves time for the user to see it.
+const passwordResetTTL = 24 * time.Hour
+
+var (
+	ErrInvalidResetToken = errors.New("password reset link is invalid or expired")
+)
+
+// consumeResetTokenScript deletes the reset token in KEYS[1] and, if KEYS[2] still
+// points at it, the user's pointer too. It returns 0 when another request redeemed the
+// token first.
+var consumeResetTokenScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('DEL', KEYS[1])
+if redis.call('GET', KEYS[2]) == ARGV[1] then
+	redis.call('DEL', KEYS[2])
+end
+return 1
+`)
+
+type PasswordResetService struct {
+	userService    *UserService
+	redis          *redis.Client
+	securityEvents *SecurityEventService
+}
+
+func NewPasswordResetService(userService *UserService, redis *redis.Client, securityEvents *SecurityEventService) *PasswordResetService {
+	return &PasswordResetService{userService: userService, redis: redis, securityEvents: securityEvents}
+}
+
+// IssueToken creates a single-use reset token for the user, replacing any earlier one.
+// Only a hash of the token is stored, so it is shown to the admin exactly once.
+func (s *PasswordResetService) IssueToken(ctx context.Context, userID string, adminID string, client ClientInfo) (*models.PasswordResetToken, error) {
+	user, err := s.userService.GetUser(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repos.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.AuthSource != models.AuthSourceLocal {
+		return nil, ErrExternalAccount
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	tokenHash := hashResetToken(token)
+
+	userKey := passwordResetUserKey(userID)
+	previous, err := s.redis.Get(ctx, userKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	pipe := s.redis.TxPipeline()
+	if previous != "" {
+		pipe.Del(ctx, passwordResetKey(previous))
+	}
+	pipe.Set(ctx, passwordResetKey(tokenHash), userID, passwordResetTTL)
+	pipe.Set(ctx, userKey, tokenHash, passwordResetTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	s.securityEvents.Record(ctx, models.SecurityEventPasswordResetSent, user.Username, userID, client, map[string]any{
+		"admin_id": adminID,
+	})
+	return &models.PasswordResetToken{Token: token, ExpiresAt: time.Now().Add(passwordResetTTL)}, nil
+}
+
+// Redeem sets a new password with a reset token and returns the user it belonged to.
+// A password rejected by the policy leaves the token usable so the user can try again.
+func (s *PasswordResetService) Redeem(ctx context.Context, token string, password string, client ClientInfo) (string, error) {
+	if token == "" {
+		return "", ErrInvalidResetToken
+	}
+	tokenHash := hashResetToken(token)
+
+	userID, err := s.redis.Get(ctx, passwordResetKey(tokenHash)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrInvalidResetToken
+	}
+	if err != nil {
+		return "", err
+	}
+
+	user, err := s.userService.GetUser(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrInvalidResetToken
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := s.userService.passwordPolicy.Validate(password, user.Username); err != nil {
+		return "", err
+	}
+
+	consumed, err := consumeResetTokenScript.Run(ctx, s.redis,
+		[]string{passwordResetKey(tokenHash), passwordResetUserKey(userID)}, tokenHash).Int()
+	if err != nil {
+		return "", err
+	}
+	if consumed == 0 {
+		return "", ErrInvalidResetToken
+	}
+
+	if _, err := s.userService.SetPassword(ctx, userID, password); err != nil {
+		return "", err
+	}
+	s.securityEvents.Record(ctx, models.SecurityEventPasswordReset, user.Username, userID, client, nil)
+	return userID, nil
+}
+
+func passwordResetKey(tokenHash string) string {
+	return "password_reset:" + tokenHash
+}
+
+func passwordResetUserKey(userID string) string {
+	return "password_reset_user:" + userID
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
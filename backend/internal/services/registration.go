@@ -0,0 +1,123 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repos"
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrRegistrationClosed = errors.New("registration is closed on this instance")
+	ErrInviteRequired     = errors.New("an invite code is required to register")
+)
+
+type RegistrationService struct {
+	userService     *UserService
+	userRepo        *repos.UserRepo
+	settingsService *SettingsService
+	inviteService   *InviteService
+	securityEvents  *SecurityEventService
+}
+
+func NewRegistrationService(userService *UserService, userRepo *repos.UserRepo, settingsService *SettingsService, inviteService *InviteService, securityEvents *SecurityEventService) *RegistrationService {
+	return &RegistrationService{
+		userService:     userService,
+		userRepo:        userRepo,
+		settingsService: settingsService,
+		inviteService:   inviteService,
+		securityEvents:  securityEvents,
+	}
+}
+
+// Info tells the sign-up page whether and how users may register
+func (s *RegistrationService) Info(ctx context.Context) (*models.RegistrationInfo, error) {
+	mode, err := s.settingsService.RegistrationMode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	requireApproval, err := s.settingsService.RequireSignupApproval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &models.RegistrationInfo{Mode: mode, RequireApproval: requireApproval}, nil
+}
+
+// Register creates an account under the instance's registration mode, joining the
+// workspace of the invite code when one is given. It returns the new user and the
+// workspace joined, if any. Accounts wait in pending when approval is required.
+func (s *RegistrationService) Register(ctx context.Context, req models.RegisterRequest, client ClientInfo) (*models.User, string, error) {
+	info, err := s.Info(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	switch info.Mode {
+	case models.RegistrationAdminOnly:
+		return nil, "", ErrRegistrationClosed
+	case models.RegistrationInviteOnly:
+		if req.InviteCode == "" {
+			return nil, "", ErrInviteRequired
+		}
+	}
+
+	// Check the invite first so a bad code does not leave an account behind
+	if req.InviteCode != "" {
+		if _, err := s.inviteService.PreviewInvite(ctx, req.InviteCode); err != nil {
+			return nil, "", err
+		}
+	}
+
+	status := models.UserStatusActive
+	if info.RequireApproval {
+		status = models.UserStatusPending
+	}
+	user, err := s.userService.createUser(ctx, req.Username, req.Password, status)
+	if err != nil {
+		return nil, "", err
+	}
+	userID := user.Id.String()
+
+	workspaceID := ""
+	if req.InviteCode != "" {
+		workspaceID, err = s.inviteService.RedeemInvite(ctx, req.InviteCode, userID)
+		if err != nil {
+			// The invite was used up or revoked after the check above
+			if deleteErr := s.userRepo.DeleteUser(ctx, userID); deleteErr != nil {
+				return nil, "", fmt.Errorf("failed to remove user after invite error %v: %w", err, deleteErr)
+			}
+			return nil, "", err
+		}
+	}
+
+	s.securityEvents.Record(ctx, models.SecurityEventUserRegistered, user.Username, userID, client, map[string]any{
+		"status":       status,
+		"workspace_id": workspaceID,
+	})
+	return user, workspaceID, nil
+}
+
+func (s *RegistrationService) ListPending(ctx context.Context) ([]*models.User, error) {
+	return s.userRepo.ListPendingUsers(ctx)
+}
+
+// Decide approves or rejects a pending registration. Deciding a user who is not
+// pending returns repos.ErrUserNotFound.
+func (s *RegistrationService) Decide(ctx context.Context, userID string, approve bool, adminID string, client ClientInfo) (*models.User, error) {
+	status, eventType := models.UserStatusRejected, models.SecurityEventRegistrationRejected
+	if approve {
+		status, eventType = models.UserStatusActive, models.SecurityEventRegistrationApproved
+	}
+	if err := s.userRepo.DecidePendingUser(ctx, userID, status); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.securityEvents.Record(ctx, eventType, user.Username, userID, client, map[string]any{
+		"admin_id": adminID,
+	})
+	return user, nil
+}
@@ -15,17 +15,20 @@ var (
 	ErrInvalidUserID    = errors.New("invalid user ID")
 	ErrInvalidRoleID    = errors.New("invalid role ID")
 	ErrRoleAssignmentExists = errors.New("role assignment already exists")
+	ErrBotOutsideWorkspace = errors.New("bots can only hold roles in their own workspace")
 )
 
 type RoleService struct {
 	roleRepo *repos.RoleRepo
 	userRepo *repos.UserRepo
+	botRepo  *repos.BotRepo
 }
 
-func NewRoleService(roleRepo *repos.RoleRepo, userRepo *repos.UserRepo) *RoleService {
+func NewRoleService(roleRepo *repos.RoleRepo, userRepo *repos.UserRepo, botRepo *repos.BotRepo) *RoleService {
 	return &RoleService{
 		roleRepo: roleRepo,
 		userRepo: userRepo,
+		botRepo:  botRepo,
 	}
 }
 
@@ -170,11 +173,21 @@ func (s *RoleService) AssignRoleToUser(ctx context.Context, workspaceID, userID
 	}
 
 	// Verify user exists
-	_, err := s.userRepo.GetUserByID(ctx, userID)
+	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
+	if user.IsBot {
+		botWorkspaceID, err := s.botRepo.GetBotWorkspaceID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if botWorkspaceID != workspaceID {
+			return nil, ErrBotOutsideWorkspace
+		}
+	}
+
 	// Verify role exists
 	_, err = s.roleRepo.GetRoleByID(ctx, roleID)
 	if err != nil {
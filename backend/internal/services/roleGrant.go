@@ -0,0 +1,52 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"slices"
+)
+
+// ErrRoleNotGrantable is returned when a member hands out a role with more access than their own
+var ErrRoleNotGrantable = errors.New("cannot grant a role that has permissions you do not hold")
+
+// roleLookup is the part of RoleService needed to resolve the roles being granted
+type roleLookup interface {
+	GetRoleByID(ctx context.Context, roleID int) (*models.Role, error)
+}
+
+// permissionLookup is the part of UserRepo needed to check what a member may grant
+type permissionLookup interface {
+	GetUserPermissions(ctx context.Context, userID string, workspaceID string) ([]string, error)
+}
+
+// checkGrantableRoles returns ErrRoleNotGrantable unless the granter holds every
+// permission of the roles in the workspace, so handing out roles cannot be used to
+// escalate privileges. Instance admins may grant any role.
+func checkGrantableRoles(ctx context.Context, permissions permissionLookup, workspaceID string, granterID string, granterIsAdmin bool, roles ...*models.Role) error {
+	if granterIsAdmin {
+		return nil
+	}
+
+	held, err := permissions.GetUserPermissions(ctx, granterID, workspaceID)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		for _, permission := range role.Permissions {
+			if !slices.Contains(held, permission.Name) {
+				return ErrRoleNotGrantable
+			}
+		}
+	}
+	return nil
+}
+
+// creatorIDs splits the actor behind a new record into its created_by and
+// created_by_admin columns, exactly one of which is set
+func creatorIDs(actorID string, actorIsAdmin bool) (createdBy string, createdByAdmin string) {
+	if actorIsAdmin {
+		return "", actorID
+	}
+	return actorID, ""
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+type heldPermissions map[string][]string
+
+func (h heldPermissions) GetUserPermissions(ctx context.Context, userID string, workspaceID string) ([]string, error) {
+	return h[userID+"/"+workspaceID], nil
+}
+
+type rolesByID map[int]*models.Role
+
+func (r rolesByID) GetRoleByID(ctx context.Context, roleID int) (*models.Role, error) {
+	role, ok := r[roleID]
+	if !ok {
+		return nil, ErrRoleNotFound
+	}
+	return role, nil
+}
+
+var (
+	memberRole = &models.Role{ID: 1, Name: "Member", Permissions: []models.Permission{
+		{Name: "channel:read"}, {Name: "message:send"},
+	}}
+	adminRole = &models.Role{ID: 2, Name: "Admin", Permissions: []models.Permission{
+		{Name: "channel:read"}, {Name: "workspace:manage-workspace"},
+	}}
+	granterPermissions = heldPermissions{
+		"alice/ws1": {"channel:read", "message:send", "workspace:manage-invites"},
+	}
+)
+
+func TestCheckGrantableRoles(t *testing.T) {
+	tests := []struct {
+		name    string
+		granter string
+		isAdmin bool
+		roles   []*models.Role
+		want    error
+	}{
+		{"no roles", "alice", false, nil, nil},
+		{"role within held permissions", "alice", false, []*models.Role{memberRole}, nil},
+		{"role with a permission the granter lacks", "alice", false, []*models.Role{adminRole}, ErrRoleNotGrantable},
+		{"one disallowed role among allowed ones", "alice", false, []*models.Role{memberRole, adminRole}, ErrRoleNotGrantable},
+		{"member of another workspace only", "bob", false, []*models.Role{memberRole}, ErrRoleNotGrantable},
+		{"instance admin", "root", true, []*models.Role{adminRole}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkGrantableRoles(context.Background(), granterPermissions, "ws1", tt.granter, tt.isAdmin, tt.roles...)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("checkGrantableRoles() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateInviteRejectsRolesTheInviterLacks(t *testing.T) {
+	service := &InviteService{
+		roleService: rolesByID{memberRole.ID: memberRole, adminRole.ID: adminRole},
+		userRepo:    granterPermissions,
+	}
+
+	_, err := service.CreateInvite(context.Background(), "ws1", "alice", false, models.CreateInviteRequest{RoleID: adminRole.ID})
+	if !errors.Is(err, ErrRoleNotGrantable) {
+		t.Fatalf("CreateInvite() = %v, want %v", err, ErrRoleNotGrantable)
+	}
+}
+
+func TestCreatorIDs(t *testing.T) {
+	if createdBy, createdByAdmin := creatorIDs("alice", false); createdBy != "alice" || createdByAdmin != "" {
+		t.Errorf("creatorIDs(member) = %q, %q", createdBy, createdByAdmin)
+	}
+	if createdBy, createdByAdmin := creatorIDs("root", true); createdBy != "" || createdByAdmin != "root" {
+		t.Errorf("creatorIDs(admin) = %q, %q", createdBy, createdByAdmin)
+	}
+}
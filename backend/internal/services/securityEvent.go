@@ -0,0 +1,75 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repos"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	defaultSecurityEventLimit = 50
+	maxSecurityEventLimit     = 200
+)
+
+// ClientInfo identifies where an authentication attempt came from
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+type SecurityEventService struct {
+	securityEventRepo *repos.SecurityEventRepo
+}
+
+func NewSecurityEventService(securityEventRepo *repos.SecurityEventRepo) *SecurityEventService {
+	return &SecurityEventService{securityEventRepo: securityEventRepo}
+}
+
+// Record stores a security event. Failures are logged rather than returned so that
+// auditing problems never block a login.
+func (s *SecurityEventService) Record(ctx context.Context, eventType string, username string, userID string, client ClientInfo, details map[string]any) {
+	event := &models.SecurityEvent{
+		Type:      eventType,
+		IP:        client.IP,
+		UserAgent: truncateRunes(client.UserAgent, 1024),
+		Details:   json.RawMessage(`{}`),
+	}
+	if username != "" {
+		event.Username.String, event.Username.Valid = truncateRunes(username, 255), true
+	}
+	if userID != "" {
+		if err := event.UserID.Scan(userID); err != nil {
+			fmt.Println("Invalid security event user ID:", err)
+		}
+	}
+	if len(details) > 0 {
+		if encoded, err := json.Marshal(details); err == nil {
+			event.Details = encoded
+		}
+	}
+
+	if err := s.securityEventRepo.CreateSecurityEvent(ctx, event); err != nil {
+		fmt.Println("Failed to record security event:", err)
+	}
+}
+
+func (s *SecurityEventService) List(ctx context.Context, filter models.SecurityEventFilter) ([]*models.SecurityEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultSecurityEventLimit
+	}
+	if filter.Limit > maxSecurityEventLimit {
+		filter.Limit = maxSecurityEventLimit
+	}
+	return s.securityEventRepo.ListSecurityEvents(ctx, filter)
+}
+
+// truncateRunes shortens attacker-controlled input without splitting a UTF-8 sequence
+func truncateRunes(value string, max int) string {
+	runes := []rune(value)
+	if len(runes) <= max {
+		return value
+	}
+	return string(runes[:max])
+}
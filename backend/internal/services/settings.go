@@ -0,0 +1,109 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repos"
+	"context"
+	"errors"
+	"strconv"
+)
+
+const (
+	SettingRequireMFA            = "require_mfa"
+	SettingRegistrationMode      = "registration_mode"
+	SettingRequireSignupApproval = "require_signup_approval"
+)
+
+var (
+	ErrInvalidRegistrationMode = errors.New("registration mode must be open, invite_only or admin_only")
+)
+
+type SettingsService struct {
+	settingsRepo *repos.SettingsRepo
+}
+
+func NewSettingsService(settingsRepo *repos.SettingsRepo) *SettingsService {
+	return &SettingsService{settingsRepo: settingsRepo}
+}
+
+func (s *SettingsService) GetSettings(ctx context.Context) (*models.InstanceSettings, error) {
+	requireMFA, err := s.RequireMFA(ctx)
+	if err != nil {
+		return nil, err
+	}
+	registrationMode, err := s.RegistrationMode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	requireSignupApproval, err := s.RequireSignupApproval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &models.InstanceSettings{
+		RequireMFA:            requireMFA,
+		RegistrationMode:      registrationMode,
+		RequireSignupApproval: requireSignupApproval,
+	}, nil
+}
+
+// UpdateSettings returns ErrInvalidRegistrationMode, before changing anything, for unknown modes
+func (s *SettingsService) UpdateSettings(ctx context.Context, req models.UpdateInstanceSettingsRequest) (*models.InstanceSettings, error) {
+	if req.RegistrationMode != nil && !validRegistrationMode(*req.RegistrationMode) {
+		return nil, ErrInvalidRegistrationMode
+	}
+	if req.RequireMFA != nil {
+		if err := s.settingsRepo.SetSetting(ctx, SettingRequireMFA, strconv.FormatBool(*req.RequireMFA)); err != nil {
+			return nil, err
+		}
+	}
+	if req.RegistrationMode != nil {
+		if err := s.settingsRepo.SetSetting(ctx, SettingRegistrationMode, *req.RegistrationMode); err != nil {
+			return nil, err
+		}
+	}
+	if req.RequireSignupApproval != nil {
+		if err := s.settingsRepo.SetSetting(ctx, SettingRequireSignupApproval, strconv.FormatBool(*req.RequireSignupApproval)); err != nil {
+			return nil, err
+		}
+	}
+	return s.GetSettings(ctx)
+}
+
+// RequireMFA reports whether every user must enroll a second factor
+func (s *SettingsService) RequireMFA(ctx context.Context) (bool, error) {
+	return s.getBool(ctx, SettingRequireMFA, false)
+}
+
+// RegistrationMode reports who may sign up. Instances default to admin-created accounts only.
+func (s *SettingsService) RegistrationMode(ctx context.Context) (string, error) {
+	value, ok, err := s.settingsRepo.GetSetting(ctx, SettingRegistrationMode)
+	if err != nil || !ok || !validRegistrationMode(value) {
+		return models.RegistrationAdminOnly, err
+	}
+	return value, nil
+}
+
+// RequireSignupApproval reports whether self-registered accounts wait for an admin
+func (s *SettingsService) RequireSignupApproval(ctx context.Context) (bool, error) {
+	return s.getBool(ctx, SettingRequireSignupApproval, false)
+}
+
+func validRegistrationMode(mode string) bool {
+	switch mode {
+	case models.RegistrationOpen, models.RegistrationInviteOnly, models.RegistrationAdminOnly:
+		return true
+	}
+	return false
+}
+
+func (s *SettingsService) getBool(ctx context.Context, key string, fallback bool) (bool, error) {
+	value, ok, err := s.settingsRepo.GetSetting(ctx, key)
+	if err != nil || !ok {
+		return fallback, err
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fallback, nil
+	}
+	return parsed, nil
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repos"
+	"backend/pkg/grouproles"
+	"backend/pkg/oidc"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SSOStateTTL bounds how long a user may take to sign in at the provider
+const SSOStateTTL = 10 * time.Minute
+
+var (
+	ErrSSOState         = errors.New("single sign-on request is invalid or expired")
+	ErrSSONotLinked     = errors.New("no account is linked to this sign-in and automatic provisioning is disabled")
+	ErrSSOAlreadyLinked = errors.New("this sign-in is already linked to another account")
+)
+
+// ssoState is kept in Redis between the redirect to the provider and the callback
+type ssoState struct {
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+	// LinkUserID is set when a signed-in user is linking the provider account
+	// rather than signing in with it
+	LinkUserID string `json:"link_user_id,omitempty"`
+}
+
+// SSOResult describes a completed single sign-on callback
+type SSOResult struct {
+	UserID   string
+	Username string
+	// Linked is true when the callback linked an account instead of signing in
+	Linked  bool
+	Changes []GroupRoleChange
+}
+
+type SSOService struct {
+	provider       *oidc.Provider
+	name           string
+	mappings       []grouproles.GroupRole
+	autoProvision  bool
+	identityRepo   *repos.IdentityRepo
+	userRepo       *repos.UserRepo
+	groupRoleSync  *GroupRoleSync
+	redis          *redis.Client
+	securityEvents *SecurityEventService
+}
+
+// NewSSOService signs users in through provider. Provider groups listed in mappings
+// grant workspace roles, and users without a linked account are created on their
+// first sign-in when autoProvision is set.
+func NewSSOService(provider *oidc.Provider, name string, mappings []grouproles.GroupRole, autoProvision bool, identityRepo *repos.IdentityRepo, userRepo *repos.UserRepo, groupRoleSync *GroupRoleSync, redis *redis.Client, securityEvents *SecurityEventService) *SSOService {
+	return &SSOService{
+		provider:       provider,
+		name:           name,
+		mappings:       mappings,
+		autoProvision:  autoProvision,
+		identityRepo:   identityRepo,
+		userRepo:       userRepo,
+		groupRoleSync:  groupRoleSync,
+		redis:          redis,
+		securityEvents: securityEvents,
+	}
+}
+
+// Config tells the login page how to label the single sign-on button
+func (s *SSOService) Config() models.SSOConfig {
+	return models.SSOConfig{Enabled: true, Name: s.name}
+}
+
+// StartLogin returns the provider URL to send the browser to, and the state the
+// callback will carry so the caller can bind it to the browser. When linkUserID is
+// set, the callback links the provider account to that user instead of signing in.
+func (s *SSOService) StartLogin(ctx context.Context, linkUserID string) (string, string, error) {
+	req, err := oidc.NewAuthRequest()
+	if err != nil {
+		return "", "", err
+	}
+	authURL, err := s.provider.AuthCodeURL(ctx, req)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := json.Marshal(ssoState{CodeVerifier: req.CodeVerifier, Nonce: req.Nonce, LinkUserID: linkUserID})
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.redis.Set(ctx, ssoStateKey(req.State), state, SSOStateTTL).Err(); err != nil {
+		return "", "", err
+	}
+	return authURL, req.State, nil
+}
+
+// Complete redeems the authorization code from the provider's callback. It finds the
+// user linked to the provider account, provisioning one if allowed, and syncs their
+// workspace roles from the groups in the ID token.
+func (s *SSOService) Complete(ctx context.Context, stateParam string, code string, client ClientInfo) (*SSOResult, error) {
+	if stateParam == "" || code == "" {
+		return nil, ErrSSOState
+	}
+	// Each state is redeemable once, so a leaked callback URL cannot be replayed
+	raw, err := s.redis.GetDel(ctx, ssoStateKey(stateParam)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrSSOState
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state ssoState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, ErrSSOState
+	}
+
+	token, err := s.provider.Exchange(ctx, code, &oidc.AuthRequest{
+		State:        stateParam,
+		Nonce:        state.Nonce,
+		CodeVerifier: state.CodeVerifier,
+	})
+	if err != nil {
+		s.securityEvents.Record(ctx, models.SecurityEventSSOFailed, "", state.LinkUserID, client, map[string]any{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	if state.LinkUserID != "" {
+		return s.link(ctx, token, state.LinkUserID, client)
+	}
+
+	user, err := s.findOrProvisionUser(ctx, token, client)
+	if err != nil {
+		return nil, err
+	}
+	switch user.Status {
+	case models.UserStatusPending:
+		return nil, ErrAccountPending
+	case models.UserStatusRejected:
+		return nil, ErrAccountRejected
+	}
+
+	userID := user.Id.String()
+	changes, err := s.groupRoleSync.Sync(ctx, userID, s.mappings, token.Groups)
+	if err != nil {
+		// A bad mapping should not lock people out, so the sign-in still succeeds
+		fmt.Println("SSO group role sync error:", err)
+	}
+
+	s.securityEvents.Record(ctx, models.SecurityEventSSOLogin, user.Username, userID, client, map[string]any{
+		"issuer":  token.Issuer,
+		"subject": token.Subject,
+	})
+	return &SSOResult{UserID: userID, Username: user.Username, Changes: changes}, nil
+}
+
+func (s *SSOService) link(ctx context.Context, token *oidc.IDToken, userID string, client ClientInfo) (*SSOResult, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.identityRepo.LinkIdentity(ctx, token.Issuer, token.Subject, userID)
+	if errors.Is(err, repos.ErrIdentityLinked) {
+		// Linking the same account twice is harmless
+		linkedID, lookupErr := s.identityRepo.GetUserIDByIdentity(ctx, token.Issuer, token.Subject)
+		if lookupErr != nil || linkedID != userID {
+			return nil, ErrSSOAlreadyLinked
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	s.securityEvents.Record(ctx, models.SecurityEventSSOLinked, user.Username, userID, client, map[string]any{
+		"issuer":  token.Issuer,
+		"subject": token.Subject,
+	})
+	return &SSOResult{UserID: userID, Username: user.Username, Linked: true}, nil
+}
+
+func (s *SSOService) findOrProvisionUser(ctx context.Context, token *oidc.IDToken, client ClientInfo) (*models.User, error) {
+	userID, err := s.identityRepo.GetUserIDByIdentity(ctx, token.Issuer, token.Subject)
+	if err == nil {
+		return s.userRepo.GetUserByID(ctx, userID)
+	}
+	if !errors.Is(err, repos.ErrIdentityNotFound) {
+		return nil, err
+	}
+	if !s.autoProvision {
+		return nil, ErrSSONotLinked
+	}
+
+	// Provisioned users sign in through the provider only
+	passwordHash, err := unusablePasswordHash()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, username := range ssoUsernameCandidates(token) {
+		user, err := s.identityRepo.CreateUserWithIdentity(ctx, username, passwordHash, token.Issuer, token.Subject)
+		if errors.Is(err, repos.ErrUsernameTaken) {
+			continue
+		}
+		if errors.Is(err, repos.ErrIdentityLinked) {
+			// A concurrent callback for the same account provisioned it first
+			return s.findOrProvisionUser(ctx, token, client)
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.securityEvents.Record(ctx, models.SecurityEventSSOProvisioned, user.Username, user.Id.String(), client, map[string]any{
+			"issuer":  token.Issuer,
+			"subject": token.Subject,
+		})
+		return user, nil
+	}
+	return nil, fmt.Errorf("failed to find a free username for %q: %w", token.Subject, repos.ErrUsernameTaken)
+}
+
+// ssoUsernameCandidates derives usernames for a provisioned user from the ID token,
+// falling back to suffixed variants when the preferred one is taken
+func ssoUsernameCandidates(token *oidc.IDToken) []string {
+	base := ""
+	for _, claim := range []string{token.PreferredUsername, strings.Split(token.Email, "@")[0], token.Name} {
+		if base = sanitizeUsername(claim); base != "" {
+			break
+		}
+	}
+	if base == "" {
+		base = "user"
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+
+	sum := sha256.Sum256([]byte(token.Issuer + " " + token.Subject))
+	suffix := hex.EncodeToString(sum[:])
+	candidates := []string{base, base + "-" + suffix[:4], base + "-" + suffix[:8]}
+	// Usernames must be at least three characters
+	if err := ValidateUsername(base); err != nil {
+		candidates = candidates[1:]
+	}
+	return candidates
+}
+
+// sanitizeUsername maps a provider claim onto the characters ValidateUsername allows
+func sanitizeUsername(claim string) string {
+	var b strings.Builder
+	for _, c := range claim {
+		switch {
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9'):
+			b.WriteRune(c)
+		case b.Len() == 0:
+			// Usernames start with a letter or digit
+		case c == '.' || c == '-' || c == '_':
+			b.WriteRune(c)
+		case c == ' ':
+			b.WriteRune('.')
+		}
+	}
+	return b.String()
+}
+
+func ssoStateKey(state string) string {
+	return "oidc_state:" + state
+}
+
+// IsSSOUserError reports whether err is the user's problem rather than ours, so the
+// login page can show it
+func IsSSOUserError(err error) bool {
+	return errors.Is(err, ErrSSOState) ||
+		errors.Is(err, ErrSSONotLinked) ||
+		errors.Is(err, ErrSSOAlreadyLinked) ||
+		errors.Is(err, ErrAccountPending) ||
+		errors.Is(err, ErrAccountRejected) ||
+		errors.Is(err, oidc.ErrInvalidIDToken) ||
+		errors.Is(err, oidc.ErrTokenExchange)
+}
@@ -3,46 +3,241 @@ package services
 import (
 	"backend/internal/models"
 	"backend/internal/repos"
+	"backend/pkg/passwordpolicy"
+	"backend/pkg/ratelimiter"
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
 	ErrInvalidPassword = errors.New("invalid password")
+	ErrInvalidUsername = errors.New("username must be 3 to 50 letters, digits, dots, dashes or underscores, starting with a letter or digit")
+	ErrAccountPending  = errors.New("account is waiting for admin approval")
+	ErrAccountRejected = errors.New("account registration was rejected")
+	ErrExternalAccount = errors.New("this account does not have a local password")
 )
 
+// dummyPasswordHash is compared against for unknown usernames so they take as long as wrong passwords
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("barbedwork-dummy-password"), bcrypt.DefaultCost)
+
+// unusablePasswordHash hashes a random password nobody knows, for accounts that never
+// sign in with a local password
+func unusablePasswordHash() (string, error) {
+	password, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
 type UserService struct {
-	userRepo *repos.UserRepo
+	userRepo       *repos.UserRepo
+	passwordPolicy *passwordpolicy.Policy
+	lockout        ratelimiter.Lockout
+	securityEvents *SecurityEventService
+	authenticators []Authenticator
+}
+
+// NewUserService checks logins against each authenticator in turn, falling back to
+// the passwords stored in the database when none are given
+func NewUserService(userRepo *repos.UserRepo, passwordPolicy *passwordpolicy.Policy, lockout ratelimiter.Lockout, securityEvents *SecurityEventService, authenticators ...Authenticator) *UserService {
+	if len(authenticators) == 0 {
+		authenticators = []Authenticator{NewPasswordAuthenticator(userRepo)}
+	}
+	return &UserService{userRepo: userRepo, passwordPolicy: passwordPolicy, lockout: lockout, securityEvents: securityEvents, authenticators: authenticators}
+}
+
+// CreateUser checks the password against the policy, returning a *passwordpolicy.ValidationError
+// if it is rejected, and stores the user with its bcrypt hash
+func (s *UserService) CreateUser(ctx context.Context, username string, password string) (*models.User, error) {
+	return s.createUser(ctx, username, password, models.UserStatusActive)
+}
+
+func (s *UserService) createUser(ctx context.Context, username string, password string, status string) (*models.User, error) {
+	if err := ValidateUsername(username); err != nil {
+		return nil, err
+	}
+	passwordHash, err := s.HashPassword(password, username)
+	if err != nil {
+		return nil, err
+	}
+	return s.userRepo.CreateUser(ctx, username, passwordHash, status, models.AuthSourceLocal)
+}
+
+// ValidateUsername allows 3 to 50 characters from a conservative set, so usernames
+// stay readable in mentions and URLs
+func ValidateUsername(username string) error {
+	if len(username) < 3 || len(username) > 50 {
+		return ErrInvalidUsername
+	}
+	for i, c := range username {
+		alphanumeric := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if alphanumeric {
+			continue
+		}
+		if i == 0 || (c != '.' && c != '-' && c != '_') {
+			return ErrInvalidUsername
+		}
+	}
+	return nil
+}
+
+// HashPassword validates a new password for the user against the policy and hashes it
+func (s *UserService) HashPassword(password string, username string) (string, error) {
+	if err := s.passwordPolicy.Validate(password, username); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Login checks a user's password with each authenticator in turn. Failures are tracked per username, so attackers
+// rotating addresses are still locked out, and unknown usernames are handled exactly
+// like wrong passwords so neither timing nor lockouts reveal which accounts exist.
+func (s *UserService) Login(ctx context.Context, username string, password string, client ClientInfo) (*models.User, error) {
+	lockoutKey := userLockoutKey(username)
+	if err := checkLockout(ctx, s.lockout, lockoutKey); err != nil {
+		var lockoutErr *LockoutError
+		if errors.As(err, &lockoutErr) {
+			s.securityEvents.Record(ctx, models.SecurityEventLoginBlocked, username, "", client, nil)
+		}
+		return nil, err
+	}
+
+	user, err := s.authenticate(ctx, username, password)
+	if errors.Is(err, ErrInvalidPassword) || errors.Is(err, ErrUnknownAccount) {
+		// The event names the account when there is one, whichever source rejected it
+		known, _ := s.userRepo.GetUserByUsername(ctx, username)
+		return nil, s.recordFailedLogin(ctx, lockoutKey, username, known, client)
+	}
+	if err != nil {
+		fmt.Println("Error authenticating user:", err)
+		return nil, err
+	}
+
+	if err := s.lockout.Reset(ctx, lockoutKey); err != nil {
+		return nil, err
+	}
+
+	// Only reported once the password is right, so it reveals nothing to guessers
+	switch user.Status {
+	case models.UserStatusPending:
+		return nil, ErrAccountPending
+	case models.UserStatusRejected:
+		return nil, ErrAccountRejected
+	}
+	return user, nil
 }
 
-func NewUserService(userRepo *repos.UserRepo) *UserService {
-	return &UserService{userRepo: userRepo}
+// authenticate asks each authenticator in turn until one recognises the username
+func (s *UserService) authenticate(ctx context.Context, username string, password string) (*models.User, error) {
+	for _, authenticator := range s.authenticators {
+		user, err := authenticator.Authenticate(ctx, username, password)
+		if errors.Is(err, ErrUnknownAccount) {
+			continue
+		}
+		return user, err
+	}
+	return nil, ErrUnknownAccount
 }
 
-func (s *UserService) CreateUser(ctx context.Context, username string, passwordHash string) (*models.User, error) {
-	//check name length
-	if len(username) > 50 {
-		return nil, errors.New("username must be less than 50 characters")
+// ChangePassword replaces a signed-in user's password once they confirm the current one.
+// Wrong current passwords count towards the login lockout so a hijacked session cannot
+// be used to guess the password.
+func (s *UserService) ChangePassword(ctx context.Context, userID string, currentPassword string, newPassword string, client ClientInfo) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.AuthSource != models.AuthSourceLocal {
+		return ErrExternalAccount
+	}
+
+	lockoutKey := userLockoutKey(user.Username)
+	if err := checkLockout(ctx, s.lockout, lockoutKey); err != nil {
+		return err
+	}
+
+	passwordHash, err := s.userRepo.GetPasswordHash(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(currentPassword)) != nil {
+		return s.recordFailedLogin(ctx, lockoutKey, user.Username, user, client)
+	}
+
+	if _, err := s.SetPassword(ctx, userID, newPassword); err != nil {
+		return err
 	}
-	return s.userRepo.CreateUser(ctx, username, passwordHash)
+	s.securityEvents.Record(ctx, models.SecurityEventPasswordChanged, user.Username, userID, client, nil)
+	return nil
 }
 
-func (s *UserService) Login(ctx context.Context, username string, password string) (*models.User, error) {
-	user, err := s.userRepo.GetUserByUsername(ctx, username)
+// SetPassword replaces a user's password without checking the old one, returning a
+// *passwordpolicy.ValidationError if the new one is rejected. Any login lockout on
+// the account is lifted.
+func (s *UserService) SetPassword(ctx context.Context, userID string, password string) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
-		fmt.Println("Error getting user by username:", err)
 		return nil, err
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		fmt.Println("Password comparison failed:", err)
-		return nil, ErrInvalidPassword
+	if user.AuthSource != models.AuthSourceLocal {
+		return nil, ErrExternalAccount
+	}
+	passwordHash, err := s.HashPassword(password, user.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, userID, passwordHash); err != nil {
+		return nil, err
+	}
+	if err := s.lockout.Reset(ctx, userLockoutKey(user.Username)); err != nil {
+		return nil, err
 	}
 	return user, nil
 }
 
+func (s *UserService) recordFailedLogin(ctx context.Context, lockoutKey string, username string, user *models.User, client ClientInfo) error {
+	userID := ""
+	if user != nil {
+		userID = user.Id.String()
+	}
+	s.securityEvents.Record(ctx, models.SecurityEventLoginFailed, username, userID, client, nil)
+
+	lockoutErr, err := recordFailedAttempt(ctx, s.lockout, lockoutKey)
+	if err != nil {
+		return err
+	}
+	if lockoutErr != nil {
+		s.securityEvents.Record(ctx, models.SecurityEventLoginLockedOut, username, userID, client, map[string]any{
+			"retry_after_seconds": int(lockoutErr.RetryAfter.Seconds()),
+		})
+		return lockoutErr
+	}
+	return ErrInvalidPassword
+}
+
+// userLockoutKey normalises the submitted username, bounding its length since it is attacker-controlled
+func userLockoutKey(username string) string {
+	key := strings.ToLower(strings.TrimSpace(username))
+	if len(key) > 64 {
+		key = key[:64]
+	}
+	return "user:" + key
+}
+
 func (s *UserService) GetAllUsers(ctx context.Context) ([]*models.User, error) {
 	return s.userRepo.GetAllUsers(ctx)
 }
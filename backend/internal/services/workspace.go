@@ -1 +1,50 @@
-package services
\ No newline at end of file
+package services
+
+import (
+	"backend/internal/repos"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DefaultMemberRole is assigned to users joining a workspace when no other role is configured
+const DefaultMemberRole = "Member"
+
+var (
+	ErrDefaultRoleNotFound = errors.New("default member role does not exist")
+)
+
+type WorkspaceService struct {
+	workspaceRepo     *repos.WorkspaceRepo
+	defaultMemberRole string
+}
+
+func NewWorkspaceService(workspaceRepo *repos.WorkspaceRepo, defaultMemberRole string) *WorkspaceService {
+	if defaultMemberRole == "" {
+		defaultMemberRole = DefaultMemberRole
+	}
+	return &WorkspaceService{
+		workspaceRepo:     workspaceRepo,
+		defaultMemberRole: defaultMemberRole,
+	}
+}
+
+// AddUserToWorkspace adds the user as a member holding the default role.
+// It reports whether the user was newly added.
+func (s *WorkspaceService) AddUserToWorkspace(ctx context.Context, userID string, workspaceID string) (bool, error) {
+	if workspaceID == "" {
+		return false, errors.New("workspace ID is required")
+	}
+	if userID == "" {
+		return false, ErrInvalidUserID
+	}
+
+	added, err := s.workspaceRepo.AddUserToWorkspace(ctx, userID, workspaceID, s.defaultMemberRole)
+	if err != nil {
+		if errors.Is(err, repos.ErrRoleNotFound) {
+			return false, fmt.Errorf("%w: %q", ErrDefaultRoleNotFound, s.defaultMemberRole)
+		}
+		return false, err
+	}
+	return added, nil
+}
@@ -0,0 +1,143 @@
+// Package apitoken generates personal access tokens and decides which scope an API
+// request made with one needs.
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Prefix marks a bearer credential as a personal access token rather than an
+// access JWT, and makes leaked tokens easy to find with secret scanners
+const Prefix = "bwp_"
+
+// displayLength is how much of a token is kept in the clear so its owner can tell
+// tokens apart
+const displayLength = len(Prefix) + 6
+
+// Scopes lists every scope a token can be granted. A write scope also grants the
+// matching read scope.
+var Scopes = []string{
+	"workspaces:read", "workspaces:write",
+	"channels:read", "channels:write",
+	"messages:read", "messages:write",
+	"roles:read", "roles:write",
+	"invites:read", "invites:write",
+	"events:read",
+	"user:read",
+}
+
+// Generate returns a new token, the part of it that may be displayed, and the hash
+// to store. The token itself is never stored.
+func Generate() (token string, displayPrefix string, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+	token = Prefix + base64.RawURLEncoding.EncodeToString(buf)
+	return token, token[:displayLength], Hash(token), nil
+}
+
+// Hash returns the value tokens are looked up by
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsToken reports whether a bearer credential is a personal access token
+func IsToken(credential string) bool {
+	return strings.HasPrefix(credential, Prefix)
+}
+
+// NormalizeScopes checks requested scopes, dropping duplicates and sorting them
+func NormalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(Scopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !slices.Contains(normalized, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+	slices.Sort(normalized)
+	return normalized, nil
+}
+
+// Allows reports whether the granted scopes cover the required one
+func Allows(granted []string, required string) bool {
+	if slices.Contains(granted, required) {
+		return true
+	}
+	resource, access, _ := strings.Cut(required, ":")
+	return access == "read" && slices.Contains(granted, resource+":write")
+}
+
+// RequiredScope maps a request onto the scope a token needs to make it. Reads
+// need the resource's read scope and everything else its write scope. Account,
+// authentication and admin routes have no scope, so tokens can never reach them:
+// a leaked token must not be able to mint more tokens or change the password.
+func RequiredScope(method string, path string) (string, bool) {
+	resource := resourceFor(path)
+	if resource == "" {
+		return "", false
+	}
+	read := method == http.MethodGet || method == http.MethodHead
+	if resource == "events" || resource == "user" {
+		// Read-only resources
+		if !read {
+			return "", false
+		}
+	}
+	if read {
+		return resource + ":read", true
+	}
+	return resource + ":write", true
+}
+
+func resourceFor(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case path == "/user":
+		return "user"
+	case path == "/api/ws":
+		return "events"
+	case path == "/api/permissions", path == "/api/roles", strings.HasPrefix(path, "/api/roles/"):
+		return "roles"
+	case len(segments) == 3 && segments[0] == "api" && segments[1] == "invites":
+		return "invites"
+	case len(segments) < 2 || segments[0] != "api" || segments[1] != "workspaces":
+		return ""
+	}
+
+	// /api/workspaces/{workspaceId}/...
+	if len(segments) <= 3 {
+		return "workspaces"
+	}
+	switch segments[3] {
+	case "channels":
+		if len(segments) >= 6 && segments[5] == "messages" {
+			return "messages"
+		}
+		// Webhooks are credentials of their own, so tokens cannot manage them
+		if len(segments) >= 6 && segments[5] == "webhooks" {
+			return ""
+		}
+		return "channels"
+	case "invites":
+		return "invites"
+	case "user-roles":
+		return "roles"
+	}
+	return ""
+}
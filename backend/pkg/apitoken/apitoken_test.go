@@ -0,0 +1,104 @@
+package apitoken
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRequiredScope(t *testing.T) {
+	testCases := []struct {
+		method string
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{http.MethodGet, "/api/workspaces", "workspaces:read", true},
+		{http.MethodGet, "/api/workspaces/ws1", "workspaces:read", true},
+		{http.MethodPost, "/api/workspaces/ws1/channels", "channels:write", true},
+		{http.MethodGet, "/api/workspaces/ws1/channels/ch1/messages", "messages:read", true},
+		{http.MethodPost, "/api/workspaces/ws1/channels/ch1/messages", "messages:write", true},
+		{http.MethodDelete, "/api/workspaces/ws1/invites/inv1", "invites:write", true},
+		{http.MethodPost, "/api/invites/abc123", "invites:write", true},
+		{http.MethodPost, "/api/workspaces/ws1/user-roles", "roles:write", true},
+		{http.MethodGet, "/api/permissions", "roles:read", true},
+		{http.MethodPut, "/api/roles/3", "roles:write", true},
+		{http.MethodGet, "/api/ws", "events:read", true},
+		{http.MethodGet, "/user", "user:read", true},
+		{http.MethodPost, "/user", "", false},
+		{http.MethodGet, "/api/workspaces/ws1/unknown", "", false},
+		{http.MethodPost, "/api/workspaces/ws1/bots/bot1/tokens", "", false},
+		{http.MethodPost, "/api/workspaces/ws1/channels/ch1/webhooks", "", false},
+		{http.MethodGet, "/api/workspaces/ws1/outgoing-webhooks/wh1/deliveries", "", false},
+		{http.MethodGet, "/api/user/tokens", "", false},
+		{http.MethodPut, "/api/user/password", "", false},
+		{http.MethodGet, "/api/user/sessions", "", false},
+		{http.MethodPost, "/api/auth/oidc/link", "", false},
+		{http.MethodGet, "/api/admin/users", "", false},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.method+" "+testCase.path, func(t *testing.T) {
+			got, ok := RequiredScope(testCase.method, testCase.path)
+			if got != testCase.want || ok != testCase.wantOK {
+				t.Errorf("RequiredScope = %q, %v; want %q, %v", got, ok, testCase.want, testCase.wantOK)
+			}
+		})
+	}
+}
+
+func TestAllows(t *testing.T) {
+	testCases := []struct {
+		granted  []string
+		required string
+		want     bool
+	}{
+		{[]string{"messages:read"}, "messages:read", true},
+		{[]string{"messages:write"}, "messages:read", true},
+		{[]string{"messages:read"}, "messages:write", false},
+		{[]string{"channels:write"}, "messages:read", false},
+		{nil, "messages:read", false},
+	}
+	for _, testCase := range testCases {
+		if got := Allows(testCase.granted, testCase.required); got != testCase.want {
+			t.Errorf("Allows(%v, %q) = %v, want %v", testCase.granted, testCase.required, got, testCase.want)
+		}
+	}
+}
+
+func TestNormalizeScopes(t *testing.T) {
+	got, err := NormalizeScopes([]string{"messages:write", "workspaces:read", "messages:write"})
+	if err != nil {
+		t.Fatalf("NormalizeScopes failed: %v", err)
+	}
+	if want := []string{"messages:write", "workspaces:read"}; !slices.Equal(got, want) {
+		t.Errorf("NormalizeScopes = %v, want %v", got, want)
+	}
+
+	for _, scopes := range [][]string{nil, {"messages:delete"}, {"admin"}} {
+		if _, err := NormalizeScopes(scopes); err == nil {
+			t.Errorf("NormalizeScopes(%v) succeeded, want an error", scopes)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	token, displayPrefix, hash, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !IsToken(token) || !strings.HasPrefix(token, displayPrefix) {
+		t.Errorf("token %q does not start with %q and %q", token, Prefix, displayPrefix)
+	}
+	if len(displayPrefix) >= len(token)/2 {
+		t.Errorf("display prefix %q reveals too much of the token", displayPrefix)
+	}
+	if hash != Hash(token) || strings.Contains(hash, token[len(Prefix):]) {
+		t.Errorf("hash %q does not match the token", hash)
+	}
+
+	other, _, _, err := Generate()
+	if err != nil || other == token {
+		t.Errorf("Generate returned a repeated token")
+	}
+}
@@ -0,0 +1,57 @@
+// Package grouproles maps groups from an external identity source, such as an
+// OpenID provider or an LDAP directory, to workspace roles.
+package grouproles
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GroupRole grants Role in WorkspaceID to members of the provider group Group
+type GroupRole struct {
+	Group       string
+	WorkspaceID string
+	Role        string
+}
+
+// Parse reads mappings written as comma separated
+// "group=workspaceID:Role" entries, for example
+//
+//	engineering=6f1c...:Member,eng-leads=6f1c...:Admin
+func Parse(spec string) ([]GroupRole, error) {
+	var mappings []GroupRole
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		group, target, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("group role mapping %q is missing '='", entry)
+		}
+		workspaceID, role, ok := strings.Cut(target, ":")
+		group, workspaceID, role = strings.TrimSpace(group), strings.TrimSpace(workspaceID), strings.TrimSpace(role)
+		if !ok || group == "" || workspaceID == "" || role == "" {
+			return nil, fmt.Errorf("group role mapping %q must look like group=workspaceID:Role", entry)
+		}
+		mappings = append(mappings, GroupRole{Group: group, WorkspaceID: workspaceID, Role: role})
+	}
+	return mappings, nil
+}
+
+// Split separates the mappings that apply to a user in groups from
+// those that do not, so roles can be both granted and withdrawn
+func Split(mappings []GroupRole, groups []string) (granted []GroupRole, withdrawn []GroupRole) {
+	member := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		member[group] = true
+	}
+	for _, mapping := range mappings {
+		if member[mapping.Group] {
+			granted = append(granted, mapping)
+		} else {
+			withdrawn = append(withdrawn, mapping)
+		}
+	}
+	return granted, withdrawn
+}
@@ -0,0 +1,32 @@
+package grouproles_test
+
+import (
+	"backend/pkg/grouproles"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	mappings, err := grouproles.Parse(" engineering=ws-1:Member , leads=ws-1:Admin,")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := []grouproles.GroupRole{
+		{Group: "engineering", WorkspaceID: "ws-1", Role: "Member"},
+		{Group: "leads", WorkspaceID: "ws-1", Role: "Admin"},
+	}
+	if !reflect.DeepEqual(mappings, want) {
+		t.Errorf("Parse() = %v, want %v", mappings, want)
+	}
+
+	for _, spec := range []string{"engineering", "engineering=ws-1", "=ws-1:Member", "engineering=ws-1:"} {
+		if _, err := grouproles.Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+
+	granted, withdrawn := grouproles.Split(mappings, []string{"engineering"})
+	if len(granted) != 1 || granted[0].Group != "engineering" || len(withdrawn) != 1 || withdrawn[0].Group != "leads" {
+		t.Errorf("Split() = %v, %v", granted, withdrawn)
+	}
+}
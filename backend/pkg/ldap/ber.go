@@ -0,0 +1,253 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BER element classes and the universal tags LDAP uses. Only the subset of
+// ASN.1 BER (X.690) that LDAP needs is supported: definite lengths and low tag numbers.
+const (
+	ClassUniversal   = 0x00
+	ClassApplication = 0x40
+	ClassContext     = 0x80
+
+	TagBoolean     = 0x01
+	TagInteger     = 0x02
+	TagOctetString = 0x04
+	TagNull        = 0x05
+	TagEnumerated  = 0x0a
+	TagSequence    = 0x10
+	TagSet         = 0x11
+)
+
+// maxPacketSize bounds what we read from the network before giving up
+const maxPacketSize = 16 << 20
+
+var errMalformedPacket = errors.New("ldap: malformed BER packet")
+
+// Packet is a decoded BER element. Constructed elements have Children; primitive
+// ones have Value.
+type Packet struct {
+	Class       byte
+	Constructed bool
+	Tag         byte
+	Value       []byte
+	Children    []*Packet
+}
+
+// NewPrimitive builds an element holding value
+func NewPrimitive(class byte, tag byte, value []byte) *Packet {
+	return &Packet{Class: class, Tag: tag, Value: value}
+}
+
+// NewConstructed builds an element holding children
+func NewConstructed(class byte, tag byte, children ...*Packet) *Packet {
+	return &Packet{Class: class, Constructed: true, Tag: tag, Children: children}
+}
+
+func NewSequence(children ...*Packet) *Packet {
+	return NewConstructed(ClassUniversal, TagSequence, children...)
+}
+
+func NewOctetString(value string) *Packet {
+	return NewPrimitive(ClassUniversal, TagOctetString, []byte(value))
+}
+
+func NewBoolean(value bool) *Packet {
+	if value {
+		return NewPrimitive(ClassUniversal, TagBoolean, []byte{0xff})
+	}
+	return NewPrimitive(ClassUniversal, TagBoolean, []byte{0x00})
+}
+
+func NewInteger(value int64) *Packet {
+	return NewPrimitive(ClassUniversal, TagInteger, encodeInteger(value))
+}
+
+func NewEnumerated(value int64) *Packet {
+	return NewPrimitive(ClassUniversal, TagEnumerated, encodeInteger(value))
+}
+
+// encodeInteger writes the shortest two's complement big-endian form
+func encodeInteger(value int64) []byte {
+	var out []byte
+	for {
+		out = append([]byte{byte(value)}, out...)
+		value >>= 8
+		last := out[0]
+		if (value == 0 && last&0x80 == 0) || (value == -1 && last&0x80 != 0) {
+			return out
+		}
+	}
+}
+
+// Int decodes an INTEGER or ENUMERATED value
+func (p *Packet) Int() (int64, error) {
+	if p.Constructed || len(p.Value) == 0 || len(p.Value) > 8 {
+		return 0, errMalformedPacket
+	}
+	value := int64(int8(p.Value[0]))
+	for _, b := range p.Value[1:] {
+		value = value<<8 | int64(b)
+	}
+	return value, nil
+}
+
+// Bool decodes a BOOLEAN value
+func (p *Packet) Bool() (bool, error) {
+	if p.Constructed || len(p.Value) != 1 {
+		return false, errMalformedPacket
+	}
+	return p.Value[0] != 0, nil
+}
+
+// String returns a primitive value as text
+func (p *Packet) String() string {
+	return string(p.Value)
+}
+
+// Is reports whether the element has the given class and tag
+func (p *Packet) Is(class byte, tag byte) bool {
+	return p.Class == class && p.Tag == tag
+}
+
+// Bytes encodes the element
+func (p *Packet) Bytes() []byte {
+	content := p.Value
+	if p.Constructed {
+		content = nil
+		for _, child := range p.Children {
+			content = append(content, child.Bytes()...)
+		}
+	}
+	identifier := p.Class | p.Tag
+	if p.Constructed {
+		identifier |= 0x20
+	}
+	out := append([]byte{identifier}, encodeLength(len(content))...)
+	return append(out, content...)
+}
+
+func encodeLength(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+	var digits []byte
+	for length > 0 {
+		digits = append([]byte{byte(length)}, digits...)
+		length >>= 8
+	}
+	return append([]byte{0x80 | byte(len(digits))}, digits...)
+}
+
+// ReadPacket reads one complete element from r
+func ReadPacket(r *bufio.Reader) (*Packet, error) {
+	identifier, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length, err := readLength(r)
+	if err != nil {
+		return nil, err
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+	return decodePacket(identifier, content)
+}
+
+// ParsePacket decodes one element that fills data exactly
+func ParsePacket(data []byte) (*Packet, error) {
+	packet, rest, err := parsePacket(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errMalformedPacket
+	}
+	return packet, nil
+}
+
+func parsePacket(data []byte) (*Packet, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, errMalformedPacket
+	}
+	identifier := data[0]
+	length := int(data[1])
+	data = data[2:]
+	if length&0x80 != 0 {
+		digits := length & 0x7f
+		// Indefinite lengths are not allowed in LDAP
+		if digits == 0 || digits > 4 || len(data) < digits {
+			return nil, nil, errMalformedPacket
+		}
+		length = 0
+		for _, b := range data[:digits] {
+			length = length<<8 | int(b)
+		}
+		data = data[digits:]
+	}
+	if length > len(data) {
+		return nil, nil, errMalformedPacket
+	}
+	packet, err := decodePacket(identifier, data[:length])
+	if err != nil {
+		return nil, nil, err
+	}
+	return packet, data[length:], nil
+}
+
+func readLength(r *bufio.Reader) (int, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if first&0x80 == 0 {
+		return int(first), nil
+	}
+	digits := int(first & 0x7f)
+	if digits == 0 || digits > 4 {
+		return 0, errMalformedPacket
+	}
+	length := 0
+	for i := 0; i < digits; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	if length > maxPacketSize {
+		return 0, fmt.Errorf("ldap: packet of %d bytes is too large", length)
+	}
+	return length, nil
+}
+
+func decodePacket(identifier byte, content []byte) (*Packet, error) {
+	// High tag numbers (0x1f) never appear in LDAP
+	if identifier&0x1f == 0x1f {
+		return nil, errMalformedPacket
+	}
+	packet := &Packet{
+		Class:       identifier & 0xc0,
+		Constructed: identifier&0x20 != 0,
+		Tag:         identifier & 0x1f,
+	}
+	if !packet.Constructed {
+		packet.Value = content
+		return packet, nil
+	}
+	for len(content) > 0 {
+		child, rest, err := parsePacket(content)
+		if err != nil {
+			return nil, err
+		}
+		packet.Children = append(packet.Children, child)
+		content = rest
+	}
+	return packet, nil
+}
@@ -0,0 +1,342 @@
+// Package ldap is a minimal LDAPv3 client (RFC 4511) covering what directory
+// authentication needs: simple binds, searches and StartTLS.
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Protocol operations, application-specific tags
+const (
+	ApplicationBindRequest           = 0
+	ApplicationBindResponse          = 1
+	ApplicationUnbindRequest         = 2
+	ApplicationSearchRequest         = 3
+	ApplicationSearchResultEntry     = 4
+	ApplicationSearchResultDone      = 5
+	ApplicationSearchResultReference = 19
+	ApplicationExtendedRequest       = 23
+	ApplicationExtendedResponse      = 24
+)
+
+// Search scopes
+const (
+	ScopeBaseObject   = 0
+	ScopeSingleLevel  = 1
+	ScopeWholeSubtree = 2
+)
+
+// Result codes this package reacts to
+const (
+	ResultSuccess            = 0
+	ResultProtocolError      = 2
+	ResultSizeLimitExceeded  = 4
+	ResultNoSuchObject       = 32
+	ResultInvalidCredentials = 49
+	ResultUnwillingToPerform = 53
+)
+
+// startTLSOID names the StartTLS extended operation (RFC 4511 section 4.14)
+const startTLSOID = "1.3.6.1.4.1.1466.20037"
+
+var ErrInvalidCredentials = errors.New("ldap: invalid credentials")
+
+// Error is a result code other than success returned by the server
+type Error struct {
+	ResultCode int64
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap: result code %d", e.ResultCode)
+	}
+	return fmt.Sprintf("ldap: result code %d: %s", e.ResultCode, e.Message)
+}
+
+// Entry is one object returned by a search
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Values returns an attribute's values, matching its name case-insensitively
+func (e *Entry) Values(attribute string) []string {
+	for name, values := range e.Attributes {
+		if strings.EqualFold(name, attribute) {
+			return values
+		}
+	}
+	return nil
+}
+
+// Value returns an attribute's first value, or "" when it has none
+func (e *Entry) Value(attribute string) string {
+	if values := e.Values(attribute); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+type SearchRequest struct {
+	BaseDN     string
+	Scope      int
+	Filter     string
+	Attributes []string
+	// SizeLimit caps the entries returned; 0 leaves it to the server
+	SizeLimit int
+}
+
+// Conn is a connection to a directory server. It runs one operation at a time and
+// is not safe for concurrent use.
+type Conn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	host    string
+	timeout time.Duration
+	nextID  int64
+}
+
+// Dial connects to an ldap:// or ldaps:// URL. Every operation on the returned
+// connection must finish within timeout, 10 seconds when it is zero.
+func Dial(ctx context.Context, rawURL string, tlsConfig *tls.Config, timeout time.Duration) (*Conn, error) {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: invalid URL: %w", err)
+	}
+	host := parsed.Hostname()
+	port := parsed.Port()
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	switch parsed.Scheme {
+	case "ldap":
+		if port == "" {
+			port = "389"
+		}
+		conn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	case "ldaps":
+		if port == "" {
+			port = "636"
+		}
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: withServerName(tlsConfig, host)}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	default:
+		return nil, fmt.Errorf("ldap: unsupported URL scheme %q", parsed.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ldap: failed to connect: %w", err)
+	}
+	return &Conn{conn: conn, reader: bufio.NewReader(conn), host: host, timeout: timeout}, nil
+}
+
+func withServerName(config *tls.Config, host string) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config = config.Clone()
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	return config
+}
+
+// StartTLS upgrades a plain connection to TLS before any credentials are sent
+func (c *Conn) StartTLS(tlsConfig *tls.Config) error {
+	request := NewConstructed(ClassApplication, ApplicationExtendedRequest,
+		NewPrimitive(ClassContext, 0, []byte(startTLSOID)),
+	)
+	response, err := c.roundTrip(request, ApplicationExtendedResponse)
+	if err != nil {
+		return err
+	}
+	if err := resultError(response); err != nil {
+		return err
+	}
+
+	tlsConn := tls.Client(c.conn, withServerName(tlsConfig, c.host))
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if err := tlsConn.Handshake(); err != nil {
+		return fmt.Errorf("ldap: TLS handshake failed: %w", err)
+	}
+	c.conn = tlsConn
+	c.reader = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// Bind authenticates the connection as dn. An empty password is refused here
+// because servers treat it as an unauthenticated bind that always succeeds.
+func (c *Conn) Bind(dn string, password string) error {
+	if password == "" {
+		return ErrInvalidCredentials
+	}
+	request := NewConstructed(ClassApplication, ApplicationBindRequest,
+		NewInteger(3),
+		NewOctetString(dn),
+		NewPrimitive(ClassContext, 0, []byte(password)),
+	)
+	response, err := c.roundTrip(request, ApplicationBindResponse)
+	if err != nil {
+		return err
+	}
+	err = resultError(response)
+	var ldapErr *Error
+	if errors.As(err, &ldapErr) && ldapErr.ResultCode == ResultInvalidCredentials {
+		return ErrInvalidCredentials
+	}
+	return err
+}
+
+// Search returns every entry matching the request. Referrals are ignored.
+func (c *Conn) Search(req *SearchRequest) ([]*Entry, error) {
+	filter, err := CompileFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	attributes := NewSequence()
+	for _, attribute := range req.Attributes {
+		attributes.Children = append(attributes.Children, NewOctetString(attribute))
+	}
+	request := NewConstructed(ClassApplication, ApplicationSearchRequest,
+		NewOctetString(req.BaseDN),
+		NewEnumerated(int64(req.Scope)),
+		NewEnumerated(0), // never dereference aliases
+		NewInteger(int64(req.SizeLimit)),
+		NewInteger(int64(c.timeout/time.Second)),
+		NewBoolean(false),
+		filter,
+		attributes,
+	)
+
+	messageID, err := c.send(request)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	for {
+		op, err := c.receive(messageID)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case op.Is(ClassApplication, ApplicationSearchResultEntry):
+			entry, err := parseEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case op.Is(ClassApplication, ApplicationSearchResultReference):
+			continue
+		case op.Is(ClassApplication, ApplicationSearchResultDone):
+			return entries, resultError(op)
+		default:
+			return nil, fmt.Errorf("ldap: unexpected response to search")
+		}
+	}
+}
+
+// Close unbinds and closes the connection
+func (c *Conn) Close() error {
+	c.send(NewPrimitive(ClassApplication, ApplicationUnbindRequest, nil))
+	return c.conn.Close()
+}
+
+func (c *Conn) roundTrip(request *Packet, responseTag byte) (*Packet, error) {
+	messageID, err := c.send(request)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.receive(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if !response.Is(ClassApplication, responseTag) {
+		return nil, fmt.Errorf("ldap: unexpected response type %d", response.Tag)
+	}
+	return response, nil
+}
+
+func (c *Conn) send(op *Packet) (int64, error) {
+	c.nextID++
+	message := NewSequence(NewInteger(c.nextID), op)
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := c.conn.Write(message.Bytes()); err != nil {
+		return 0, fmt.Errorf("ldap: failed to send request: %w", err)
+	}
+	return c.nextID, nil
+}
+
+// receive reads messages until the response to messageID arrives, returning its
+// protocol operation
+func (c *Conn) receive(messageID int64) (*Packet, error) {
+	for {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+		message, err := ReadPacket(c.reader)
+		if err != nil {
+			return nil, fmt.Errorf("ldap: failed to read response: %w", err)
+		}
+		if !message.Is(ClassUniversal, TagSequence) || len(message.Children) < 2 {
+			return nil, errMalformedPacket
+		}
+		id, err := message.Children[0].Int()
+		if err != nil {
+			return nil, err
+		}
+		// Message ID 0 is an unsolicited notification, such as the server disconnecting
+		if id == 0 {
+			op := message.Children[1]
+			if op.Is(ClassApplication, ApplicationExtendedResponse) {
+				if err := resultError(op); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if id == messageID {
+			return message.Children[1], nil
+		}
+	}
+}
+
+// resultError reads the LDAPResult at the start of a response
+func resultError(op *Packet) error {
+	if len(op.Children) < 3 {
+		return errMalformedPacket
+	}
+	code, err := op.Children[0].Int()
+	if err != nil {
+		return err
+	}
+	if code == ResultSuccess {
+		return nil
+	}
+	return &Error{ResultCode: code, Message: op.Children[2].String()}
+}
+
+func parseEntry(op *Packet) (*Entry, error) {
+	if len(op.Children) != 2 {
+		return nil, errMalformedPacket
+	}
+	entry := &Entry{DN: op.Children[0].String(), Attributes: map[string][]string{}}
+	for _, attribute := range op.Children[1].Children {
+		if len(attribute.Children) != 2 {
+			return nil, errMalformedPacket
+		}
+		name := attribute.Children[0].String()
+		for _, value := range attribute.Children[1].Children {
+			entry.Attributes[name] = append(entry.Attributes[name], value.String())
+		}
+	}
+	return entry, nil
+}
@@ -0,0 +1,233 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrAccountNotFound  = errors.New("ldap: no directory account matches the username")
+	ErrAmbiguousAccount = errors.New("ldap: more than one directory account matches the username")
+)
+
+// DirectoryConfig describes how accounts and groups are laid out in the directory.
+// Filters may use {username}, and group filters also {dn}; both are escaped.
+type DirectoryConfig struct {
+	URL       string
+	StartTLS  bool
+	TLSConfig *tls.Config
+	Timeout   time.Duration
+
+	// BindDN and BindPassword are the service account used to search the directory
+	BindDN       string
+	BindPassword string
+
+	UserBaseDN        string
+	UserFilter        string
+	UsernameAttribute string
+
+	// GroupBaseDN may be empty to skip group lookups
+	GroupBaseDN        string
+	GroupFilter        string
+	GroupNameAttribute string
+}
+
+// Account is a directory user and the names of the groups they belong to
+type Account struct {
+	DN       string
+	Username string
+	Groups   []string
+}
+
+// Directory looks accounts up in an LDAP server and checks their passwords
+type Directory struct {
+	config DirectoryConfig
+}
+
+// NewDirectory fills in defaults suited to OpenLDAP: accounts matched on uid and
+// groupOfNames or posixGroup groups named by cn
+func NewDirectory(config DirectoryConfig) *Directory {
+	if config.UsernameAttribute == "" {
+		config.UsernameAttribute = "uid"
+	}
+	if config.UserFilter == "" {
+		config.UserFilter = "(" + config.UsernameAttribute + "={username})"
+	}
+	if config.GroupFilter == "" {
+		config.GroupFilter = "(|(member={dn})(uniqueMember={dn})(memberUid={username}))"
+	}
+	if config.GroupNameAttribute == "" {
+		config.GroupNameAttribute = "cn"
+	}
+	return &Directory{config: config}
+}
+
+// DirectoryConfigFromEnv reads the LDAP_* variables. It returns nil when LDAP_URL is
+// unset, meaning directory authentication is disabled.
+func DirectoryConfigFromEnv() (*DirectoryConfig, error) {
+	rawURL := os.Getenv("LDAP_URL")
+	if rawURL == "" {
+		return nil, nil
+	}
+	config := &DirectoryConfig{
+		URL:                rawURL,
+		StartTLS:           os.Getenv("LDAP_START_TLS") == "true",
+		BindDN:             os.Getenv("LDAP_BIND_DN"),
+		BindPassword:       os.Getenv("LDAP_BIND_PASSWORD"),
+		UserBaseDN:         os.Getenv("LDAP_USER_BASE_DN"),
+		UserFilter:         os.Getenv("LDAP_USER_FILTER"),
+		UsernameAttribute:  os.Getenv("LDAP_USERNAME_ATTRIBUTE"),
+		GroupBaseDN:        os.Getenv("LDAP_GROUP_BASE_DN"),
+		GroupFilter:        os.Getenv("LDAP_GROUP_FILTER"),
+		GroupNameAttribute: os.Getenv("LDAP_GROUP_NAME_ATTRIBUTE"),
+	}
+	if config.UserBaseDN == "" {
+		return nil, errors.New("LDAP_USER_BASE_DN is required when LDAP_URL is set")
+	}
+	if value := os.Getenv("LDAP_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return nil, errors.New("LDAP_TIMEOUT must be a positive duration such as 10s")
+		}
+		config.Timeout = timeout
+	}
+	return config, nil
+}
+
+// Authenticate checks the password by binding as the user's entry. Unknown users
+// report ErrAccountNotFound and wrong passwords ErrInvalidCredentials.
+func (d *Directory) Authenticate(ctx context.Context, username string, password string) (*Account, error) {
+	conn, err := d.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := d.findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Bind(entry.DN, password); err != nil {
+		return nil, err
+	}
+
+	// Users may not be allowed to read groups, so look them up as the service account
+	if err := d.bindService(conn); err != nil {
+		return nil, err
+	}
+	return d.account(conn, entry, username)
+}
+
+// LookupAccount finds a user and their groups without checking a password
+func (d *Directory) LookupAccount(ctx context.Context, username string) (*Account, error) {
+	conn, err := d.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := d.findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+	return d.account(conn, entry, username)
+}
+
+func (d *Directory) connect(ctx context.Context) (*Conn, error) {
+	conn, err := Dial(ctx, d.config.URL, d.config.TLSConfig, d.config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if d.config.StartTLS {
+		if err := conn.StartTLS(d.config.TLSConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if err := d.bindService(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (d *Directory) bindService(conn *Conn) error {
+	if d.config.BindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(d.config.BindDN, d.config.BindPassword); err != nil {
+		return fmt.Errorf("ldap: service account bind failed: %w", err)
+	}
+	return nil
+}
+
+func (d *Directory) findUser(conn *Conn, username string) (*Entry, error) {
+	if username == "" {
+		return nil, ErrAccountNotFound
+	}
+	entries, err := conn.Search(&SearchRequest{
+		BaseDN:     d.config.UserBaseDN,
+		Scope:      ScopeWholeSubtree,
+		Filter:     expandFilter(d.config.UserFilter, map[string]string{"username": username}),
+		Attributes: []string{d.config.UsernameAttribute},
+		SizeLimit:  2,
+	})
+	var ldapErr *Error
+	if errors.As(err, &ldapErr) && ldapErr.ResultCode == ResultSizeLimitExceeded {
+		return nil, ErrAmbiguousAccount
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch len(entries) {
+	case 0:
+		return nil, ErrAccountNotFound
+	case 1:
+		return entries[0], nil
+	}
+	return nil, ErrAmbiguousAccount
+}
+
+func (d *Directory) account(conn *Conn, entry *Entry, username string) (*Account, error) {
+	// The directory's spelling of the username wins over what the user typed
+	account := &Account{DN: entry.DN, Username: entry.Value(d.config.UsernameAttribute)}
+	if account.Username == "" {
+		account.Username = username
+	}
+	if d.config.GroupBaseDN == "" {
+		return account, nil
+	}
+
+	groups, err := conn.Search(&SearchRequest{
+		BaseDN: d.config.GroupBaseDN,
+		Scope:  ScopeWholeSubtree,
+		Filter: expandFilter(d.config.GroupFilter, map[string]string{
+			"dn":       entry.DN,
+			"username": account.Username,
+		}),
+		Attributes: []string{d.config.GroupNameAttribute},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ldap: group search failed: %w", err)
+	}
+	for _, group := range groups {
+		if name := group.Value(d.config.GroupNameAttribute); name != "" {
+			account.Groups = append(account.Groups, name)
+		}
+	}
+	return account, nil
+}
+
+// expandFilter substitutes escaped values for {placeholders} in a filter template
+func expandFilter(template string, values map[string]string) string {
+	pairs := make([]string, 0, len(values)*2)
+	for name, value := range values {
+		pairs = append(pairs, "{"+name+"}", EscapeFilter(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
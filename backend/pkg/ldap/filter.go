@@ -0,0 +1,191 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Search filter choices (RFC 4511 section 4.5.1), context-specific tags
+const (
+	FilterAnd            = 0
+	FilterOr             = 1
+	FilterNot            = 2
+	FilterEqualityMatch  = 3
+	FilterSubstrings     = 4
+	FilterGreaterOrEqual = 5
+	FilterLessOrEqual    = 6
+	FilterPresent        = 7
+	FilterApproxMatch    = 8
+
+	SubstringInitial = 0
+	SubstringAny     = 1
+	SubstringFinal   = 2
+)
+
+// EscapeFilter escapes a value for use inside a search filter (RFC 4515 section 3),
+// so user input cannot change the filter's meaning
+func EscapeFilter(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\', '*', '(', ')', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// CompileFilter parses a string filter such as "(&(objectClass=person)(uid=alice))"
+func CompileFilter(filter string) (*Packet, error) {
+	filter = strings.TrimSpace(filter)
+	// A bare item without parentheses is common in configuration
+	if !strings.HasPrefix(filter, "(") {
+		filter = "(" + filter + ")"
+	}
+	packet, rest, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("ldap: unexpected %q after filter", rest)
+	}
+	return packet, nil
+}
+
+func compileFilter(filter string) (*Packet, string, error) {
+	if !strings.HasPrefix(filter, "(") {
+		return nil, "", fmt.Errorf("ldap: filter %q must start with '('", filter)
+	}
+	filter = filter[1:]
+	if filter == "" {
+		return nil, "", fmt.Errorf("ldap: unterminated filter")
+	}
+
+	switch filter[0] {
+	case '&', '|':
+		tag := byte(FilterAnd)
+		if filter[0] == '|' {
+			tag = FilterOr
+		}
+		packet := NewConstructed(ClassContext, tag)
+		rest := filter[1:]
+		for strings.HasPrefix(rest, "(") {
+			child, next, err := compileFilter(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			packet.Children = append(packet.Children, child)
+			rest = next
+		}
+		if len(packet.Children) == 0 {
+			return nil, "", fmt.Errorf("ldap: empty filter list")
+		}
+		return closeFilter(packet, rest)
+	case '!':
+		child, rest, err := compileFilter(filter[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		return closeFilter(NewConstructed(ClassContext, FilterNot, child), rest)
+	}
+
+	end := strings.IndexByte(filter, ')')
+	if end < 0 {
+		return nil, "", fmt.Errorf("ldap: unterminated filter")
+	}
+	packet, err := compileItem(filter[:end])
+	if err != nil {
+		return nil, "", err
+	}
+	return packet, filter[end+1:], nil
+}
+
+func closeFilter(packet *Packet, rest string) (*Packet, string, error) {
+	if !strings.HasPrefix(rest, ")") {
+		return nil, "", fmt.Errorf("ldap: unterminated filter")
+	}
+	return packet, rest[1:], nil
+}
+
+// compileItem handles a simple comparison such as uid=alice, cn=a*b or mail=*
+func compileItem(item string) (*Packet, error) {
+	eq := strings.IndexByte(item, '=')
+	if eq <= 0 {
+		return nil, fmt.Errorf("ldap: invalid filter item %q", item)
+	}
+	attribute, value := item[:eq], item[eq+1:]
+
+	tag := byte(FilterEqualityMatch)
+	switch attribute[len(attribute)-1] {
+	case '>':
+		tag = FilterGreaterOrEqual
+	case '<':
+		tag = FilterLessOrEqual
+	case '~':
+		tag = FilterApproxMatch
+	}
+	if tag != FilterEqualityMatch {
+		attribute = attribute[:len(attribute)-1]
+	}
+	if attribute == "" || strings.ContainsAny(attribute, "()*\\ ") {
+		return nil, fmt.Errorf("ldap: invalid attribute in filter item %q", item)
+	}
+
+	if tag == FilterEqualityMatch && value == "*" {
+		return NewPrimitive(ClassContext, FilterPresent, []byte(attribute)), nil
+	}
+	if tag == FilterEqualityMatch && strings.Contains(value, "*") {
+		parts := strings.Split(value, "*")
+		substrings := NewSequence()
+		for i, part := range parts {
+			if part == "" {
+				continue
+			}
+			unescaped, err := unescapeFilterValue(part)
+			if err != nil {
+				return nil, err
+			}
+			kind := byte(SubstringAny)
+			switch i {
+			case 0:
+				kind = SubstringInitial
+			case len(parts) - 1:
+				kind = SubstringFinal
+			}
+			substrings.Children = append(substrings.Children, NewPrimitive(ClassContext, kind, []byte(unescaped)))
+		}
+		return NewConstructed(ClassContext, FilterSubstrings, NewOctetString(attribute), substrings), nil
+	}
+
+	unescaped, err := unescapeFilterValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return NewConstructed(ClassContext, tag, NewOctetString(attribute), NewOctetString(unescaped)), nil
+}
+
+func unescapeFilterValue(value string) (string, error) {
+	if !strings.Contains(value, "\\") {
+		return value, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			b.WriteByte(value[i])
+			continue
+		}
+		if i+3 > len(value) {
+			return "", fmt.Errorf("ldap: invalid escape in filter value %q", value)
+		}
+		decoded, err := hex.DecodeString(value[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("ldap: invalid escape in filter value %q", value)
+		}
+		b.Write(decoded)
+		i += 2
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,209 @@
+package ldap_test
+
+import (
+	"backend/pkg/ldap"
+	"backend/pkg/ldap/ldaptest"
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const (
+	serviceDN = "cn=service,dc=example,dc=com"
+	aliceDN   = "uid=alice,ou=people,dc=example,dc=com"
+)
+
+func newDirectoryServer() *ldaptest.Server {
+	return ldaptest.NewServer(
+		ldaptest.Entry{DN: serviceDN, Password: "service-secret"},
+		ldaptest.Entry{
+			DN:         aliceDN,
+			Password:   "alice-secret",
+			Attributes: map[string][]string{"objectClass": {"person"}, "uid": {"alice"}, "cn": {"Alice Example"}},
+		},
+		ldaptest.Entry{
+			DN:         "uid=bob,ou=people,dc=example,dc=com",
+			Password:   "bob-secret",
+			Attributes: map[string][]string{"objectClass": {"person"}, "uid": {"bob"}},
+		},
+		ldaptest.Entry{
+			DN:         "cn=engineering,ou=groups,dc=example,dc=com",
+			Attributes: map[string][]string{"objectClass": {"groupOfNames"}, "cn": {"engineering"}, "member": {aliceDN}},
+		},
+		ldaptest.Entry{
+			DN:         "cn=ops,ou=groups,dc=example,dc=com",
+			Attributes: map[string][]string{"objectClass": {"posixGroup"}, "cn": {"ops"}, "memberUid": {"alice", "bob"}},
+		},
+	)
+}
+
+func newDirectory(server *ldaptest.Server) *ldap.Directory {
+	return ldap.NewDirectory(ldap.DirectoryConfig{
+		URL:          server.URL(),
+		BindDN:       serviceDN,
+		BindPassword: "service-secret",
+		UserBaseDN:   "ou=people,dc=example,dc=com",
+		GroupBaseDN:  "ou=groups,dc=example,dc=com",
+	})
+}
+
+func TestDirectoryAuthenticate(t *testing.T) {
+	server := newDirectoryServer()
+	defer server.Close()
+	directory := newDirectory(server)
+
+	account, err := directory.Authenticate(context.Background(), "ALICE", "alice-secret")
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if account.DN != aliceDN || account.Username != "alice" {
+		t.Errorf("account = %+v, want alice's entry", account)
+	}
+	sort.Strings(account.Groups)
+	if !reflect.DeepEqual(account.Groups, []string{"engineering", "ops"}) {
+		t.Errorf("groups = %v, want [engineering ops]", account.Groups)
+	}
+	if server.BindCount(aliceDN) != 1 {
+		t.Errorf("alice bound %d times, want 1", server.BindCount(aliceDN))
+	}
+}
+
+func TestDirectoryAuthenticateFailures(t *testing.T) {
+	server := newDirectoryServer()
+	defer server.Close()
+	directory := newDirectory(server)
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     error
+	}{
+		{"wrong password", "alice", "guess", ldap.ErrInvalidCredentials},
+		{"empty password", "alice", "", ldap.ErrInvalidCredentials},
+		{"unknown user", "carol", "alice-secret", ldap.ErrAccountNotFound},
+		{"filter injection", "*", "alice-secret", ldap.ErrAccountNotFound},
+		{"filter injection with parentheses", "alice)(uid=*", "alice-secret", ldap.ErrAccountNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := directory.Authenticate(context.Background(), tt.username, tt.password)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Authenticate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if server.BindCount(aliceDN) != 0 {
+		t.Errorf("alice bound %d times, want 0", server.BindCount(aliceDN))
+	}
+}
+
+func TestDirectoryRejectsAmbiguousUsers(t *testing.T) {
+	server := newDirectoryServer()
+	defer server.Close()
+	directory := ldap.NewDirectory(ldap.DirectoryConfig{
+		URL:          server.URL(),
+		BindDN:       serviceDN,
+		BindPassword: "service-secret",
+		UserBaseDN:   "ou=people,dc=example,dc=com",
+		UserFilter:   "(objectClass=person)",
+	})
+
+	if _, err := directory.Authenticate(context.Background(), "alice", "alice-secret"); !errors.Is(err, ldap.ErrAmbiguousAccount) {
+		t.Errorf("Authenticate() error = %v, want ErrAmbiguousAccount", err)
+	}
+}
+
+func TestDirectoryServiceAccountFailure(t *testing.T) {
+	server := newDirectoryServer()
+	defer server.Close()
+	directory := ldap.NewDirectory(ldap.DirectoryConfig{
+		URL:          server.URL(),
+		BindDN:       serviceDN,
+		BindPassword: "wrong",
+		UserBaseDN:   "ou=people,dc=example,dc=com",
+	})
+
+	// A misconfigured service account is an outage, not a wrong user password
+	_, err := directory.Authenticate(context.Background(), "alice", "alice-secret")
+	if err == nil || errors.Is(err, ldap.ErrAccountNotFound) {
+		t.Fatalf("Authenticate() error = %v, want a service bind failure", err)
+	}
+}
+
+func TestDirectoryLookupAccountTracksGroupChanges(t *testing.T) {
+	server := newDirectoryServer()
+	defer server.Close()
+	directory := newDirectory(server)
+
+	server.SetEntries(
+		ldaptest.Entry{DN: serviceDN, Password: "service-secret"},
+		ldaptest.Entry{DN: "uid=bob,ou=people,dc=example,dc=com", Attributes: map[string][]string{"uid": {"bob"}}},
+		ldaptest.Entry{
+			DN:         "cn=ops,ou=groups,dc=example,dc=com",
+			Attributes: map[string][]string{"cn": {"ops"}, "memberUid": {"bob"}},
+		},
+	)
+	account, err := directory.LookupAccount(context.Background(), "bob")
+	if err != nil {
+		t.Fatalf("LookupAccount() error = %v", err)
+	}
+	if !reflect.DeepEqual(account.Groups, []string{"ops"}) {
+		t.Errorf("groups = %v, want [ops]", account.Groups)
+	}
+	if _, err := directory.LookupAccount(context.Background(), "alice"); !errors.Is(err, ldap.ErrAccountNotFound) {
+		t.Errorf("LookupAccount(removed user) error = %v, want ErrAccountNotFound", err)
+	}
+}
+
+func TestCompileFilter(t *testing.T) {
+	valid := []string{
+		"(uid=alice)",
+		"uid=alice",
+		"(&(objectClass=person)(|(uid=alice)(mail=alice@*)))",
+		"(!(cn=*))",
+		"(cn=a*b*c)",
+		"(cn=\\2a)",
+		"(uidNumber>=1000)",
+	}
+	for _, filter := range valid {
+		if _, err := ldap.CompileFilter(filter); err != nil {
+			t.Errorf("CompileFilter(%q) error = %v", filter, err)
+		}
+	}
+
+	invalid := []string{"(uid=alice", "(&)", "(=alice)", "(uid=\\zz)", "(uid=alice))", "(&(uid=alice)"}
+	for _, filter := range invalid {
+		if _, err := ldap.CompileFilter(filter); err == nil {
+			t.Errorf("CompileFilter(%q) succeeded, want an error", filter)
+		}
+	}
+}
+
+func TestEscapeFilter(t *testing.T) {
+	got := ldap.EscapeFilter("a*b(c)\\d\x00")
+	want := "a\\2ab\\28c\\29\\5cd\\00"
+	if got != want {
+		t.Errorf("EscapeFilter() = %q, want %q", got, want)
+	}
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+	for _, value := range []int64{0, 1, 127, 128, 255, 256, -1, -128, -129, 1 << 40} {
+		packet, err := ldap.ParsePacket(ldap.NewInteger(value).Bytes())
+		if err != nil {
+			t.Fatalf("ParsePacket(%d) error = %v", value, err)
+		}
+		if got, _ := packet.Int(); got != value {
+			t.Errorf("integer round trip = %d, want %d", got, value)
+		}
+	}
+
+	long := make([]byte, 70000)
+	packet, err := ldap.ParsePacket(ldap.NewSequence(ldap.NewOctetString(string(long))).Bytes())
+	if err != nil || len(packet.Children) != 1 || len(packet.Children[0].Value) != len(long) {
+		t.Errorf("long octet string did not round trip: %v", err)
+	}
+}
@@ -0,0 +1,320 @@
+// Package ldaptest runs an in-process LDAP server for tests. It answers simple
+// binds and searches over a fixed set of entries, which is enough to exercise
+// directory authentication without a slapd container.
+package ldaptest
+
+import (
+	"backend/pkg/ldap"
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Entry is an object in the test directory. Entries with a Password can be bound to.
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+	Password   string
+}
+
+type Server struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	entries []Entry
+	binds   map[string]int
+	conns   map[net.Conn]bool
+
+	wg sync.WaitGroup
+}
+
+// NewServer listens on a local port and serves entries until Close
+func NewServer(entries ...Entry) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("ldaptest: failed to listen: " + err.Error())
+	}
+	s := &Server{listener: listener, entries: entries, binds: map[string]int{}, conns: map[net.Conn]bool{}}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// URL is the ldap:// address to configure the client with
+func (s *Server) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+// SetEntries replaces the directory's contents
+func (s *Server) SetEntries(entries ...Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = entries
+}
+
+// BindCount reports how many successful binds dn has made, so tests can check
+// which account the client authenticated as
+func (s *Server) BindCount(dn string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.binds[normalizeDN(dn)]
+}
+
+// Close stops the server, dropping any connections clients left open
+func (s *Server) Close() {
+	s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		message, err := ldap.ReadPacket(reader)
+		if err != nil || len(message.Children) < 2 {
+			return
+		}
+		id := message.Children[0]
+		op := message.Children[1]
+
+		var responses []*ldap.Packet
+		switch {
+		case op.Is(ldap.ClassApplication, ldap.ApplicationBindRequest):
+			responses = []*ldap.Packet{s.bind(op)}
+		case op.Is(ldap.ClassApplication, ldap.ApplicationSearchRequest):
+			responses = s.search(op)
+		case op.Is(ldap.ClassApplication, ldap.ApplicationUnbindRequest):
+			return
+		case op.Is(ldap.ClassApplication, ldap.ApplicationExtendedRequest):
+			responses = []*ldap.Packet{result(ldap.ApplicationExtendedResponse, ldap.ResultProtocolError, "extended operations are not supported")}
+		default:
+			return
+		}
+
+		for _, response := range responses {
+			if _, err := conn.Write(ldap.NewSequence(id, response).Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) bind(op *ldap.Packet) *ldap.Packet {
+	if len(op.Children) != 3 || op.Children[2].Tag != 0 {
+		return result(ldap.ApplicationBindResponse, ldap.ResultProtocolError, "only simple binds are supported")
+	}
+	dn := normalizeDN(op.Children[1].String())
+	password := op.Children[2].String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entry := range s.entries {
+		if normalizeDN(entry.DN) == dn && entry.Password != "" && entry.Password == password {
+			s.binds[dn]++
+			return result(ldap.ApplicationBindResponse, ldap.ResultSuccess, "")
+		}
+	}
+	return result(ldap.ApplicationBindResponse, ldap.ResultInvalidCredentials, "invalid credentials")
+}
+
+func (s *Server) search(op *ldap.Packet) []*ldap.Packet {
+	if len(op.Children) != 8 {
+		return []*ldap.Packet{result(ldap.ApplicationSearchResultDone, ldap.ResultProtocolError, "malformed search")}
+	}
+	base := normalizeDN(op.Children[0].String())
+	scope, _ := op.Children[1].Int()
+	sizeLimit, _ := op.Children[3].Int()
+	filter := op.Children[6]
+	var wanted []string
+	for _, attribute := range op.Children[7].Children {
+		wanted = append(wanted, attribute.String())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var responses []*ldap.Packet
+	for _, entry := range s.entries {
+		if !inScope(normalizeDN(entry.DN), base, scope) || !matches(filter, entry) {
+			continue
+		}
+		if sizeLimit > 0 && int64(len(responses)) == sizeLimit {
+			return append(responses, result(ldap.ApplicationSearchResultDone, ldap.ResultSizeLimitExceeded, ""))
+		}
+		responses = append(responses, searchEntry(entry, wanted))
+	}
+	return append(responses, result(ldap.ApplicationSearchResultDone, ldap.ResultSuccess, ""))
+}
+
+func searchEntry(entry Entry, wanted []string) *ldap.Packet {
+	attributes := ldap.NewSequence()
+	for name, values := range entry.Attributes {
+		if !requested(name, wanted) {
+			continue
+		}
+		set := ldap.NewConstructed(ldap.ClassUniversal, ldap.TagSet)
+		for _, value := range values {
+			set.Children = append(set.Children, ldap.NewOctetString(value))
+		}
+		attributes.Children = append(attributes.Children, ldap.NewSequence(ldap.NewOctetString(name), set))
+	}
+	return ldap.NewConstructed(ldap.ClassApplication, ldap.ApplicationSearchResultEntry, ldap.NewOctetString(entry.DN), attributes)
+}
+
+func requested(name string, wanted []string) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	for _, attribute := range wanted {
+		if attribute == "*" || strings.EqualFold(attribute, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func result(tag byte, code int64, message string) *ldap.Packet {
+	return ldap.NewConstructed(ldap.ClassApplication, tag,
+		ldap.NewEnumerated(code),
+		ldap.NewOctetString(""),
+		ldap.NewOctetString(message),
+	)
+}
+
+func inScope(dn string, base string, scope int64) bool {
+	switch scope {
+	case ldap.ScopeBaseObject:
+		return dn == base
+	case ldap.ScopeSingleLevel:
+		parent := ""
+		if comma := strings.IndexByte(dn, ','); comma >= 0 {
+			parent = dn[comma+1:]
+		}
+		return parent == base
+	}
+	return base == "" || dn == base || strings.HasSuffix(dn, ","+base)
+}
+
+// matches evaluates the filter choices the client can send. Comparisons ignore case,
+// as with the caseIgnoreMatch rule most directory attributes use.
+func matches(filter *ldap.Packet, entry Entry) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matches(child, entry) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matches(child, entry) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return len(filter.Children) == 1 && !matches(filter.Children[0], entry)
+	case ldap.FilterPresent:
+		return len(values(entry, filter.String())) > 0
+	case ldap.FilterEqualityMatch:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		want := filter.Children[1].String()
+		for _, value := range values(entry, filter.Children[0].String()) {
+			if equalValue(value, want) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterSubstrings:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		for _, value := range values(entry, filter.Children[0].String()) {
+			if matchSubstrings(strings.ToLower(value), filter.Children[1].Children) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func matchSubstrings(value string, parts []*ldap.Packet) bool {
+	for _, part := range parts {
+		text := strings.ToLower(part.String())
+		switch part.Tag {
+		case ldap.SubstringInitial:
+			if !strings.HasPrefix(value, text) {
+				return false
+			}
+			value = value[len(text):]
+		case ldap.SubstringAny:
+			index := strings.Index(value, text)
+			if index < 0 {
+				return false
+			}
+			value = value[index+len(text):]
+		case ldap.SubstringFinal:
+			if !strings.HasSuffix(value, text) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func values(entry Entry, attribute string) []string {
+	if strings.EqualFold(attribute, "dn") {
+		return []string{entry.DN}
+	}
+	for name, values := range entry.Attributes {
+		if strings.EqualFold(name, attribute) {
+			return values
+		}
+	}
+	return nil
+}
+
+// equalValue compares case-insensitively, treating DNs by their normal form
+func equalValue(a string, b string) bool {
+	return strings.EqualFold(a, b) || normalizeDN(a) == normalizeDN(b)
+}
+
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, part := range parts {
+		parts[i] = strings.ToLower(strings.TrimSpace(part))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,97 @@
+package middleware_test
+
+import (
+	"backend/pkg/apitoken"
+	"backend/pkg/middleware"
+	"backend/pkg/testutil"
+	"backend/pkg/utilities"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeAPITokens verifies a fixed set of tokens
+type fakeAPITokens struct {
+	tokens map[string]*utilities.APITokenClaims
+	fail   bool
+}
+
+func (f *fakeAPITokens) VerifyAPIToken(ctx context.Context, token string) (*utilities.APITokenClaims, error) {
+	if f.fail {
+		return nil, errors.New("database unavailable")
+	}
+	claims, ok := f.tokens[token]
+	if !ok {
+		return nil, utilities.ErrInvalidAPIToken
+	}
+	return claims, nil
+}
+
+func TestAPITokenAuthentication(t *testing.T) {
+	const readToken = apitoken.Prefix + "read"
+	const writeToken = apitoken.Prefix + "write"
+	verifier := &fakeAPITokens{tokens: map[string]*utilities.APITokenClaims{
+		readToken:  {TokenID: "token-1", UserID: "user123", Scopes: []string{"messages:read"}},
+		writeToken: {TokenID: "token-2", UserID: "user123", Scopes: []string{"messages:write"}},
+	}}
+	messages := "/api/workspaces/ws1/channels/ch1/messages"
+
+	testCases := []struct {
+		name       string
+		store      utilities.SessionStore
+		method     string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{name: "read scope reads", method: http.MethodGet, path: messages, token: readToken, wantStatus: http.StatusOK},
+		{name: "read scope cannot write", method: http.MethodPost, path: messages, token: readToken, wantStatus: http.StatusForbidden},
+		{name: "write scope writes", method: http.MethodPost, path: messages, token: writeToken, wantStatus: http.StatusOK},
+		{name: "write scope implies read", method: http.MethodGet, path: messages, token: writeToken, wantStatus: http.StatusOK},
+		{name: "other resource", method: http.MethodGet, path: "/api/workspaces/ws1", token: writeToken, wantStatus: http.StatusForbidden},
+		{name: "account routes are never reachable", method: http.MethodGet, path: "/api/user/tokens", token: writeToken, wantStatus: http.StatusForbidden},
+		{name: "admin routes are never reachable", method: http.MethodGet, path: "/api/admin/users", token: writeToken, wantStatus: http.StatusForbidden},
+		{name: "unknown token", method: http.MethodGet, path: messages, token: apitoken.Prefix + "unknown", wantStatus: http.StatusUnauthorized},
+		{name: "store without token support", store: testutil.NewMemorySessionStore(), method: http.MethodGet, path: messages, token: readToken, wantStatus: http.StatusUnauthorized},
+		{name: "verifier failure", store: utilities.WithAPITokens(testutil.NewMemorySessionStore(), &fakeAPITokens{fail: true}), method: http.MethodGet, path: messages, token: readToken, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			store := testCase.store
+			if store == nil {
+				store = utilities.WithAPITokens(testutil.NewMemorySessionStore(), verifier)
+			}
+
+			var gotUserID string
+			var gotScopes bool
+			handler := middleware.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserID, _ = utilities.GetUserID(r.Context())
+				_, gotScopes = utilities.GetAPITokenScopes(r.Context())
+				testutil.AssertEqualBool(t, utilities.IsInstanceAdmin(r.Context()), false)
+				w.WriteHeader(http.StatusOK)
+			}), middleware.TokenAuthMiddleware(store))
+
+			req := httptest.NewRequest(testCase.method, testCase.path, nil)
+			req.Header.Set("Authorization", "Bearer "+testCase.token)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			testutil.AssertStatusCode(t, recorder.Code, testCase.wantStatus)
+			if testCase.wantStatus == http.StatusOK {
+				testutil.AssertEqual(t, "user ID", gotUserID, "user123")
+				testutil.AssertEqualBool(t, gotScopes, true)
+			}
+		})
+	}
+}
+
+func TestSessionTokensStillWorkWithAPITokens(t *testing.T) {
+	sessions := testutil.NewMemorySessionStore()
+	store := utilities.WithAPITokens(sessions, &fakeAPITokens{})
+	device := login(t, store, "user123")
+
+	testutil.AssertStatusCode(t, authorizedStatus(store, device.accessToken), http.StatusOK)
+}
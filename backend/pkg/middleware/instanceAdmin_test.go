@@ -0,0 +1,51 @@
+package middleware_test
+
+import (
+	"backend/pkg/middleware"
+	"backend/pkg/testutil"
+	"backend/pkg/utilities"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInstanceAdminComesFromSession(t *testing.T) {
+	store := testutil.NewMemorySessionStore()
+	ctx := context.Background()
+
+	adminSession, err := utilities.StartAdminSession(ctx, store, "admin-account-1", "test-agent", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Failed to start admin session: %v", err)
+	}
+	adminToken, err := utilities.GenerateAccessToken(ctx, store, "admin-account-1", adminSession.ID)
+	if err != nil {
+		t.Fatalf("Failed to generate access token: %v", err)
+	}
+	// A user whose ID happens to be "admin" gets no special treatment
+	userToken := login(t, store, "admin").accessToken
+
+	testCases := []struct {
+		name        string
+		accessToken string
+		want        bool
+	}{
+		{name: "admin panel session", accessToken: adminToken, want: true},
+		{name: "user session", accessToken: userToken, want: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var got bool
+			handler := middleware.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = utilities.IsInstanceAdmin(r.Context())
+			}), middleware.TokenAuthMiddleware(store))
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+testCase.accessToken)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			testutil.AssertEqualBool(t, got, testCase.want)
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package middleware_test
+
+import (
+	"backend/internal/handlers"
+	"backend/pkg/middleware"
+	"backend/pkg/testutil"
+	"backend/pkg/utilities"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type allowAllLimiter struct{}
+
+func (allowAllLimiter) Allow(ctx context.Context, key string, window time.Duration) (bool, error) {
+	return true, nil
+}
+
+type deviceLogin struct {
+	sessionID    string
+	accessToken  string
+	refreshToken string
+}
+
+func login(t *testing.T, store utilities.SessionStore, userID string) deviceLogin {
+	t.Helper()
+	ctx := context.Background()
+	session, err := utilities.StartSession(ctx, store, userID, "test-agent", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Failed to start session: %v", err)
+	}
+	accessToken, err := utilities.GenerateAccessToken(ctx, store, userID, session.ID)
+	if err != nil {
+		t.Fatalf("Failed to generate access token: %v", err)
+	}
+	refreshToken, err := utilities.GenerateRefreshToken(ctx, store, userID, session.ID)
+	if err != nil {
+		t.Fatalf("Failed to generate refresh token: %v", err)
+	}
+	return deviceLogin{sessionID: session.ID, accessToken: accessToken, refreshToken: refreshToken}
+}
+
+func newSessionRouter(store utilities.SessionStore) *http.ServeMux {
+	mux := http.NewServeMux()
+	handlers.NewSessionHandler(store, allowAllLimiter{}).RegisterRoutes(mux)
+	return mux
+}
+
+// authorizedStatus runs an access token through TokenAuthMiddleware
+func authorizedStatus(store utilities.SessionStore, accessToken string) int {
+	handler := middleware.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), middleware.TokenAuthMiddleware(store))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+func postWithRefreshCookie(router http.Handler, path string, refreshToken string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	if refreshToken != "" {
+		req.AddCookie(&http.Cookie{Name: utilities.RefreshTokenCookieName, Value: refreshToken})
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func refreshCookie(t *testing.T, recorder *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == utilities.RefreshTokenCookieName {
+			return cookie
+		}
+	}
+	t.Fatalf("expected a %s cookie in the response", utilities.RefreshTokenCookieName)
+	return nil
+}
+
+func TestLogoutRevokesOnlyCurrentSession(t *testing.T) {
+	store := testutil.NewMemorySessionStore()
+	router := newSessionRouter(store)
+	laptop := login(t, store, "user123")
+	phone := login(t, store, "user123")
+
+	testutil.AssertStatusCode(t, authorizedStatus(store, laptop.accessToken), http.StatusOK)
+
+	recorder := postWithRefreshCookie(router, "/api/auth/logout", laptop.refreshToken)
+	testutil.AssertStatusCode(t, recorder.Code, http.StatusNoContent)
+
+	// The logged-out device can no longer authenticate or refresh
+	testutil.AssertStatusCode(t, authorizedStatus(store, laptop.accessToken), http.StatusUnauthorized)
+	refresh := postWithRefreshCookie(router, "/api/auth/refresh", laptop.refreshToken)
+	testutil.AssertStatusCode(t, refresh.Code, http.StatusUnauthorized)
+
+	// Other devices stay signed in
+	testutil.AssertStatusCode(t, authorizedStatus(store, phone.accessToken), http.StatusOK)
+	refresh = postWithRefreshCookie(router, "/api/auth/refresh", phone.refreshToken)
+	testutil.AssertStatusCode(t, refresh.Code, http.StatusOK)
+}
+
+func TestLogoutClearsCookie(t *testing.T) {
+	testCases := []struct {
+		name         string
+		dev          bool
+		withCookie   bool
+		wantSameSite http.SameSite
+	}{
+		{name: "valid session", withCookie: true, wantSameSite: http.SameSiteLaxMode},
+		{name: "valid session in dev mode", dev: true, withCookie: true, wantSameSite: http.SameSiteNoneMode},
+		{name: "no refresh cookie", withCookie: false, wantSameSite: http.SameSiteLaxMode},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if testCase.dev {
+				t.Setenv("DEV", "true")
+			} else {
+				t.Setenv("DEV", "")
+			}
+
+			store := testutil.NewMemorySessionStore()
+			router := newSessionRouter(store)
+			refreshToken := ""
+			if testCase.withCookie {
+				refreshToken = login(t, store, "user123").refreshToken
+			}
+
+			recorder := postWithRefreshCookie(router, "/api/auth/logout", refreshToken)
+			testutil.AssertStatusCode(t, recorder.Code, http.StatusNoContent)
+
+			cookie := refreshCookie(t, recorder)
+			testutil.AssertEqual(t, "value", cookie.Value, "")
+			testutil.AssertEqualBool(t, cookie.MaxAge < 0, true)
+			testutil.AssertEqualBool(t, cookie.HttpOnly, true)
+			testutil.AssertEqualBool(t, cookie.Secure, true)
+			testutil.AssertEqual(t, "path", cookie.Path, "/")
+			testutil.AssertEqual(t, "same site", cookie.SameSite, testCase.wantSameSite)
+		})
+	}
+}
+
+func TestLogoutWithStaleRefreshToken(t *testing.T) {
+	store := testutil.NewMemorySessionStore()
+	router := newSessionRouter(store)
+	device := login(t, store, "user123")
+
+	first := postWithRefreshCookie(router, "/api/auth/logout", device.refreshToken)
+	testutil.AssertStatusCode(t, first.Code, http.StatusNoContent)
+
+	// Logging out twice is harmless and still clears the cookie
+	second := postWithRefreshCookie(router, "/api/auth/logout", device.refreshToken)
+	testutil.AssertStatusCode(t, second.Code, http.StatusNoContent)
+	testutil.AssertEqualBool(t, refreshCookie(t, second).MaxAge < 0, true)
+}
+
+func TestLogoutRequiresPost(t *testing.T) {
+	router := newSessionRouter(testutil.NewMemorySessionStore())
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/logout", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	testutil.AssertStatusCode(t, recorder.Code, http.StatusMethodNotAllowed)
+}
+
+func TestRefreshRotatesCookie(t *testing.T) {
+	store := testutil.NewMemorySessionStore()
+	router := newSessionRouter(store)
+	device := login(t, store, "user123")
+
+	recorder := postWithRefreshCookie(router, "/api/auth/refresh", device.refreshToken)
+	testutil.AssertStatusCode(t, recorder.Code, http.StatusOK)
+	rotated := refreshCookie(t, recorder)
+	testutil.AssertEqualBool(t, rotated.Value != device.refreshToken, true)
+
+	// Replaying the old token revokes the session, including the rotated token
+	replay := postWithRefreshCookie(router, "/api/auth/refresh", device.refreshToken)
+	testutil.AssertStatusCode(t, replay.Code, http.StatusUnauthorized)
+	again := postWithRefreshCookie(router, "/api/auth/refresh", rotated.Value)
+	testutil.AssertStatusCode(t, again.Code, http.StatusUnauthorized)
+	testutil.AssertStatusCode(t, authorizedStatus(store, device.accessToken), http.StatusUnauthorized)
+}
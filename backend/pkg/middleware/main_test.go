@@ -0,0 +1,12 @@
+package middleware
+
+import (
+	"backend/pkg/testutil"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	testutil.InstallTestKeyRing()
+	os.Exit(m.Run())
+}
@@ -3,6 +3,9 @@ package middleware
 import (
 	"backend/pkg/utilities"
 	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
 )
 
 func PermissionMiddleware(permissionChecker *utilities.PermissionChecker, requiredPermissions ...string) Middleware {
@@ -14,16 +17,27 @@ func PermissionMiddleware(permissionChecker *utilities.PermissionChecker, requir
 				return
 			}
 
-			// Admin users bypass all permission checks
-			if userId == "admin" {
+			// Instance admins bypass all permission checks
+			if utilities.IsInstanceAdmin(r.Context()) {
 				next.ServeHTTP(w, r)
 				return
 			}
 
+			// Permissions only apply inside the workspace the request targets
+			workspaceID := WorkspaceIDFromRequest(r)
+			if workspaceID == "" {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			if _, err := uuid.Parse(workspaceID); err != nil {
+				http.Error(w, "Invalid workspace ID", http.StatusBadRequest)
+				return
+			}
+
 			// Check if the user has any of the required permissions
 			hasPermission := false
 			for _, permission := range requiredPermissions {
-				userHasPermission, err := permissionChecker.CheckUserPermission(r.Context(), userId, permission)
+				userHasPermission, err := permissionChecker.CheckUserPermission(r.Context(), userId, workspaceID, permission)
 				if err != nil {
 					http.Error(w, "Internal server error", http.StatusInternalServerError)
 					return
@@ -42,4 +56,19 @@ func PermissionMiddleware(permissionChecker *utilities.PermissionChecker, requir
 			next.ServeHTTP(w, r)
 		})
 	}
-}
\ No newline at end of file
+}
+
+// WorkspaceIDFromRequest returns the workspace a request targets, taken from the
+// {workspaceId} path value or, for prefix routes, the /api/workspaces/{id}/ segment.
+func WorkspaceIDFromRequest(r *http.Request) string {
+	if workspaceID := r.PathValue("workspaceId"); workspaceID != "" {
+		return workspaceID
+	}
+
+	rest, found := strings.CutPrefix(r.URL.Path, "/api/workspaces/")
+	if !found {
+		return ""
+	}
+	workspaceID, _, _ := strings.Cut(rest, "/")
+	return workspaceID
+}
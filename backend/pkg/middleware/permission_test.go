@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"backend/pkg/testutil"
+	"backend/pkg/utilities"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	workspaceA = "11111111-1111-1111-1111-111111111111"
+	workspaceB = "22222222-2222-2222-2222-222222222222"
+)
+
+// fakePermissionSource maps user -> workspace -> permissions granted by roles there
+type fakePermissionSource struct {
+	permissions map[string]map[string][]string
+	err         error
+}
+
+func (s *fakePermissionSource) GetUserPermissions(ctx context.Context, userID string, workspaceID string) ([]string, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.permissions[userID][workspaceID], nil
+}
+
+func TestPermissionMiddleware(t *testing.T) {
+	source := &fakePermissionSource{permissions: map[string]map[string][]string{
+		"moderator": {
+			workspaceA: {"workspace:manage-channels", "workspace:view-channels"},
+			workspaceB: {"workspace:view-channels"},
+		},
+		"member": {
+			workspaceA: {"workspace:view-channels"},
+		},
+	}}
+
+	testCases := []struct {
+		name           string
+		userID         string
+		path           string
+		required       []string
+		instanceAdmin  bool
+		source         utilities.PermissionSource
+		expectedStatus int
+	}{
+		{
+			name:           "permission held in the target workspace",
+			userID:         "moderator",
+			path:           "/api/workspaces/" + workspaceA + "/channels",
+			required:       []string{"workspace:manage-channels"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "permission from another workspace does not carry over",
+			userID:         "moderator",
+			path:           "/api/workspaces/" + workspaceB + "/channels",
+			required:       []string{"workspace:manage-channels"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "any of several permissions is enough",
+			userID:         "moderator",
+			path:           "/api/workspaces/" + workspaceB + "/channels",
+			required:       []string{"workspace:manage-channels", "workspace:view-channels"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "user without roles in the workspace",
+			userID:         "member",
+			path:           "/api/workspaces/" + workspaceB + "/channels",
+			required:       []string{"workspace:view-channels"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "workspace resolved from a prefix route",
+			userID:         "moderator",
+			path:           "/api/workspaces/" + workspaceB + "/user-roles",
+			required:       []string{"workspace:manage-channels"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "route without a workspace is denied",
+			userID:         "moderator",
+			path:           "/api/roles",
+			required:       []string{"workspace:manage-channels"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "malformed workspace ID",
+			userID:         "moderator",
+			path:           "/api/workspaces/not-a-uuid/channels",
+			required:       []string{"workspace:manage-channels"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "instance admin bypasses workspace permissions",
+			userID:         "33333333-3333-3333-3333-333333333333",
+			path:           "/api/workspaces/" + workspaceB + "/channels",
+			required:       []string{"workspace:manage-roles"},
+			instanceAdmin:  true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "user ID admin is not an instance admin",
+			userID:         "admin",
+			path:           "/api/workspaces/" + workspaceB + "/channels",
+			required:       []string{"workspace:manage-roles"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "unauthenticated request",
+			userID:         "",
+			path:           "/api/workspaces/" + workspaceA + "/channels",
+			required:       []string{"workspace:view-channels"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "permission lookup failure",
+			userID:         "moderator",
+			path:           "/api/workspaces/" + workspaceA + "/channels",
+			required:       []string{"workspace:view-channels"},
+			source:         &fakePermissionSource{err: errors.New("db down")},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var permissionSource utilities.PermissionSource = source
+			if testCase.source != nil {
+				permissionSource = testCase.source
+			}
+			checker := utilities.NewPermissionChecker(permissionSource)
+
+			handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}), PermissionMiddleware(checker, testCase.required...))
+
+			mux := http.NewServeMux()
+			mux.Handle("/api/workspaces/{workspaceId}/channels", handler)
+			mux.Handle("/api/workspaces/", handler)
+			mux.Handle("/api/roles", handler)
+
+			req := httptest.NewRequest(http.MethodGet, testCase.path, nil)
+			if testCase.userID != "" {
+				req = req.WithContext(utilities.WithUserID(req.Context(), testCase.userID))
+			}
+			if testCase.instanceAdmin {
+				req = req.WithContext(utilities.WithInstanceAdmin(req.Context(), true))
+			}
+			recorder := httptest.NewRecorder()
+			mux.ServeHTTP(recorder, req)
+
+			testutil.AssertStatusCode(t, recorder.Code, testCase.expectedStatus)
+		})
+	}
+}
+
+func TestPermissionCheckerIsWorkspaceScoped(t *testing.T) {
+	checker := utilities.NewPermissionChecker(&fakePermissionSource{permissions: map[string]map[string][]string{
+		"moderator": {workspaceA: {"workspace:manage-channels"}},
+	}})
+
+	testCases := []struct {
+		name        string
+		workspaceID string
+		want        bool
+	}{
+		{name: "own workspace", workspaceID: workspaceA, want: true},
+		{name: "other workspace", workspaceID: workspaceB, want: false},
+		{name: "no workspace", workspaceID: "", want: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			got, err := checker.CheckUserPermission(context.Background(), "moderator", testCase.workspaceID, "workspace:manage-channels")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			testutil.AssertEqualBool(t, got, testCase.want)
+		})
+	}
+}
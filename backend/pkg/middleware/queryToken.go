@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// QueryAccessTokenMiddleware lets clients that cannot set headers, such as
+// browser WebSockets, pass the access token as the access_token query parameter.
+// It must run before TokenAuthMiddleware.
+func QueryAccessTokenMiddleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				if token := r.URL.Query().Get("access_token"); token != "" {
+					r = r.Clone(r.Context())
+					r.Header.Set("Authorization", "Bearer "+token)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,9 +2,9 @@ package middleware
 
 import (
 	"backend/pkg/ratelimiter"
+	"backend/pkg/utilities"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 )
 
@@ -36,10 +36,7 @@ func RateLimitMiddleware(limiter ratelimiter.RateLimiter, window time.Duration,
 }
 
 func getUserKey(r *http.Request) string {
-	// Use the client's IP address as the key
-	ip := r.RemoteAddr
-	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-		ip = strings.Split(forwarded, ",")[0]
-	}
-	return ip
+	// Use the client's IP address as the key. X-Forwarded-For only counts when it
+	// comes from a trusted proxy, so clients cannot pick a fresh bucket per request.
+	return utilities.ClientIP(r)
 }
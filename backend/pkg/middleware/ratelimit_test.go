@@ -108,3 +108,33 @@ func TestRateLimitMiddleware(t *testing.T) {
 		})
 	}
 }
+
+// keyRecordingLimiter allows everything and remembers the keys it was asked about
+type keyRecordingLimiter struct {
+	keys []string
+}
+
+func (l *keyRecordingLimiter) Allow(ctx context.Context, key string, window time.Duration) (bool, error) {
+	l.keys = append(l.keys, key)
+	return true, nil
+}
+
+func TestRateLimitKeyIgnoresUntrustedForwardedFor(t *testing.T) {
+	limiter := &keyRecordingLimiter{}
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		RateLimitMiddleware(limiter, time.Minute, "test_context"))
+
+	// One peer on fresh connections, claiming a different address each time
+	for i, forwarded := range []string{"", "198.51.100.1", "198.51.100.2, 10.0.0.1"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = fmt.Sprintf("203.0.113.7:%d", 40000+i)
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	for _, key := range limiter.keys {
+		assertEqual(t, "rate limit key", key, "rate_limit:test_context:203.0.113.7")
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"backend/pkg/apitoken"
 	"backend/pkg/utilities"
 	"errors"
 	"fmt"
@@ -10,6 +11,10 @@ import (
 
 const UserIDKey utilities.ContextKey = "userID"
 
+// TokenAuthMiddleware requires a valid access token whose session is still active.
+// Expired access tokens are rejected with 401; clients renew them through POST /api/auth/refresh.
+// When the store was wrapped with utilities.WithAPITokens, personal access tokens are
+// accepted too, limited to routes their scopes cover.
 func TokenAuthMiddleware(store utilities.SessionStore) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -22,35 +27,14 @@ func TokenAuthMiddleware(store utilities.SessionStore) Middleware {
 			}
 			accessToken := strings.TrimPrefix(authHeader, "Bearer ")
 
-			// Extract refresh token from cookies
-			cookie, err := r.Cookie(utilities.RefreshTokenCookieName)
-			if err != nil {
-				fmt.Println("No refresh token found")
-				if errors.Is(err, http.ErrNoCookie) {
-					http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
-				} else {
-					http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
-				}
-				return
-			}
-			refreshToken := cookie.Value
-
-			// First try to validate the access token
-			accessUserID, err := utilities.ValidateAccessToken(r.Context(), store, accessToken)
-			if err == nil {
-				// Access token is valid, proceed with the request
-				ctx := r.Context()
-				ctx = utilities.WithUserID(ctx, accessUserID)
-				r = r.WithContext(ctx)
-				next.ServeHTTP(w, r)
+			if apitoken.IsToken(accessToken) {
+				serveWithAPIToken(w, r, next, store, accessToken)
 				return
 			}
 
-			// Access token is invalid, try to validate refresh token
-			refreshUserID, _, err := utilities.ValidateRefreshToken(r.Context(), store, refreshToken)
+			claims, err := utilities.ValidateAccessToken(r.Context(), store, accessToken)
 			if err != nil {
-				fmt.Println("Refresh token invalid:", err)
-				errorMsg := `{"error": "Session expired, please log in again"}`
+				errorMsg := `{"error": "Unauthorized"}`
 				statusCode := http.StatusUnauthorized
 
 				if errors.Is(err, utilities.ErrRedisLookupFailed) {
@@ -60,22 +44,11 @@ func TokenAuthMiddleware(store utilities.SessionStore) Middleware {
 				http.Error(w, errorMsg, statusCode)
 				return
 			}
-			fmt.Println("Resfresh token: ", refreshToken)
-			// Refresh token is valid, generate new access token
-			fmt.Println("Generating new access token for user:", refreshUserID)
-			newAccessToken, err := utilities.GenerateAccessToken(r.Context(), store, refreshUserID)
-			if err != nil {
-				fmt.Println("Failed to generate new access token:", err)
-				http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
-				return
-			}
 
-			// Set the new access token in the response header
-			w.Header().Set("X-New-Access-Token", newAccessToken)
-
-			// Update the user ID for the rest of the middleware
 			ctx := r.Context()
-			ctx = utilities.WithUserID(ctx, refreshUserID)
+			ctx = utilities.WithUserID(ctx, claims.Subject)
+			ctx = utilities.WithSessionID(ctx, claims.SessionID)
+			ctx = utilities.WithInstanceAdmin(ctx, claims.InstanceAdmin)
 			r = r.WithContext(ctx)
 
 			// Call the next handler
@@ -83,3 +56,33 @@ func TokenAuthMiddleware(store utilities.SessionStore) Middleware {
 		})
 	}
 }
+
+func serveWithAPIToken(w http.ResponseWriter, r *http.Request, next http.Handler, store utilities.SessionStore, token string) {
+	verifier, ok := store.(utilities.APITokenVerifier)
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	claims, err := verifier.VerifyAPIToken(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, utilities.ErrInvalidAPIToken) {
+			http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		fmt.Println("Failed to verify API token:", err)
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	required, ok := apitoken.RequiredScope(r.Method, r.URL.Path)
+	if !ok || !apitoken.Allows(claims.Scopes, required) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+required+`"`)
+		http.Error(w, `{"error": "Token does not have the required scope"}`, http.StatusForbidden)
+		return
+	}
+
+	// Tokens have no device session and never carry instance admin rights
+	ctx := utilities.WithUserID(r.Context(), claims.UserID)
+	ctx = utilities.WithAPITokenScopes(ctx, claims.Scopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
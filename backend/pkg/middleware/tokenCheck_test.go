@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 type fakeStore struct {
@@ -18,26 +19,48 @@ type fakeStore struct {
 	revokedSessions    map[string]bool
 }
 
-func (s *fakeStore) GetSessionStatus(ctx context.Context, userId string) (string, error) {
+func (s *fakeStore) CreateSession(ctx context.Context, session utilities.Session, ttlSeconds int) error {
+	return nil
+}
+
+func (s *fakeStore) GetSession(ctx context.Context, sessionId string) (*utilities.Session, error) {
 	if s.failRedis {
-		return "", errors.New(customErrors.ErrRedisLookupFailed)
+		return nil, errors.New(customErrors.ErrRedisLookupFailed)
 	}
-	return "active", nil
+	session := &utilities.Session{ID: sessionId, UserID: "user123", LastSeenAt: time.Now()}
+	for jti, storedSessionId := range s.validRefreshTokens {
+		if storedSessionId == sessionId {
+			session.RefreshJti = jti
+		}
+	}
+	return session, nil
+}
+
+func (s *fakeStore) TouchSession(ctx context.Context, sessionId string, seenAt time.Time) error {
+	return nil
 }
 
-func (s *fakeStore) GetRefreshTokenUser(ctx context.Context, jti string) (string, error) {
-	return "user123", nil
+func (s *fakeStore) ListSessions(ctx context.Context, userId string) ([]utilities.Session, error) {
+	return nil, nil
 }
 
-func (s *fakeStore) StoreSession(ctx context.Context, userId string, ttlSeconds int) (string, error) {
+func (s *fakeStore) GetRefreshTokenSession(ctx context.Context, jti string) (string, error) {
 	return "session123", nil
 }
 
-func (s *fakeStore) StoreRefreshToken(ctx context.Context, jti string, userId string, ttlSeconds int) error {
+func (s *fakeStore) StoreRefreshToken(ctx context.Context, jti string, sessionId string, ttlSeconds int) error {
+	s.validRefreshTokens[jti] = sessionId
 	return nil
 }
 
-func (s *fakeStore) RevokeSession(ctx context.Context, userId string, jti string) error {
+func (s *fakeStore) RotateRefreshToken(ctx context.Context, sessionId string, userId string, oldJti string, newJti string, ttlSeconds int) (bool, error) {
+	delete(s.validRefreshTokens, oldJti)
+	s.validRefreshTokens[newJti] = sessionId
+	return true, nil
+}
+
+func (s *fakeStore) RevokeSession(ctx context.Context, sessionId string) error {
+	s.revokedSessions[sessionId] = true
 	return nil
 }
 
@@ -59,11 +82,11 @@ func TestTokenAuthMiddleware(t *testing.T) {
 				revokedSessions:    make(map[string]bool),
 			},
 			setupRequest: func(req *http.Request, store *fakeStore) {
-				accessToken, err := utilities.GenerateAccessToken(context.Background(), store, "user123")
+				accessToken, err := utilities.GenerateAccessToken(context.Background(), store, "user123", "session123")
 				if err != nil {
 					t.Fatalf("Failed to generate access token: %v", err)
 				}
-				refreshToken, err := utilities.GenerateRefreshToken(context.Background(), store, "user123")
+				refreshToken, err := utilities.GenerateRefreshToken(context.Background(), store, "user123", "session123")
 				if err != nil {
 					t.Fatalf("Failed to generate refresh token: %v", err)
 				}
@@ -73,15 +96,15 @@ func TestTokenAuthMiddleware(t *testing.T) {
 		},
 		{
 			name:            "invalid access token with valid refresh token",
-			expectedStatus:  http.StatusOK,
-			expectNewAccess: true,
+			expectedStatus:  http.StatusUnauthorized,
+			expectNewAccess: false,
 			fakeStore: &fakeStore{
 				validAccessTokens:  make(map[string]string),
 				validRefreshTokens: make(map[string]string),
 				revokedSessions:    make(map[string]bool),
 			},
 			setupRequest: func(req *http.Request, store *fakeStore) {
-				refreshToken, err := utilities.GenerateRefreshToken(context.Background(), store, "user123")
+				refreshToken, err := utilities.GenerateRefreshToken(context.Background(), store, "user123", "session123")
 				if err != nil {
 					t.Fatalf("Failed to generate refresh token: %v", err)
 				}
@@ -113,7 +136,7 @@ func TestTokenAuthMiddleware(t *testing.T) {
 				revokedSessions:    make(map[string]bool),
 			},
 			setupRequest: func(req *http.Request, store *fakeStore) {
-				accessToken, err := utilities.GenerateAccessToken(context.Background(), store, "user123")
+				accessToken, err := utilities.GenerateAccessToken(context.Background(), store, "user123", "session123")
 				if err != nil {
 					t.Fatalf("Failed to generate access token: %v", err)
 				}
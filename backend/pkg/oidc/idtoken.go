@@ -0,0 +1,103 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// clockSkew tolerates small clock differences between us and the provider
+const clockSkew = time.Minute
+
+var ErrInvalidIDToken = errors.New("oidc: invalid ID token")
+
+// signingMethods are the asymmetric algorithms ID tokens may be signed with.
+// Symmetric HS* tokens would be verifiable with the client secret, which we do
+// not accept, and "none" is never allowed.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// IDToken holds the verified claims about the signed-in user
+type IDToken struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+	Groups            []string
+	Expiry            time.Time
+}
+
+// VerifyIDToken checks the token's signature, issuer, audience, expiry and nonce
+func (p *Provider) VerifyIDToken(ctx context.Context, raw string, nonce string) (*IDToken, error) {
+	if _, err := p.Metadata(ctx); err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.keys.key(ctx, kid)
+	},
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(p.config.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	// The nonce ties the token to the login this browser started
+	tokenNonce, _ := claims["nonce"].(string)
+	if subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	// OpenID Connect Core 3.1.3.7: with several audiences, azp must name us
+	audience, _ := claims.GetAudience()
+	if azp, ok := claims["azp"].(string); (ok || len(audience) > 1) && azp != p.config.ClientID {
+		return nil, fmt.Errorf("%w: authorized party mismatch", ErrInvalidIDToken)
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	expiry, _ := claims.GetExpirationTime()
+
+	token := &IDToken{
+		Issuer:  p.config.Issuer,
+		Subject: subject,
+		Expiry:  expiry.Time,
+		Groups:  stringList(claims[p.config.GroupsClaim]),
+	}
+	token.Email, _ = claims["email"].(string)
+	token.EmailVerified, _ = claims["email_verified"].(bool)
+	token.PreferredUsername, _ = claims["preferred_username"].(string)
+	token.Name, _ = claims["name"].(string)
+	return token, nil
+}
+
+// stringList reads a claim that providers send either as a list or a single string
+func stringList(claim any) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []any:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
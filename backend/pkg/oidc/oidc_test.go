@@ -0,0 +1,203 @@
+package oidc_test
+
+import (
+	"backend/pkg/oidc"
+	"backend/pkg/oidc/oidctest"
+	"context"
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const redirectURL = "http://localhost:8080/api/auth/oidc/callback"
+
+func newProvider(server *oidctest.Server) *oidc.Provider {
+	return oidc.NewProvider(oidc.Config{
+		Issuer:       server.Issuer(),
+		ClientID:     server.ClientID,
+		ClientSecret: server.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"profile", "email"},
+	}, nil)
+}
+
+// login runs the browser side of the flow and returns the callback query
+func login(t *testing.T, server *oidctest.Server, provider *oidc.Provider, req *oidc.AuthRequest) url.Values {
+	t.Helper()
+	authURL, err := provider.AuthCodeURL(context.Background(), req)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	callback, err := server.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	return callback.Query()
+}
+
+func TestAuthorizationCodeFlowWithPKCE(t *testing.T) {
+	server := oidctest.NewServer()
+	defer server.Close()
+	server.SetUser(oidctest.User{Subject: "abc123", PreferredUsername: "alice", Groups: []string{"engineering", "ops"}})
+	provider := newProvider(server)
+
+	req, err := oidc.NewAuthRequest()
+	if err != nil {
+		t.Fatalf("NewAuthRequest() error = %v", err)
+	}
+	callback := login(t, server, provider, req)
+	if callback.Get("state") != req.State {
+		t.Fatalf("state = %q, want %q", callback.Get("state"), req.State)
+	}
+
+	token, err := provider.Exchange(context.Background(), callback.Get("code"), req)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if token.Subject != "abc123" || token.PreferredUsername != "alice" {
+		t.Errorf("token = %+v, want subject abc123 and username alice", token)
+	}
+	if !reflect.DeepEqual(token.Groups, []string{"engineering", "ops"}) {
+		t.Errorf("groups = %v, want [engineering ops]", token.Groups)
+	}
+
+	// Codes cannot be redeemed twice
+	if _, err := provider.Exchange(context.Background(), callback.Get("code"), req); !errors.Is(err, oidc.ErrTokenExchange) {
+		t.Errorf("second Exchange() error = %v, want ErrTokenExchange", err)
+	}
+}
+
+func TestExchangeRejectsWrongCodeVerifier(t *testing.T) {
+	server := oidctest.NewServer()
+	defer server.Close()
+	provider := newProvider(server)
+
+	req, _ := oidc.NewAuthRequest()
+	callback := login(t, server, provider, req)
+
+	stolen := *req
+	stolen.CodeVerifier = "not-the-verifier"
+	if _, err := provider.Exchange(context.Background(), callback.Get("code"), &stolen); !errors.Is(err, oidc.ErrTokenExchange) {
+		t.Errorf("Exchange() error = %v, want ErrTokenExchange", err)
+	}
+}
+
+func TestExchangeRejectsNonceMismatch(t *testing.T) {
+	server := oidctest.NewServer()
+	defer server.Close()
+	provider := newProvider(server)
+
+	req, _ := oidc.NewAuthRequest()
+	callback := login(t, server, provider, req)
+
+	replayed := *req
+	replayed.Nonce = "other-nonce"
+	if _, err := provider.Exchange(context.Background(), callback.Get("code"), &replayed); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Errorf("Exchange() error = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestVerifyIDTokenRejectsBadClaims(t *testing.T) {
+	server := oidctest.NewServer()
+	defer server.Close()
+	provider := newProvider(server)
+	now := time.Now()
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   server.Issuer(),
+			"sub":   "abc123",
+			"aud":   server.ClientID,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Minute).Unix(),
+			"nonce": "n",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+	}{
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "someone-else" }},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Hour).Unix() }},
+		{"no expiry", func(c jwt.MapClaims) { delete(c, "exp") }},
+		{"no subject", func(c jwt.MapClaims) { delete(c, "sub") }},
+		{"other authorized party", func(c jwt.MapClaims) {
+			c["aud"] = []string{server.ClientID, "other"}
+			c["azp"] = "other"
+		}},
+	}
+
+	if _, err := provider.VerifyIDToken(context.Background(), server.SignIDToken(valid()), "n"); err != nil {
+		t.Fatalf("VerifyIDToken(valid) error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(claims)
+			_, err := provider.VerifyIDToken(context.Background(), server.SignIDToken(claims), "n")
+			if !errors.Is(err, oidc.ErrInvalidIDToken) {
+				t.Errorf("VerifyIDToken() error = %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRejectsUnsignedTokens(t *testing.T) {
+	server := oidctest.NewServer()
+	defer server.Close()
+	provider := newProvider(server)
+
+	claims := jwt.MapClaims{
+		"iss": server.Issuer(), "sub": "abc123", "aud": server.ClientID,
+		"exp": time.Now().Add(time.Minute).Unix(), "nonce": "n",
+	}
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.VerifyIDToken(context.Background(), unsigned, "n"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Errorf("VerifyIDToken() error = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	server := oidctest.NewServer()
+	defer server.Close()
+
+	provider := oidc.NewProvider(oidc.Config{Issuer: server.Issuer() + "/other", ClientID: "barbedwork"}, nil)
+	if _, err := provider.Metadata(context.Background()); !errors.Is(err, oidc.ErrDiscovery) {
+		t.Errorf("Metadata() error = %v, want ErrDiscovery", err)
+	}
+}
+
+func TestTrailingSlashIssuerIsKeptVerbatim(t *testing.T) {
+	server := oidctest.NewServer()
+	defer server.Close()
+	server.SetIssuer(server.URL + "/")
+
+	provider := newProvider(server)
+	req, err := oidc.NewAuthRequest()
+	if err != nil {
+		t.Fatalf("NewAuthRequest() error = %v", err)
+	}
+	callback := login(t, server, provider, req)
+	token, err := provider.Exchange(context.Background(), callback.Get("code"), req)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if token.Issuer != server.URL+"/" {
+		t.Errorf("token issuer = %q, want %q", token.Issuer, server.URL+"/")
+	}
+
+	// The same provider configured without the slash does not match it
+	provider = oidc.NewProvider(oidc.Config{Issuer: server.URL, ClientID: server.ClientID}, nil)
+	if _, err := provider.Metadata(context.Background()); !errors.Is(err, oidc.ErrDiscovery) {
+		t.Errorf("Metadata() error = %v, want ErrDiscovery", err)
+	}
+}
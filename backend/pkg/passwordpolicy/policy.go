@@ -0,0 +1,246 @@
+// Package passwordpolicy checks new passwords against a configurable policy:
+// a minimum length, an estimated entropy and a blocklist of common passwords.
+package passwordpolicy
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	DefaultMinLength      = 10
+	DefaultMinEntropyBits = 40
+	// MaxLength is bcrypt's input limit; longer passwords would be silently truncated
+	MaxLength = 72
+)
+
+// Violation codes, stable for the frontend to translate
+const (
+	CodeTooShort         = "too_short"
+	CodeTooLong          = "too_long"
+	CodeTooWeak          = "too_weak"
+	CodeCommon           = "common"
+	CodeContainsUsername = "contains_username"
+)
+
+//go:embed common-passwords.txt
+var defaultBlocklist string
+
+// Violation is one rule a password broke
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every rule a password broke so they can all be shown at once
+type ValidationError struct {
+	Violations []Violation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return "password does not meet the policy: " + strings.Join(messages, "; ")
+}
+
+type Policy struct {
+	MinLength      int
+	MinEntropyBits float64
+	blocklist      map[string]struct{}
+}
+
+// New builds a policy. Blocklist entries are matched case-insensitively.
+func New(minLength int, minEntropyBits float64, blocklist []string) *Policy {
+	policy := &Policy{
+		MinLength:      minLength,
+		MinEntropyBits: minEntropyBits,
+		blocklist:      make(map[string]struct{}, len(blocklist)),
+	}
+	for _, entry := range blocklist {
+		policy.blocklist[strings.ToLower(entry)] = struct{}{}
+	}
+	return policy
+}
+
+// WithMinLength returns a copy of the policy requiring at least minLength characters,
+// for accounts that warrant stricter rules than the instance default
+func (p *Policy) WithMinLength(minLength int) *Policy {
+	stricter := *p
+	if minLength > stricter.MinLength {
+		stricter.MinLength = minLength
+	}
+	return &stricter
+}
+
+// FromEnv builds the policy from PASSWORD_MIN_LENGTH, PASSWORD_MIN_ENTROPY_BITS and
+// PASSWORD_BLOCKLIST_FILE, falling back to the defaults and the built-in blocklist
+func FromEnv() (*Policy, error) {
+	minLength := DefaultMinLength
+	if value := os.Getenv("PASSWORD_MIN_LENGTH"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > MaxLength {
+			return nil, fmt.Errorf("PASSWORD_MIN_LENGTH must be between 1 and %d", MaxLength)
+		}
+		minLength = parsed
+	}
+
+	minEntropy := float64(DefaultMinEntropyBits)
+	if value := os.Getenv("PASSWORD_MIN_ENTROPY_BITS"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("PASSWORD_MIN_ENTROPY_BITS must be a non-negative number")
+		}
+		minEntropy = parsed
+	}
+
+	blocklist, err := ReadBlocklist(strings.NewReader(defaultBlocklist))
+	if err != nil {
+		return nil, err
+	}
+	if path := os.Getenv("PASSWORD_BLOCKLIST_FILE"); path != "" {
+		blocklist, err = LoadBlocklist(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return New(minLength, minEntropy, blocklist), nil
+}
+
+// LoadBlocklist reads a blocklist file with one password per line
+func LoadBlocklist(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open password blocklist: %w", err)
+	}
+	defer file.Close()
+	return ReadBlocklist(file)
+}
+
+// ReadBlocklist reads one password per line, skipping blank lines and # comments
+func ReadBlocklist(r io.Reader) ([]string, error) {
+	var entries []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read password blocklist: %w", err)
+	}
+	return entries, nil
+}
+
+// Validate returns a *ValidationError listing every rule the password breaks.
+// userInputs, such as the username, must not appear in the password.
+func (p *Policy) Validate(password string, userInputs ...string) error {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, Violation{
+			Code:    CodeTooShort,
+			Message: fmt.Sprintf("Password must be at least %d characters", p.MinLength),
+		})
+	}
+	if len(password) > MaxLength {
+		violations = append(violations, Violation{
+			Code:    CodeTooLong,
+			Message: fmt.Sprintf("Password must be at most %d bytes", MaxLength),
+		})
+	}
+
+	lowered := strings.ToLower(password)
+	if _, blocked := p.blocklist[lowered]; blocked {
+		violations = append(violations, Violation{
+			Code:    CodeCommon,
+			Message: "Password is too common",
+		})
+	}
+
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if len(input) >= 3 && strings.Contains(lowered, input) {
+			violations = append(violations, Violation{
+				Code:    CodeContainsUsername,
+				Message: "Password must not contain your username",
+			})
+			break
+		}
+	}
+
+	// Short passwords already fail; only judge the strength of long enough ones
+	if length >= p.MinLength && EstimateEntropy(password) < p.MinEntropyBits {
+		violations = append(violations, Violation{
+			Code:    CodeTooWeak,
+			Message: "Password is too predictable; use more varied characters or a longer passphrase",
+		})
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// EstimateEntropy gives a rough strength estimate in bits. Each character is worth
+// log2 of the size of the character classes used, except characters that repeat or
+// continue a sequence from the previous one (aaa, abc, 321), which are worth one bit.
+func EstimateEntropy(password string) float64 {
+	pool := 0
+	var hasLower, hasUpper, hasDigit, hasSymbol, hasOther bool
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			hasLower = true
+		case r >= 'A' && r <= 'Z':
+			hasUpper = true
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			hasSymbol = true
+		default:
+			hasOther = true
+		}
+	}
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{hasLower, 26}, {hasUpper, 26}, {hasDigit, 10}, {hasSymbol, 33}, {hasOther, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	bitsPerChar := math.Log2(float64(pool))
+	entropy := 0.0
+	previous, step := rune(-1), rune(0)
+	for _, r := range password {
+		diff := r - previous
+		repeated := previous >= 0 && diff == 0
+		sequential := previous >= 0 && diff == step && (diff == 1 || diff == -1)
+		if repeated || sequential {
+			entropy++
+		} else {
+			entropy += bitsPerChar
+		}
+		previous, step = r, diff
+	}
+	return entropy
+}
@@ -0,0 +1,82 @@
+package passwordpolicy_test
+
+import (
+	"backend/pkg/passwordpolicy"
+	"backend/pkg/testutil"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	policy := passwordpolicy.New(10, 40, []string{"password123", "CorrectHorse"})
+
+	testCases := []struct {
+		name      string
+		password  string
+		username  string
+		wantCodes []string
+	}{
+		{name: "strong passphrase", password: "violet-anchor-gravel-79", username: "alice"},
+		{name: "empty", password: "", wantCodes: []string{passwordpolicy.CodeTooShort}},
+		{name: "too short", password: "Xk9#q", wantCodes: []string{passwordpolicy.CodeTooShort}},
+		{name: "too long for bcrypt", password: strings.Repeat("Xk9#qLm2$v", 8), wantCodes: []string{passwordpolicy.CodeTooLong}},
+		{name: "blocklisted", password: "password123", wantCodes: []string{passwordpolicy.CodeCommon}},
+		{name: "blocklist is case-insensitive", password: "correcthorse", wantCodes: []string{passwordpolicy.CodeCommon}},
+		{name: "repeated characters", password: "aaaaaaaaaaaa", wantCodes: []string{passwordpolicy.CodeTooWeak}},
+		{name: "sequence", password: "1234567890", wantCodes: []string{passwordpolicy.CodeTooWeak}},
+		{name: "contains username", password: "Alice-river-stone-42", username: "alice", wantCodes: []string{passwordpolicy.CodeContainsUsername}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := policy.Validate(testCase.password, testCase.username)
+			if len(testCase.wantCodes) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var validationErr *passwordpolicy.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate() error = %v, want a *ValidationError", err)
+			}
+			codes := make([]string, len(validationErr.Violations))
+			for i, violation := range validationErr.Violations {
+				codes[i] = violation.Code
+			}
+			testutil.AssertEqual(t, "codes", strings.Join(codes, ","), strings.Join(testCase.wantCodes, ","))
+		})
+	}
+}
+
+func TestEstimateEntropyPenalisesPatterns(t *testing.T) {
+	varied := passwordpolicy.EstimateEntropy("qzmtrbxk")
+	repeated := passwordpolicy.EstimateEntropy("qqqqqqqq")
+	sequential := passwordpolicy.EstimateEntropy("abcdefgh")
+
+	testutil.AssertEqualBool(t, repeated < varied, true)
+	testutil.AssertEqualBool(t, sequential < varied, true)
+}
+
+func TestReadBlocklist(t *testing.T) {
+	entries, err := passwordpolicy.ReadBlocklist(strings.NewReader("# comment\n\nhunter2\n  letmein  \n"))
+	if err != nil {
+		t.Fatalf("ReadBlocklist() error = %v", err)
+	}
+	testutil.AssertEqual(t, "entries", strings.Join(entries, ","), "hunter2,letmein")
+}
+
+func TestFromEnvUsesBuiltInBlocklist(t *testing.T) {
+	t.Setenv("PASSWORD_BLOCKLIST_FILE", "")
+	policy, err := passwordpolicy.FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv() error = %v", err)
+	}
+
+	var validationErr *passwordpolicy.ValidationError
+	if !errors.As(policy.Validate("qwertyuiop123"), &validationErr) {
+		t.Fatal("expected a common password to be rejected")
+	}
+}
@@ -0,0 +1,112 @@
+package ratelimiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Lockout counts failed attempts per key and blocks the key for a while once
+// too many failures happen within the window. Unlike RateLimiter, successful
+// attempts do not count towards the limit.
+type Lockout interface {
+	// Check returns how long the key is still locked out for, or zero
+	Check(ctx context.Context, key string) (time.Duration, error)
+	// Fail records a failed attempt and returns the lockout it triggered, if any
+	Fail(ctx context.Context, key string) (time.Duration, error)
+	// Reset forgets previous failures after a successful attempt
+	Reset(ctx context.Context, key string) error
+}
+
+// lockoutFailScript increments the failure counter and swaps it for a lock key
+// once the limit is reached, so concurrent failures cannot skip the lockout.
+// Each lockout within the strike memory doubles the next one, up to the maximum.
+var lockoutFailScript = redis.NewScript(`
+	local failures = redis.call("INCR", KEYS[1])
+	if failures == 1 then
+		redis.call("EXPIRE", KEYS[1], ARGV[2])
+	end
+	if failures < tonumber(ARGV[1]) then
+		return 0
+	end
+
+	local strikes = redis.call("INCR", KEYS[3])
+	redis.call("EXPIRE", KEYS[3], ARGV[5])
+	local duration = math.floor(tonumber(ARGV[3]) * 2 ^ (strikes - 1))
+	if duration > tonumber(ARGV[4]) then
+		duration = tonumber(ARGV[4])
+	end
+
+	redis.call("SET", KEYS[2], 1, "EX", duration)
+	redis.call("DEL", KEYS[1])
+	return duration
+`)
+
+type RedisLockout struct {
+	rdb             *redis.Client
+	maxFailures     int
+	window          time.Duration
+	lockDuration    time.Duration
+	maxLockDuration time.Duration
+	strikeMemory    time.Duration
+}
+
+// NewRedisLockout locks a key for lockDuration after maxFailures failures within window
+func NewRedisLockout(rdb *redis.Client, maxFailures int, window time.Duration, lockDuration time.Duration) *RedisLockout {
+	return NewRedisBackoffLockout(rdb, maxFailures, window, lockDuration, lockDuration)
+}
+
+// NewRedisBackoffLockout is like NewRedisLockout, but every further lockout within a day
+// of the previous one doubles, up to maxLockDuration
+func NewRedisBackoffLockout(rdb *redis.Client, maxFailures int, window time.Duration, lockDuration time.Duration, maxLockDuration time.Duration) *RedisLockout {
+	return &RedisLockout{
+		rdb:             rdb,
+		maxFailures:     maxFailures,
+		window:          window,
+		lockDuration:    lockDuration,
+		maxLockDuration: maxLockDuration,
+		strikeMemory:    24 * time.Hour,
+	}
+}
+
+func failuresKey(key string) string {
+	return fmt.Sprintf("lockout:failures:%s", key)
+}
+
+func lockedKey(key string) string {
+	return fmt.Sprintf("lockout:locked:%s", key)
+}
+
+func strikesKey(key string) string {
+	return fmt.Sprintf("lockout:strikes:%s", key)
+}
+
+func (l *RedisLockout) Check(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := l.rdb.PTTL(ctx, lockedKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	// PTTL reports missing keys as a negative duration
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (l *RedisLockout) Fail(ctx context.Context, key string) (time.Duration, error) {
+	seconds, err := lockoutFailScript.Run(ctx, l.rdb,
+		[]string{failuresKey(key), lockedKey(key), strikesKey(key)},
+		l.maxFailures, int(l.window.Seconds()), int(l.lockDuration.Seconds()),
+		int(l.maxLockDuration.Seconds()), int(l.strikeMemory.Seconds()),
+	).Int()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func (l *RedisLockout) Reset(ctx context.Context, key string) error {
+	return l.rdb.Del(ctx, failuresKey(key), strikesKey(key)).Err()
+}
@@ -0,0 +1,22 @@
+package testutil
+
+import (
+	"backend/pkg/utilities"
+	"crypto/ed25519"
+	"crypto/rand"
+)
+
+// InstallTestKeyRing signs and verifies tokens with a fresh in-memory Ed25519 key.
+// Call it from TestMain in packages that issue tokens.
+func InstallTestKeyRing() *utilities.KeyRing {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic("failed to generate test signing key: " + err.Error())
+	}
+	ring, err := utilities.NewKeyRing(utilities.SigningKey{ID: "test", PrivateKey: privateKey})
+	if err != nil {
+		panic(err)
+	}
+	utilities.SetKeyRing(ring)
+	return ring
+}
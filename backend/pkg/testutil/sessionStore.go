@@ -0,0 +1,108 @@
+package testutil
+
+import (
+	"backend/pkg/utilities"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MemorySessionStore is an in-memory utilities.SessionStore that mirrors the
+// bookkeeping RedisSessionStore does, for tests that need real session state.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*utilities.Session
+	refresh  map[string]string
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string]*utilities.Session),
+		refresh:  make(map[string]string),
+	}
+}
+
+func (s *MemorySessionStore) CreateSession(ctx context.Context, session utilities.Session, ttlSeconds int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[session.ID] = &session
+	return nil
+}
+
+func (s *MemorySessionStore) GetSession(ctx context.Context, sessionId string) (*utilities.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[sessionId]
+	if !ok {
+		return nil, redis.Nil
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (s *MemorySessionStore) TouchSession(ctx context.Context, sessionId string, seenAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session, ok := s.sessions[sessionId]; ok {
+		session.LastSeenAt = seenAt
+	}
+	return nil
+}
+
+func (s *MemorySessionStore) ListSessions(ctx context.Context, userId string) ([]utilities.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sessions []utilities.Session
+	for _, session := range s.sessions {
+		if session.UserID == userId {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+func (s *MemorySessionStore) GetRefreshTokenSession(ctx context.Context, jti string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sessionId, ok := s.refresh[jti]
+	if !ok {
+		return "", redis.Nil
+	}
+	return sessionId, nil
+}
+
+func (s *MemorySessionStore) StoreRefreshToken(ctx context.Context, jti string, sessionId string, ttlSeconds int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh[jti] = sessionId
+	if session, ok := s.sessions[sessionId]; ok {
+		session.RefreshJti = jti
+	}
+	return nil
+}
+
+func (s *MemorySessionStore) RotateRefreshToken(ctx context.Context, sessionId string, userId string, oldJti string, newJti string, ttlSeconds int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[sessionId]
+	if !ok || session.UserID != userId || session.RefreshJti != oldJti {
+		return false, nil
+	}
+	session.RefreshJti = newJti
+	s.refresh[newJti] = sessionId
+	return true, nil
+}
+
+func (s *MemorySessionStore) RevokeSession(ctx context.Context, sessionId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[sessionId]
+	if !ok {
+		return redis.Nil
+	}
+	delete(s.refresh, session.RefreshJti)
+	delete(s.sessions, sessionId)
+	return nil
+}
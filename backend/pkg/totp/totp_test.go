@@ -0,0 +1,101 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B test vectors for SHA1, truncated to the last six digits
+func TestCodeMatchesRFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("Code() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(t=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateAllowsOneStepOfSkew(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name   string
+		codeAt time.Time
+		valid  bool
+	}{
+		{"current step", now, true},
+		{"previous step", now.Add(-Period), true},
+		{"next step", now.Add(Period), true},
+		{"two steps old", now.Add(-2 * Period), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Code(secret, tt.codeAt)
+			if err != nil {
+				t.Fatalf("Code() error = %v", err)
+			}
+			step, ok := Validate(secret, code, now)
+			if ok != tt.valid {
+				t.Fatalf("Validate() = %v, want %v", ok, tt.valid)
+			}
+			if ok && step != Step(tt.codeAt) {
+				t.Errorf("Validate() step = %d, want %d", step, Step(tt.codeAt))
+			}
+		})
+	}
+}
+
+func TestValidateRejectsMalformedInput(t *testing.T) {
+	secret, _ := GenerateSecret()
+	now := time.Now()
+	for _, code := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := Validate(secret, code, now); ok {
+			t.Errorf("Validate(%q) accepted a malformed code", code)
+		}
+	}
+	if _, ok := Validate("not base32!", "123456", now); ok {
+		t.Error("Validate() accepted an invalid secret")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("BarbedWork", "alice", "JBSWY3DPEHPK3PXP")
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("invalid URI: %v", err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("unexpected URI prefix: %s", uri)
+	}
+	if !strings.HasSuffix(parsed.Path, "BarbedWork:alice") {
+		t.Errorf("unexpected label in %s", parsed.Path)
+	}
+	query := parsed.Query()
+	if query.Get("secret") != "JBSWY3DPEHPK3PXP" || query.Get("issuer") != "BarbedWork" || query.Get("digits") != "6" {
+		t.Errorf("unexpected parameters: %s", parsed.RawQuery)
+	}
+}
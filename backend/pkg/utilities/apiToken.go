@@ -0,0 +1,45 @@
+package utilities
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidAPIToken is returned for unknown, revoked and expired personal access tokens
+var ErrInvalidAPIToken = errors.New("invalid API token")
+
+// APITokenClaims describes the user and scopes a personal access token acts with
+type APITokenClaims struct {
+	TokenID string
+	UserID  string
+	Scopes  []string
+}
+
+// APITokenVerifier resolves personal access tokens presented as bearer credentials
+type APITokenVerifier interface {
+	VerifyAPIToken(ctx context.Context, token string) (*APITokenClaims, error)
+}
+
+// apiTokenSessionStore is a session store that can also verify personal access tokens
+type apiTokenSessionStore struct {
+	SessionStore
+	APITokenVerifier
+}
+
+// WithAPITokens returns a session store that TokenAuthMiddleware also accepts
+// personal access tokens through, so every route guarded by it takes them
+func WithAPITokens(store SessionStore, verifier APITokenVerifier) SessionStore {
+	return &apiTokenSessionStore{SessionStore: store, APITokenVerifier: verifier}
+}
+
+// WithAPITokenScopes marks the request as made with a personal access token
+func WithAPITokenScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, APITokenScopesKey, scopes)
+}
+
+// GetAPITokenScopes returns the scopes of the personal access token the request
+// was made with; ok is false for requests made from a browser session
+func GetAPITokenScopes(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(APITokenScopesKey).([]string)
+	return scopes, ok
+}
@@ -0,0 +1,54 @@
+package utilities_test
+
+import (
+	"backend/pkg/utilities"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := utilities.ParseTrustedProxies("10.0.0.0/8, 192.168.1.5")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		trusted    bool
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"no proxies configured", false, "203.0.113.7:4000", "198.51.100.1", "203.0.113.7"},
+		{"untrusted peer", true, "203.0.113.7:4000", "198.51.100.1", "203.0.113.7"},
+		{"trusted peer", true, "10.1.2.3:4000", "198.51.100.1", "198.51.100.1"},
+		{"spoofed hop before proxy", true, "10.1.2.3:4000", "1.2.3.4, 198.51.100.1", "198.51.100.1"},
+		{"chain of trusted proxies", true, "10.1.2.3:4000", "198.51.100.1, 192.168.1.5", "198.51.100.1"},
+		{"trusted peer without header", true, "192.168.1.5:4000", "", "192.168.1.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.trusted {
+				utilities.SetTrustedProxies(proxies)
+			} else {
+				utilities.SetTrustedProxies(nil)
+			}
+			t.Cleanup(func() { utilities.SetTrustedProxies(nil) })
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := utilities.ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxiesRejectsGarbage(t *testing.T) {
+	if _, err := utilities.ParseTrustedProxies("10.0.0.0/8,not-an-ip"); err == nil {
+		t.Fatal("ParseTrustedProxies() accepted an invalid entry")
+	}
+}
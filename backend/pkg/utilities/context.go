@@ -4,7 +4,12 @@ import "context"
 
 type ContextKey string
 
-const UserIDKey ContextKey = "userID"
+const (
+	UserIDKey         ContextKey = "userID"
+	SessionIDKey      ContextKey = "sessionID"
+	InstanceAdminKey  ContextKey = "instanceAdmin"
+	APITokenScopesKey ContextKey = "apiTokenScopes"
+)
 
 // userIDKey is a key used to store the user ID in the context
 func WithUserID(ctx context.Context, userID string) context.Context {
@@ -16,3 +21,25 @@ func GetUserID(ctx context.Context) (string, bool) {
 	userID, ok := ctx.Value(UserIDKey).(string)
 	return userID, ok
 }
+
+// WithSessionID stores the ID of the device session the request was made from
+func WithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, SessionIDKey, sessionID)
+}
+
+// GetSessionID retrieves the session ID from the context
+func GetSessionID(ctx context.Context) (string, bool) {
+	sessionID, ok := ctx.Value(SessionIDKey).(string)
+	return sessionID, ok
+}
+
+// WithInstanceAdmin marks the request as made by an admin panel account
+func WithInstanceAdmin(ctx context.Context, instanceAdmin bool) context.Context {
+	return context.WithValue(ctx, InstanceAdminKey, instanceAdmin)
+}
+
+// IsInstanceAdmin reports whether the request was made by an admin panel account
+func IsInstanceAdmin(ctx context.Context) bool {
+	instanceAdmin, _ := ctx.Value(InstanceAdminKey).(bool)
+	return instanceAdmin
+}
@@ -0,0 +1,40 @@
+package utilities
+
+import (
+	"net/http"
+	"os"
+)
+
+// SetRefreshTokenCookie stores the refresh token in an HttpOnly cookie
+func SetRefreshTokenCookie(w http.ResponseWriter, refreshToken string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     RefreshTokenCookieName,
+		Value:    refreshToken,
+		Path:     "/",
+		MaxAge:   int(SessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: refreshCookieSameSite(),
+	})
+}
+
+// ClearRefreshTokenCookie tells the browser to drop the refresh token cookie
+func ClearRefreshTokenCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     RefreshTokenCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: refreshCookieSameSite(),
+	})
+}
+
+// refreshCookieSameSite relaxes SameSite in DEV, where the frontend runs on another origin
+func refreshCookieSameSite() http.SameSite {
+	if os.Getenv("DEV") != "" {
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteLaxMode
+}
@@ -3,7 +3,6 @@ package utilities
 import (
 	"context"
 	"errors"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -19,54 +18,106 @@ var (
 	ErrRedisLookupFailed   = errors.New("failed to query session store")
 	ErrTokenGeneration     = errors.New("failed to generate token")
 	ErrSessionStorage      = errors.New("failed to store session data")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used")
+)
+
+// SessionTTL is how long a device stays signed in without using its refresh token
+const SessionTTL = 30 * 24 * time.Hour
+
+// lastSeenResolution limits how often a session's last-seen time is written
+const lastSeenResolution = time.Minute
+
+// Token types in the typ claim. Both kinds share a signing key and carry the same
+// sub and sid, so each validator only accepts its own type.
+const (
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
 )
 
 // AccessTokenClaims defines the structure of an access token
 type AccessTokenClaims struct {
 	jwt.RegisteredClaims
+	Type      string `json:"typ"`
+	SessionID string `json:"sid"`
+	// InstanceAdmin is not part of the token; ValidateAccessToken copies it from the session
+	InstanceAdmin bool `json:"-"`
 }
 
 // RefreshTokenClaims defines the structure of a refresh token
 type RefreshTokenClaims struct {
 	jwt.RegisteredClaims
-	Jti string `json:"jti"`
+	Type      string `json:"typ"`
+	Jti       string `json:"jti"`
+	SessionID string `json:"sid"`
+}
+
+// StartSession records a new device session for the user. Tokens for it are
+// issued with GenerateAccessToken and GenerateRefreshToken.
+func StartSession(ctx context.Context, store SessionStore, userId string, userAgent string, ip string) (*Session, error) {
+	return startSession(ctx, store, userId, userAgent, ip, false)
+}
+
+// StartAdminSession records a session for an admin panel account. Its requests are
+// treated as coming from an instance admin.
+func StartAdminSession(ctx context.Context, store SessionStore, adminId string, userAgent string, ip string) (*Session, error) {
+	return startSession(ctx, store, adminId, userAgent, ip, true)
 }
 
-// GenerateAccessToken creates a new short-lived access token
-func GenerateAccessToken(ctx context.Context, store SessionStore, userId string) (string, error) {
-	expiresAt := time.Now().Add(15 * time.Minute)
+func startSession(ctx context.Context, store SessionStore, userId string, userAgent string, ip string, instanceAdmin bool) (*Session, error) {
+	now := time.Now().UTC()
+	session := Session{
+		ID:            uuid.NewString(),
+		UserID:        userId,
+		UserAgent:     userAgent,
+		IP:            ip,
+		CreatedAt:     now,
+		LastSeenAt:    now,
+		InstanceAdmin: instanceAdmin,
+	}
+
+	if err := store.CreateSession(ctx, session, int(SessionTTL.Seconds())); err != nil {
+		return nil, errors.Join(ErrSessionStorage, err)
+	}
+
+	return &session, nil
+}
+
+// GenerateAccessToken creates a new short-lived access token bound to a session
+func GenerateAccessToken(ctx context.Context, store SessionStore, userId string, sessionId string) (string, error) {
+	now := time.Now()
+	expiresAt := now.Add(15 * time.Minute)
 
 	claims := AccessTokenClaims{
+		Type:      accessTokenType,
+		SessionID: sessionId,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   userId,
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	jwtSecret := os.Getenv("JWT_SECRET")
-
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	tokenString, err := signToken(claims)
 	if err != nil {
 		return "", errors.Join(ErrTokenGeneration, err)
 	}
 
-	_, err = store.StoreSession(ctx, userId, int(time.Until(expiresAt).Seconds()))
-	if err != nil {
+	if err := store.TouchSession(ctx, sessionId, now); err != nil {
 		return "", errors.Join(ErrSessionStorage, err)
 	}
 
 	return tokenString, nil
 }
 
-// GenerateRefreshToken creates a new long-lived refresh token and stores it in Redis
-func GenerateRefreshToken(ctx context.Context, store SessionStore, userId string) (string, error) {
-	expiresAt := time.Now().Add(30 * 24 * time.Hour) // 30 days
+// GenerateRefreshToken creates a new long-lived refresh token and links it to the session
+func GenerateRefreshToken(ctx context.Context, store SessionStore, userId string, sessionId string) (string, error) {
+	expiresAt := time.Now().Add(SessionTTL)
 	jti := uuid.NewString()
 
 	claims := RefreshTokenClaims{
-		Jti: jti,
+		Type:      refreshTokenType,
+		Jti:       jti,
+		SessionID: sessionId,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   userId,
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -74,15 +125,12 @@ func GenerateRefreshToken(ctx context.Context, store SessionStore, userId string
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	jwtSecret := os.Getenv("JWT_SECRET")
-
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	tokenString, err := signToken(claims)
 	if err != nil {
 		return "", errors.Join(ErrTokenGeneration, err)
 	}
 
-	err = store.StoreRefreshToken(ctx, jti, userId, int(time.Until(expiresAt).Seconds()))
+	err = store.StoreRefreshToken(ctx, jti, sessionId, int(time.Until(expiresAt).Seconds()))
 	if err != nil {
 		return "", errors.Join(ErrSessionStorage, err)
 	}
@@ -90,88 +138,145 @@ func GenerateRefreshToken(ctx context.Context, store SessionStore, userId string
 	return tokenString, nil
 }
 
-// ValidateAccessToken checks the access token and ensures the session is valid
-// Returns the userId and an error if invalid.
-func ValidateAccessToken(ctx context.Context, store SessionStore, tokenString string) (string, error) {
+// ValidateAccessToken checks the access token and ensures its session is still active.
+// Returns the token claims and an error if invalid.
+func ValidateAccessToken(ctx context.Context, store SessionStore, tokenString string) (*AccessTokenClaims, error) {
 	var claims AccessTokenClaims
-	jwtSecret := os.Getenv("JWT_SECRET")
-
-	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(jwtSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &claims, verificationKey)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) || errors.Is(err, jwt.ErrTokenNotValidYet) || errors.Is(err, jwt.ErrTokenMalformed) {
-			return "", ErrTokenInvalid
+			return nil, ErrTokenInvalid
 		}
-		return "", errors.Join(ErrTokenInvalid, err)
+		return nil, errors.Join(ErrTokenInvalid, err)
 	}
 
-	if !token.Valid {
-		return "", ErrTokenInvalid
+	if !token.Valid || claims.Type != accessTokenType || claims.SessionID == "" {
+		return nil, ErrTokenInvalid
 	}
 
-	val, err := store.GetSessionStatus(ctx, claims.Subject)
+	session, err := store.GetSession(ctx, claims.SessionID)
 	if err != nil {
 		if err == redis.Nil {
-			return "", ErrSessionNotFound
+			return nil, ErrSessionNotFound
 		}
-		return "", errors.Join(ErrRedisLookupFailed, err)
+		return nil, errors.Join(ErrRedisLookupFailed, err)
 	}
 
-	if val != "active" {
-		return "", ErrSessionNotFound
+	if session.UserID != claims.Subject {
+		return nil, ErrSessionNotFound
 	}
+	claims.InstanceAdmin = session.InstanceAdmin
 
-	return claims.Subject, nil
+	if now := time.Now(); now.Sub(session.LastSeenAt) >= lastSeenResolution {
+		// Last-seen is informational, so a failed write does not reject the request
+		store.TouchSession(ctx, session.ID, now)
+	}
+
+	return &claims, nil
 }
 
-// ValidateRefreshToken checks the refresh token and matches it against Redis
-// Returns the userId and an error if invalid.
-func ValidateRefreshToken(ctx context.Context, store SessionStore, tokenString string) (string, string, error) {
+// ValidateRefreshToken checks the refresh token is the one currently linked to its session.
+// Returns the token claims and an error if invalid.
+func ValidateRefreshToken(ctx context.Context, store SessionStore, tokenString string) (*RefreshTokenClaims, error) {
 	var claims RefreshTokenClaims
-	jwtSecret := os.Getenv("JWT_SECRET")
+	token, err := jwt.ParseWithClaims(tokenString, &claims, verificationKey)
 
-	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) || errors.Is(err, jwt.ErrTokenNotValidYet) || errors.Is(err, jwt.ErrTokenMalformed) {
+			return nil, ErrTokenInvalid
 		}
-		return []byte(jwtSecret), nil
-	})
+		return nil, errors.Join(ErrTokenInvalid, err)
+	}
+
+	if !token.Valid || claims.Type != refreshTokenType || claims.Jti == "" || claims.SessionID == "" {
+		return nil, ErrTokenInvalid
+	}
 
+	sessionId, err := store.GetRefreshTokenSession(ctx, claims.Jti)
 	if err != nil {
-		if errors.Is(err, jwt.ErrTokenExpired) || errors.Is(err, jwt.ErrTokenNotValidYet) || errors.Is(err, jwt.ErrTokenMalformed) {
-			return "", "", ErrTokenInvalid
+		if err == redis.Nil {
+			return nil, ErrInvalidRefreshToken
 		}
-		return "", "", errors.Join(ErrTokenInvalid, err)
+		return nil, errors.Join(ErrRedisLookupFailed, err)
 	}
 
-	if !token.Valid || claims.Jti == "" {
-		return "", "", ErrTokenInvalid
+	if sessionId != claims.SessionID {
+		return nil, ErrInvalidRefreshToken
 	}
 
-	userIdFromRedis, err := store.GetRefreshTokenUser(ctx, claims.Jti)
+	session, err := store.GetSession(ctx, sessionId)
 	if err != nil {
 		if err == redis.Nil {
-			return "", "", ErrInvalidRefreshToken
+			return nil, ErrInvalidRefreshToken
 		}
-		return "", "", errors.Join(ErrRedisLookupFailed, err)
+		return nil, errors.Join(ErrRedisLookupFailed, err)
+	}
+
+	if session.UserID != claims.Subject {
+		return nil, ErrInvalidRefreshToken
 	}
 
-	if userIdFromRedis != claims.Subject {
-		return "", "", ErrInvalidRefreshToken
+	if session.RefreshJti != claims.Jti {
+		// A rotated-out token was replayed, so either it or its successor was stolen.
+		// Revoke the whole session so neither copy can be used again.
+		if err := RevokeUserSession(ctx, store, sessionId); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
 	}
 
-	return claims.Subject, claims.Jti, nil
+	return &claims, nil
 }
 
-// RevokeUserSession deletes the access token session marker and the specific refresh token entry in Redis
-// Requires the userId (for session marker) and the specific JTI of the refresh token being revoked.
-func RevokeUserSession(ctx context.Context, store SessionStore, userId string, jti string) error {
-	err := store.RevokeSession(ctx, userId, jti)
+// RotateRefreshToken exchanges a valid refresh token for a new access and refresh token pair.
+// The presented token stops working; presenting it again revokes the session.
+func RotateRefreshToken(ctx context.Context, store SessionStore, tokenString string) (accessToken string, refreshToken string, claims *RefreshTokenClaims, err error) {
+	claims, err = ValidateRefreshToken(ctx, store, tokenString)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	now := time.Now()
+	newClaims := RefreshTokenClaims{
+		Type:      refreshTokenType,
+		Jti:       uuid.NewString(),
+		SessionID: claims.SessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   claims.Subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(SessionTTL)),
+		},
+	}
+
+	refreshToken, err = signToken(newClaims)
+	if err != nil {
+		return "", "", nil, errors.Join(ErrTokenGeneration, err)
+	}
+
+	rotated, err := store.RotateRefreshToken(ctx, claims.SessionID, claims.Subject, claims.Jti, newClaims.Jti, int(SessionTTL.Seconds()))
+	if err != nil {
+		return "", "", nil, errors.Join(ErrSessionStorage, err)
+	}
+	if !rotated {
+		// Another request rotated this token first, which is the same as a replay
+		if err := RevokeUserSession(ctx, store, claims.SessionID); err != nil {
+			return "", "", nil, err
+		}
+		return "", "", nil, ErrRefreshTokenReused
+	}
+
+	accessToken, err = GenerateAccessToken(ctx, store, claims.Subject, claims.SessionID)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	return accessToken, refreshToken, &newClaims, nil
+}
+
+// RevokeUserSession signs a single device out, deleting its session and linked refresh token
+func RevokeUserSession(ctx context.Context, store SessionStore, sessionId string) error {
+	err := store.RevokeSession(ctx, sessionId)
 
 	if err != nil && err != redis.Nil {
 		return errors.Join(ErrSessionStorage, err)
@@ -179,3 +284,30 @@ func RevokeUserSession(ctx context.Context, store SessionStore, userId string, j
 
 	return nil
 }
+
+// RevokeOtherSessions signs the user out of every device except keepSessionId.
+// Returns how many sessions were revoked.
+func RevokeOtherSessions(ctx context.Context, store SessionStore, userId string, keepSessionId string) (int, error) {
+	sessions, err := store.ListSessions(ctx, userId)
+	if err != nil {
+		return 0, errors.Join(ErrRedisLookupFailed, err)
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		if session.ID == keepSessionId {
+			continue
+		}
+		if err := RevokeUserSession(ctx, store, session.ID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+
+	return revoked, nil
+}
+
+// RevokeAllUserSessions signs the user out of every device
+func RevokeAllUserSessions(ctx context.Context, store SessionStore, userId string) (int, error) {
+	return RevokeOtherSessions(ctx, store, userId, "")
+}
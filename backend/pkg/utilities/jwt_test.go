@@ -0,0 +1,111 @@
+package utilities_test
+
+import (
+	"backend/pkg/testutil"
+	"backend/pkg/utilities"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func startTestSession(t *testing.T, store utilities.SessionStore, userId string) (*utilities.Session, string, string) {
+	t.Helper()
+	ctx := context.Background()
+	session, err := utilities.StartSession(ctx, store, userId, "test-agent", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("StartSession() error = %v", err)
+	}
+	accessToken, err := utilities.GenerateAccessToken(ctx, store, userId, session.ID)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+	refreshToken, err := utilities.GenerateRefreshToken(ctx, store, userId, session.ID)
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken() error = %v", err)
+	}
+	return session, accessToken, refreshToken
+}
+
+func TestRotateRefreshTokenIssuesNewPair(t *testing.T) {
+	ctx := context.Background()
+	store := testutil.NewMemorySessionStore()
+	session, _, refreshToken := startTestSession(t, store, "user123")
+
+	accessToken, newRefreshToken, claims, err := utilities.RotateRefreshToken(ctx, store, refreshToken)
+	if err != nil {
+		t.Fatalf("RotateRefreshToken() error = %v", err)
+	}
+	if newRefreshToken == refreshToken {
+		t.Fatal("expected a new refresh token")
+	}
+	if claims.SessionID != session.ID {
+		t.Errorf("rotated token belongs to session %s, want %s", claims.SessionID, session.ID)
+	}
+
+	accessClaims, err := utilities.ValidateAccessToken(ctx, store, accessToken)
+	if err != nil {
+		t.Fatalf("new access token rejected: %v", err)
+	}
+	if accessClaims.SessionID != session.ID {
+		t.Errorf("access token session = %s, want %s", accessClaims.SessionID, session.ID)
+	}
+
+	if _, _, _, err := utilities.RotateRefreshToken(ctx, store, newRefreshToken); err != nil {
+		t.Fatalf("rotating the new refresh token failed: %v", err)
+	}
+}
+
+func TestRotateRefreshTokenReuseRevokesSession(t *testing.T) {
+	ctx := context.Background()
+	store := testutil.NewMemorySessionStore()
+	session, accessToken, refreshToken := startTestSession(t, store, "user123")
+	otherSession, otherAccessToken, _ := startTestSession(t, store, "user123")
+
+	_, newRefreshToken, _, err := utilities.RotateRefreshToken(ctx, store, refreshToken)
+	if err != nil {
+		t.Fatalf("RotateRefreshToken() error = %v", err)
+	}
+
+	// Replaying the rotated-out token is treated as theft
+	if _, _, _, err := utilities.RotateRefreshToken(ctx, store, refreshToken); !errors.Is(err, utilities.ErrRefreshTokenReused) {
+		t.Fatalf("replay error = %v, want ErrRefreshTokenReused", err)
+	}
+
+	if _, err := store.GetSession(ctx, session.ID); !errors.Is(err, redis.Nil) {
+		t.Error("expected the session to be revoked after reuse")
+	}
+	if _, _, _, err := utilities.RotateRefreshToken(ctx, store, newRefreshToken); err == nil {
+		t.Error("expected the successor refresh token to be revoked too")
+	}
+	if _, err := utilities.ValidateAccessToken(ctx, store, accessToken); !errors.Is(err, utilities.ErrSessionNotFound) {
+		t.Errorf("access token error = %v, want ErrSessionNotFound", err)
+	}
+
+	// Other devices are unaffected
+	if _, err := utilities.ValidateAccessToken(ctx, store, otherAccessToken); err != nil {
+		t.Errorf("session %s should still be active: %v", otherSession.ID, err)
+	}
+}
+
+func TestTokenTypesAreNotInterchangeable(t *testing.T) {
+	ctx := context.Background()
+	store := testutil.NewMemorySessionStore()
+	_, accessToken, refreshToken := startTestSession(t, store, "user123")
+
+	// Both tokens name the same user and session, so only typ tells them apart
+	if _, err := utilities.ValidateAccessToken(ctx, store, refreshToken); !errors.Is(err, utilities.ErrTokenInvalid) {
+		t.Errorf("refresh token as access token error = %v, want ErrTokenInvalid", err)
+	}
+	if _, err := utilities.ValidateRefreshToken(ctx, store, accessToken); !errors.Is(err, utilities.ErrTokenInvalid) {
+		t.Errorf("access token as refresh token error = %v, want ErrTokenInvalid", err)
+	}
+
+	if _, err := utilities.ValidateAccessToken(ctx, store, accessToken); err != nil {
+		t.Errorf("access token rejected: %v", err)
+	}
+	if _, err := utilities.ValidateRefreshToken(ctx, store, refreshToken); err != nil {
+		t.Errorf("refresh token rejected: %v", err)
+	}
+}
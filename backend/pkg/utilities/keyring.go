@@ -0,0 +1,268 @@
+package utilities
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// notBeforeHeader is the PEM header that schedules when a key starts signing
+const notBeforeHeader = "Not-Before"
+
+var (
+	ErrNoSigningKeys  = errors.New("no JWT signing keys configured")
+	ErrUnknownKeyID   = errors.New("token signed with an unknown key")
+	ErrInvalidKeyFile = errors.New("invalid signing key file")
+)
+
+// SigningKey is one Ed25519 key in the ring. Its ID is published as the token `kid`.
+type SigningKey struct {
+	ID         string
+	PrivateKey ed25519.PrivateKey
+	NotBefore  time.Time
+}
+
+// KeyRing holds every key tokens may be verified with. The newest key whose
+// NotBefore has passed signs new tokens, so rotation is scheduled by adding a
+// key with a future Not-Before and retired by deleting the old key's file.
+type KeyRing struct {
+	dir string
+
+	mu   sync.RWMutex
+	keys []SigningKey
+}
+
+// LoadKeyRing reads every *.pem file in dir. File names (without extension) become key IDs.
+func LoadKeyRing(dir string) (*KeyRing, error) {
+	ring := &KeyRing{dir: dir}
+	if err := ring.Reload(); err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+// NewKeyRing builds a ring from keys held in memory
+func NewKeyRing(keys ...SigningKey) (*KeyRing, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoSigningKeys
+	}
+	ring := &KeyRing{}
+	ring.set(keys)
+	return ring, nil
+}
+
+// Reload re-reads the key directory, picking up newly added and removed keys
+func (k *KeyRing) Reload() error {
+	paths, err := filepath.Glob(filepath.Join(k.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	keys := make([]SigningKey, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read signing key %s: %w", path, err)
+		}
+		key, err := ParseSigningKey(strings.TrimSuffix(filepath.Base(path), ".pem"), data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return fmt.Errorf("%w in %s", ErrNoSigningKeys, k.dir)
+	}
+
+	k.set(keys)
+	return nil
+}
+
+// Watch reloads the key directory every interval until ctx is cancelled.
+// A failed reload keeps the previous keys.
+func (k *KeyRing) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Reload(); err != nil {
+				log.Printf("keyring: failed to reload signing keys: %v", err)
+			}
+		}
+	}
+}
+
+func (k *KeyRing) set(keys []SigningKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].NotBefore.Equal(keys[j].NotBefore) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].NotBefore.Before(keys[j].NotBefore)
+	})
+
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+}
+
+// Current returns the key new tokens are signed with
+func (k *KeyRing) Current() (SigningKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := time.Now()
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		if !k.keys[i].NotBefore.After(now) {
+			return k.keys[i], nil
+		}
+	}
+	return SigningKey{}, ErrNoSigningKeys
+}
+
+// PublicKey looks up a verification key by its ID
+func (k *KeyRing) PublicKey(kid string) (ed25519.PublicKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.ID == kid {
+			return key.PrivateKey.Public().(ed25519.PublicKey), nil
+		}
+	}
+	return nil, ErrUnknownKeyID
+}
+
+// JSONWebKey is the public half of a signing key in RFC 8037 form
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS publishes every key, including scheduled ones so verifiers can fetch them ahead of rotation
+func (k *KeyRing) JWKS() JSONWebKeySet {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(k.keys))}
+	for _, key := range k.keys {
+		set.Keys = append(set.Keys, JSONWebKey{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key.PrivateKey.Public().(ed25519.PublicKey)),
+			Kid: key.ID,
+			Use: "sig",
+			Alg: "EdDSA",
+		})
+	}
+	return set
+}
+
+// ParseSigningKey decodes a PKCS#8 Ed25519 private key with an optional Not-Before header
+func ParseSigningKey(id string, data []byte) (SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return SigningKey{}, fmt.Errorf("%w: expected a PEM encoded PRIVATE KEY", ErrInvalidKeyFile)
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("%w: %v", ErrInvalidKeyFile, err)
+	}
+	privateKey, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return SigningKey{}, fmt.Errorf("%w: only Ed25519 keys are supported", ErrInvalidKeyFile)
+	}
+
+	key := SigningKey{ID: id, PrivateKey: privateKey}
+	if value, ok := block.Headers[notBeforeHeader]; ok {
+		key.NotBefore, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return SigningKey{}, fmt.Errorf("%w: invalid %s header: %v", ErrInvalidKeyFile, notBeforeHeader, err)
+		}
+	}
+
+	return key, nil
+}
+
+// GenerateSigningKeyPEM creates a new Ed25519 key, PEM encoded, that starts signing at notBefore
+func GenerateSigningKeyPEM(notBefore time.Time) ([]byte, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	block := &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	if !notBefore.IsZero() {
+		block.Headers = map[string]string{notBeforeHeader: notBefore.UTC().Format(time.RFC3339)}
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+// activeKeyRing is the ring tokens are signed and verified with; set once at startup
+var activeKeyRing atomic.Pointer[KeyRing]
+
+// SetKeyRing installs the key ring used by the token functions in this package
+func SetKeyRing(ring *KeyRing) {
+	activeKeyRing.Store(ring)
+}
+
+// signToken signs claims with the ring's current key and records its kid
+func signToken(claims jwt.Claims) (string, error) {
+	ring := activeKeyRing.Load()
+	if ring == nil {
+		return "", ErrNoSigningKeys
+	}
+	key, err := ring.Current()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// verificationKey resolves the key a token claims to be signed with
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodEd25519); !ok {
+		return nil, errors.New("unexpected signing method")
+	}
+	ring := activeKeyRing.Load()
+	if ring == nil {
+		return nil, ErrNoSigningKeys
+	}
+	kid, _ := token.Header["kid"].(string)
+	return ring.PublicKey(kid)
+}
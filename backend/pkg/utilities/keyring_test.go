@@ -0,0 +1,125 @@
+package utilities_test
+
+import (
+	"backend/pkg/testutil"
+	"backend/pkg/utilities"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	testutil.InstallTestKeyRing()
+	os.Exit(m.Run())
+}
+
+func writeKey(t *testing.T, dir string, id string, notBefore time.Time) {
+	t.Helper()
+	data, err := utilities.GenerateSigningKeyPEM(notBefore)
+	if err != nil {
+		t.Fatalf("GenerateSigningKeyPEM() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, id+".pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadKeyRingRequiresKeys(t *testing.T) {
+	if _, err := utilities.LoadKeyRing(t.TempDir()); !errors.Is(err, utilities.ErrNoSigningKeys) {
+		t.Fatalf("LoadKeyRing() error = %v, want ErrNoSigningKeys", err)
+	}
+}
+
+func TestLoadKeyRingRejectsInvalidKeys(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.pem"), []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := utilities.LoadKeyRing(dir); !errors.Is(err, utilities.ErrInvalidKeyFile) {
+		t.Fatalf("LoadKeyRing() error = %v, want ErrInvalidKeyFile", err)
+	}
+}
+
+func TestKeyRingScheduledRotation(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "2024-01", time.Time{})
+	writeKey(t, dir, "2024-02", time.Now().Add(-time.Hour))
+	writeKey(t, dir, "2024-03", time.Now().Add(24*time.Hour))
+
+	ring, err := utilities.LoadKeyRing(dir)
+	if err != nil {
+		t.Fatalf("LoadKeyRing() error = %v", err)
+	}
+
+	// The newest key that is already active signs; the scheduled one waits
+	current, err := ring.Current()
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+	if current.ID != "2024-02" {
+		t.Errorf("current key = %s, want 2024-02", current.ID)
+	}
+
+	// Every key, including the scheduled one, is published for verifiers
+	jwks := ring.JWKS()
+	if len(jwks.Keys) != 3 {
+		t.Fatalf("JWKS has %d keys, want 3", len(jwks.Keys))
+	}
+	for _, key := range jwks.Keys {
+		publicKey, err := ring.PublicKey(key.Kid)
+		if err != nil {
+			t.Fatalf("PublicKey(%s) error = %v", key.Kid, err)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(key.X)
+		if err != nil || !ed25519.PublicKey(x).Equal(publicKey) {
+			t.Errorf("JWKS key %s does not match the ring", key.Kid)
+		}
+		if key.Kty != "OKP" || key.Crv != "Ed25519" || key.Alg != "EdDSA" {
+			t.Errorf("JWKS key %s has unexpected parameters %+v", key.Kid, key)
+		}
+	}
+}
+
+func TestTokensVerifyAfterRotation(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeKey(t, dir, "old", time.Time{})
+
+	ring, err := utilities.LoadKeyRing(dir)
+	if err != nil {
+		t.Fatalf("LoadKeyRing() error = %v", err)
+	}
+	utilities.SetKeyRing(ring)
+	defer testutil.InstallTestKeyRing()
+
+	store := testutil.NewMemorySessionStore()
+	_, oldAccessToken, _ := startTestSession(t, store, "user123")
+
+	// Rotate: the new key signs, tokens from the old key still verify
+	writeKey(t, dir, "new", time.Now().Add(-time.Second))
+	if err := ring.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if current, _ := ring.Current(); current.ID != "new" {
+		t.Fatalf("current key = %s, want new", current.ID)
+	}
+	if _, err := utilities.ValidateAccessToken(ctx, store, oldAccessToken); err != nil {
+		t.Errorf("token signed with the old key was rejected: %v", err)
+	}
+
+	// Retire the old key: its tokens stop verifying
+	if err := os.Remove(filepath.Join(dir, "old.pem")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if _, err := utilities.ValidateAccessToken(ctx, store, oldAccessToken); !errors.Is(err, utilities.ErrTokenInvalid) {
+		t.Errorf("token signed with a retired key: error = %v, want ErrTokenInvalid", err)
+	}
+}
@@ -1,34 +1,41 @@
 package utilities
 
 import (
-	"backend/internal/repos"
-	"backend/internal/services"
 	"context"
 	"errors"
 )
 
+// PermissionSource resolves the permissions a user holds inside a single workspace
+type PermissionSource interface {
+	GetUserPermissions(ctx context.Context, userID string, workspaceID string) ([]string, error)
+}
 
 type PermissionChecker struct {
-	userService *services.UserService
-	userRepo    *repos.UserRepo
+	source PermissionSource
 }
 
-func NewPermissionChecker(userService *services.UserService, userRepo *repos.UserRepo) *PermissionChecker {
+func NewPermissionChecker(source PermissionSource) *PermissionChecker {
 	return &PermissionChecker{
-		userService: userService,
-		userRepo:    userRepo,
+		source: source,
 	}
 }
 
-func (p *PermissionChecker) GetUserPermissions(ctx context.Context, userID string) ([]string, error) {
-	if p.userService == nil {
-		return nil, errors.New("user service not initialized")
+// GetUserPermissions returns the user's permissions granted by their roles in the workspace
+func (p *PermissionChecker) GetUserPermissions(ctx context.Context, userID string, workspaceID string) ([]string, error) {
+	if p.source == nil {
+		return nil, errors.New("permission source not initialized")
 	}
-	return p.userRepo.GetAllUserPermissions(ctx, userID)
+	return p.source.GetUserPermissions(ctx, userID, workspaceID)
 }
 
-func (p *PermissionChecker) CheckUserPermission(ctx context.Context, userID string, requiredPermission string) (bool, error) {
-	permissions, err := p.GetUserPermissions(ctx, userID)
+// CheckUserPermission reports whether the user holds the permission in the given workspace.
+// Permissions granted in other workspaces are never considered.
+func (p *PermissionChecker) CheckUserPermission(ctx context.Context, userID string, workspaceID string, requiredPermission string) (bool, error) {
+	if workspaceID == "" {
+		return false, nil
+	}
+
+	permissions, err := p.GetUserPermissions(ctx, userID, workspaceID)
 	if err != nil {
 		return false, err
 	}
@@ -39,4 +46,4 @@ func (p *PermissionChecker) CheckUserPermission(ctx context.Context, userID stri
 		}
 	}
 	return false, nil
-}
\ No newline at end of file
+}
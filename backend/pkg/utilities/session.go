@@ -2,18 +2,37 @@ package utilities
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// Session is one signed-in device. Access tokens carry its ID in the sid claim
+// and its current refresh token is linked through RefreshJti. InstanceAdmin marks
+// sessions of admin panel accounts, which bypass workspace permission checks.
+type Session struct {
+	ID            string    `json:"id"`
+	UserID        string    `json:"userId"`
+	UserAgent     string    `json:"userAgent"`
+	IP            string    `json:"ip"`
+	CreatedAt     time.Time `json:"createdAt"`
+	LastSeenAt    time.Time `json:"lastSeenAt"`
+	InstanceAdmin bool      `json:"instanceAdmin"`
+	RefreshJti    string    `json:"-"`
+}
 
+// SessionStore persists device sessions and the refresh tokens linked to them.
+// Lookups of missing keys return redis.Nil.
 type SessionStore interface {
-	GetSessionStatus(ctx context.Context, userId string) (string, error)
-	GetRefreshTokenUser(ctx context.Context, jti string) (string, error)
-	StoreSession(ctx context.Context, userId string, ttlSeconds int) (string, error)
-	StoreRefreshToken(ctx context.Context, jti string, userId string, ttlSeconds int) error
-	RevokeSession(ctx context.Context, userId string, jti string) error
+	CreateSession(ctx context.Context, session Session, ttlSeconds int) error
+	GetSession(ctx context.Context, sessionId string) (*Session, error)
+	TouchSession(ctx context.Context, sessionId string, seenAt time.Time) error
+	ListSessions(ctx context.Context, userId string) ([]Session, error)
+	GetRefreshTokenSession(ctx context.Context, jti string) (string, error)
+	StoreRefreshToken(ctx context.Context, jti string, sessionId string, ttlSeconds int) error
+	RotateRefreshToken(ctx context.Context, sessionId string, userId string, oldJti string, newJti string, ttlSeconds int) (bool, error)
+	RevokeSession(ctx context.Context, sessionId string) error
 }
 
 type RedisSessionStore struct {
@@ -24,26 +43,169 @@ func NewRedisSessionStore(redis *redis.Client) *RedisSessionStore {
 	return &RedisSessionStore{Redis: redis}
 }
 
-func (s *RedisSessionStore) GetSessionStatus(ctx context.Context, userId string) (string, error) {
-	return s.Redis.Get(ctx, "session:"+userId).Result()
+func sessionKey(sessionId string) string {
+	return "session:" + sessionId
+}
+
+func userSessionsKey(userId string) string {
+	return "user_sessions:" + userId
 }
 
-func (s *RedisSessionStore) GetRefreshTokenUser(ctx context.Context, jti string) (string, error) {
-	return s.Redis.Get(ctx, "refresh:"+jti).Result()
+func refreshKey(jti string) string {
+	return "refresh:" + jti
+}
+
+func (s *RedisSessionStore) CreateSession(ctx context.Context, session Session, ttlSeconds int) error {
+	ttl := time.Duration(ttlSeconds) * time.Second
+	pipe := s.Redis.TxPipeline()
+	pipe.HSet(ctx, sessionKey(session.ID), map[string]any{
+		"user_id":        session.UserID,
+		"user_agent":     session.UserAgent,
+		"ip":             session.IP,
+		"created_at":     session.CreatedAt.Unix(),
+		"last_seen_at":   session.LastSeenAt.Unix(),
+		"instance_admin": session.InstanceAdmin,
+		"refresh_jti":    session.RefreshJti,
+	})
+	pipe.Expire(ctx, sessionKey(session.ID), ttl)
+	pipe.SAdd(ctx, userSessionsKey(session.UserID), session.ID)
+	// The index lives as long as the newest session
+	pipe.Expire(ctx, userSessionsKey(session.UserID), ttl)
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
-func (s *RedisSessionStore) StoreSession(ctx context.Context, userId string, ttlSeconds int) (string, error) {
-	return s.Redis.Set(ctx, "session:"+userId, "active", time.Duration(ttlSeconds)*time.Second).Result()
+func (s *RedisSessionStore) GetSession(ctx context.Context, sessionId string) (*Session, error) {
+	fields, err := s.Redis.HGetAll(ctx, sessionKey(sessionId)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, redis.Nil
+	}
+	return sessionFromFields(sessionId, fields), nil
 }
 
-func (s *RedisSessionStore) StoreRefreshToken(ctx context.Context, jti string, userId string, ttlSeconds int) error {
-	return s.Redis.Set(ctx, "refresh:"+jti, userId, time.Duration(ttlSeconds)*time.Second).Err()
+// touchSessionScript only updates sessions that still exist so a touch cannot resurrect a revoked one
+var touchSessionScript = redis.NewScript(`
+	if redis.call("EXISTS", KEYS[1]) == 1 then
+		redis.call("HSET", KEYS[1], "last_seen_at", ARGV[1])
+	end
+	return 1
+`)
+
+func (s *RedisSessionStore) TouchSession(ctx context.Context, sessionId string, seenAt time.Time) error {
+	return touchSessionScript.Run(ctx, s.Redis, []string{sessionKey(sessionId)}, seenAt.Unix()).Err()
 }
 
-func (s *RedisSessionStore) RevokeSession(ctx context.Context, userId string, jti string) error {
+func (s *RedisSessionStore) ListSessions(ctx context.Context, userId string) ([]Session, error) {
+	ids, err := s.Redis.SMembers(ctx, userSessionsKey(userId)).Result()
+	if err != nil {
+		return nil, err
+	}
+
 	pipe := s.Redis.Pipeline()
-	pipe.Del(ctx, "session:"+userId)
-	pipe.Del(ctx, "refresh:"+jti)
+	cmds := make([]*redis.MapStringStringCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(ctx, sessionKey(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(ids))
+	var expired []any
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			expired = append(expired, ids[i])
+			continue
+		}
+		sessions = append(sessions, *sessionFromFields(ids[i], fields))
+	}
+
+	// Drop index entries whose session hash has expired
+	if len(expired) > 0 {
+		s.Redis.SRem(ctx, userSessionsKey(userId), expired...)
+	}
+
+	return sessions, nil
+}
+
+func (s *RedisSessionStore) GetRefreshTokenSession(ctx context.Context, jti string) (string, error) {
+	return s.Redis.Get(ctx, refreshKey(jti)).Result()
+}
+
+func (s *RedisSessionStore) StoreRefreshToken(ctx context.Context, jti string, sessionId string, ttlSeconds int) error {
+	pipe := s.Redis.TxPipeline()
+	pipe.Set(ctx, refreshKey(jti), sessionId, time.Duration(ttlSeconds)*time.Second)
+	pipe.HSet(ctx, sessionKey(sessionId), "refresh_jti", jti)
 	_, err := pipe.Exec(ctx)
 	return err
 }
+
+// rotateRefreshScript swaps a session's refresh jti only if oldJti is still current.
+// The retired refresh:<jti> key is kept so a replay of it can be recognised as reuse.
+// Every key it touches is passed in KEYS so the script is safe on a cluster.
+var rotateRefreshScript = redis.NewScript(`
+	local sessionKey = KEYS[1]
+	if redis.call("HGET", sessionKey, "refresh_jti") ~= ARGV[1] then
+		return 0
+	end
+	if redis.call("HGET", sessionKey, "user_id") ~= ARGV[5] then
+		return 0
+	end
+	redis.call("HSET", sessionKey, "refresh_jti", ARGV[2])
+	redis.call("EXPIRE", sessionKey, ARGV[4])
+	redis.call("SET", KEYS[2], ARGV[3], "EX", ARGV[4])
+	redis.call("EXPIRE", KEYS[3], ARGV[4])
+	return 1
+`)
+
+func (s *RedisSessionStore) RotateRefreshToken(ctx context.Context, sessionId string, userId string, oldJti string, newJti string, ttlSeconds int) (bool, error) {
+	rotated, err := rotateRefreshScript.Run(ctx, s.Redis,
+		[]string{sessionKey(sessionId), refreshKey(newJti), userSessionsKey(userId)},
+		oldJti, newJti, sessionId, ttlSeconds, userId,
+	).Int()
+	if err != nil {
+		return false, err
+	}
+	return rotated == 1, nil
+}
+
+func (s *RedisSessionStore) RevokeSession(ctx context.Context, sessionId string) error {
+	session, err := s.GetSession(ctx, sessionId)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.Redis.TxPipeline()
+	pipe.Del(ctx, sessionKey(sessionId))
+	if session.RefreshJti != "" {
+		pipe.Del(ctx, refreshKey(session.RefreshJti))
+	}
+	pipe.SRem(ctx, userSessionsKey(session.UserID), sessionId)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func sessionFromFields(sessionId string, fields map[string]string) *Session {
+	return &Session{
+		ID:            sessionId,
+		UserID:        fields["user_id"],
+		UserAgent:     fields["user_agent"],
+		IP:            fields["ip"],
+		CreatedAt:     unixField(fields["created_at"]),
+		LastSeenAt:    unixField(fields["last_seen_at"]),
+		InstanceAdmin: fields["instance_admin"] == "1",
+		RefreshJti:    fields["refresh_jti"],
+	}
+}
+
+func unixField(value string) time.Time {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0).UTC()
+}
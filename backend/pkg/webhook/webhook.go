@@ -0,0 +1,174 @@
+// Package webhook signs and delivers outgoing webhook payloads. Deliveries come from
+// a Queue; failed ones are retried with exponential backoff until the RetryPolicy
+// gives up and they are dead-lettered.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+)
+
+// Headers sent with every delivery. The signature covers the timestamp and the body,
+// so receivers can reject replays of old deliveries.
+const (
+	EventHeader     = "X-BarbedWork-Event"
+	DeliveryHeader  = "X-BarbedWork-Delivery"
+	TimestampHeader = "X-BarbedWork-Timestamp"
+	SignatureHeader = "X-BarbedWork-Signature"
+)
+
+const signaturePrefix = "sha256="
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrPrivateAddress   = errors.New("webhook URL resolves to a private address")
+)
+
+// Sign returns the signature header value for a body sent at timestamp (Unix
+// seconds): the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a received delivery's signature and that it was sent within
+// tolerance of now. It is what receivers are expected to do.
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	timestamp, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(header.Get(SignatureHeader)), []byte(Sign(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Delivery is one payload on its way to a subscriber
+type Delivery struct {
+	ID        string
+	WebhookID string
+	Event     string
+	URL       string
+	Secret    string
+	Payload   []byte
+	// Attempt counts this attempt, starting at 1
+	Attempt int
+}
+
+// Sender posts signed deliveries
+type Sender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewSender(client *http.Client) *Sender {
+	return &Sender{client: client, now: time.Now}
+}
+
+// Send posts the delivery and returns the receiver's status code. Anything but a 2xx
+// response is an error.
+func (s *Sender) Send(ctx context.Context, delivery Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "BarbedWork-Webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	timestamp := s.now().Unix()
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// NewClient returns an HTTP client for deliveries. Redirects are not followed. Unless
+// allowPrivate is set, connections to loopback, private and link-local addresses are
+// refused, so webhooks cannot be pointed at services inside the deployment.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		// Checked on the resolved address, so DNS names cannot sidestep it
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || isPrivate(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 4,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+// MatchTriggerWord reports which of the words the text starts with, ignoring case.
+// The word must be followed by the end of the text or a character that is not a
+// letter or digit, so "deploy" matches "deploy now" but not "deployment".
+func MatchTriggerWord(text string, words []string) (string, bool) {
+	text = strings.TrimSpace(text)
+	for _, word := range words {
+		if word == "" || len(text) < len(word) || !strings.EqualFold(text[:len(word)], word) {
+			continue
+		}
+		rest := text[len(word):]
+		if rest == "" {
+			return word, true
+		}
+		next := []rune(rest)[0]
+		if !unicode.IsLetter(next) && !unicode.IsDigit(next) {
+			return word, true
+		}
+	}
+	return "", false
+}
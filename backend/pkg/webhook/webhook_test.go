@@ -0,0 +1,228 @@
+package webhook_test
+
+import (
+	"backend/pkg/webhook"
+	"backend/pkg/webhook/webhooktest"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+const secret = "whsec_test"
+
+// memoryQueue hands out its pending deliveries on every claim and keeps each outcome
+type memoryQueue struct {
+	mu       sync.Mutex
+	pending  []webhook.Delivery
+	outcomes map[string][]webhook.Outcome
+}
+
+func newMemoryQueue(deliveries ...webhook.Delivery) *memoryQueue {
+	return &memoryQueue{pending: deliveries, outcomes: make(map[string][]webhook.Outcome)}
+}
+
+func (q *memoryQueue) Claim(ctx context.Context, limit int) ([]webhook.Delivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	claimed := q.pending[:min(limit, len(q.pending))]
+	q.pending = q.pending[len(claimed):]
+	for i := range claimed {
+		claimed[i].Attempt = len(q.outcomes[claimed[i].ID]) + 1
+	}
+	return claimed, nil
+}
+
+func (q *memoryQueue) Record(ctx context.Context, delivery webhook.Delivery, outcome webhook.Outcome) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.outcomes[delivery.ID] = append(q.outcomes[delivery.ID], outcome)
+	if !outcome.Delivered && !outcome.Dead {
+		q.pending = append(q.pending, delivery)
+	}
+	return nil
+}
+
+func (q *memoryQueue) outcomesOf(id string) []webhook.Outcome {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.outcomes[id]
+}
+
+func newDelivery(receiver *webhooktest.Receiver, id string) webhook.Delivery {
+	return webhook.Delivery{
+		ID:        id,
+		WebhookID: "wh1",
+		Event:     "member.added",
+		URL:       receiver.URL,
+		Secret:    secret,
+		Payload:   []byte(`{"type":"member.added"}`),
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"text":"deploy"}`)
+	now := time.Unix(1700000000, 0)
+	header := http.Header{}
+	header.Set(webhook.TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	header.Set(webhook.SignatureHeader, webhook.Sign(secret, now.Unix(), body))
+
+	if err := webhook.Verify(secret, header, body, time.Minute, now); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if err := webhook.Verify("other", header, body, time.Minute, now); !errors.Is(err, webhook.ErrInvalidSignature) {
+		t.Fatalf("Verify() with wrong secret error = %v, want ErrInvalidSignature", err)
+	}
+	if err := webhook.Verify(secret, header, []byte(`{"text":"tampered"}`), time.Minute, now); !errors.Is(err, webhook.ErrInvalidSignature) {
+		t.Fatalf("Verify() with tampered body error = %v, want ErrInvalidSignature", err)
+	}
+	if err := webhook.Verify(secret, header, body, time.Minute, now.Add(2*time.Minute)); !errors.Is(err, webhook.ErrInvalidSignature) {
+		t.Fatalf("Verify() of a stale delivery error = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestSenderSignsDeliveries(t *testing.T) {
+	receiver := webhooktest.NewReceiver(secret)
+	defer receiver.Close()
+
+	statusCode, err := webhook.NewSender(http.DefaultClient).Send(context.Background(), newDelivery(receiver, "d1"))
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if statusCode != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", statusCode, http.StatusNoContent)
+	}
+
+	requests := receiver.Requests()
+	if len(requests) != 1 || !requests[0].SignatureValid {
+		t.Fatalf("requests = %+v, want one with a valid signature", requests)
+	}
+	if got := requests[0].Header.Get(webhook.DeliveryHeader); got != "d1" {
+		t.Fatalf("delivery header = %q, want d1", got)
+	}
+	if got := requests[0].Header.Get(webhook.EventHeader); got != "member.added" {
+		t.Fatalf("event header = %q, want member.added", got)
+	}
+}
+
+func TestSenderRejectsWrongSecretAndErrorResponses(t *testing.T) {
+	receiver := webhooktest.NewReceiver("another secret")
+	defer receiver.Close()
+
+	statusCode, err := webhook.NewSender(http.DefaultClient).Send(context.Background(), newDelivery(receiver, "d1"))
+	if err == nil || statusCode != http.StatusUnauthorized {
+		t.Fatalf("Send() = %d, %v; want 401 and an error", statusCode, err)
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	receiver := webhooktest.NewReceiver(secret)
+	defer receiver.Close()
+
+	_, err := webhook.NewSender(webhook.NewClient(time.Second, false)).Send(context.Background(), newDelivery(receiver, "d1"))
+	if !errors.Is(err, webhook.ErrPrivateAddress) {
+		t.Fatalf("Send() to loopback error = %v, want ErrPrivateAddress", err)
+	}
+	if len(receiver.Requests()) != 0 {
+		t.Fatal("receiver was reached despite the private address check")
+	}
+
+	if _, err := webhook.NewSender(webhook.NewClient(time.Second, true)).Send(context.Background(), newDelivery(receiver, "d2")); err != nil {
+		t.Fatalf("Send() with private addresses allowed error = %v", err)
+	}
+}
+
+func TestWorkerRetriesWithBackoff(t *testing.T) {
+	receiver := webhooktest.NewReceiver(secret)
+	defer receiver.Close()
+	receiver.FailNext(2, http.StatusServiceUnavailable)
+
+	queue := newMemoryQueue(newDelivery(receiver, "d1"))
+	policy := webhook.RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: time.Minute}
+	worker := webhook.NewWorker(queue, webhook.NewSender(http.DefaultClient), policy)
+
+	for range 3 {
+		if _, err := worker.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce() error = %v", err)
+		}
+	}
+
+	outcomes := queue.outcomesOf("d1")
+	if len(outcomes) != 3 {
+		t.Fatalf("got %d outcomes, want 3", len(outcomes))
+	}
+	for i, want := range []time.Duration{time.Second, 2 * time.Second} {
+		if outcomes[i].Delivered || outcomes[i].Dead || outcomes[i].StatusCode != http.StatusServiceUnavailable || outcomes[i].RetryIn != want {
+			t.Fatalf("outcome %d = %+v, want a 503 retried in %v", i+1, outcomes[i], want)
+		}
+	}
+	if !outcomes[2].Delivered || outcomes[2].Error != "" {
+		t.Fatalf("last outcome = %+v, want delivered", outcomes[2])
+	}
+	if claimed, _ := worker.RunOnce(context.Background()); claimed != 0 {
+		t.Fatalf("claimed %d deliveries after success, want 0", claimed)
+	}
+}
+
+func TestWorkerDeadLettersAfterMaxAttempts(t *testing.T) {
+	receiver := webhooktest.NewReceiver(secret)
+	defer receiver.Close()
+	receiver.FailNext(10, http.StatusInternalServerError)
+
+	queue := newMemoryQueue(newDelivery(receiver, "d1"))
+	policy := webhook.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute}
+	worker := webhook.NewWorker(queue, webhook.NewSender(http.DefaultClient), policy)
+
+	for range 5 {
+		if _, err := worker.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce() error = %v", err)
+		}
+	}
+
+	outcomes := queue.outcomesOf("d1")
+	if len(outcomes) != 3 {
+		t.Fatalf("got %d outcomes, want 3", len(outcomes))
+	}
+	if last := outcomes[2]; !last.Dead || last.Delivered || last.RetryIn != 0 || last.Error == "" {
+		t.Fatalf("last outcome = %+v, want dead-lettered", last)
+	}
+	if got := len(receiver.Requests()); got != 3 {
+		t.Fatalf("receiver got %d requests, want 3", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := webhook.RetryPolicy{MaxAttempts: 10, BaseDelay: 30 * time.Second, MaxDelay: 5 * time.Minute}
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, delay := range want {
+		if got := policy.Backoff(i + 1); got != delay {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, delay)
+		}
+	}
+}
+
+func TestMatchTriggerWord(t *testing.T) {
+	words := []string{"deploy", "!status"}
+	testCases := []struct {
+		text  string
+		word  string
+		match bool
+	}{
+		{"deploy", "deploy", true},
+		{"Deploy api to prod", "deploy", true},
+		{"deploy: api", "deploy", true},
+		{"deployment done", "", false},
+		{"please deploy", "", false},
+		{"!status", "!status", true},
+		{"", "", false},
+	}
+	for _, testCase := range testCases {
+		word, match := webhook.MatchTriggerWord(testCase.text, words)
+		if word != testCase.word || match != testCase.match {
+			t.Errorf("MatchTriggerWord(%q) = %q, %v; want %q, %v", testCase.text, word, match, testCase.word, testCase.match)
+		}
+	}
+}
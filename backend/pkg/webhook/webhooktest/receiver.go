@@ -0,0 +1,79 @@
+// Package webhooktest runs an in-process webhook receiver for tests. It verifies each
+// delivery's signature like a real receiver would and records what it accepted.
+package webhooktest
+
+import (
+	"backend/pkg/webhook"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// Request is a delivery the receiver got, with whether its signature was valid
+type Request struct {
+	Header         http.Header
+	Body           []byte
+	SignatureValid bool
+}
+
+type Receiver struct {
+	*httptest.Server
+	Secret string
+
+	mu       sync.Mutex
+	requests []Request
+	failures int
+	failCode int
+}
+
+// NewReceiver starts a receiver expecting deliveries signed with secret. Close it
+// when the test is done.
+func NewReceiver(secret string) *Receiver {
+	r := &Receiver{Secret: secret}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serve))
+	return r
+}
+
+// FailNext makes the next n deliveries fail with the given status code
+func (r *Receiver) FailNext(n int, statusCode int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = n
+	r.failCode = statusCode
+}
+
+// Requests returns every delivery received so far, including failed ones
+func (r *Receiver) Requests() []Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Request(nil), r.requests...)
+}
+
+func (r *Receiver) serve(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	valid := webhook.Verify(r.Secret, req.Header, body, 5*time.Minute, time.Now()) == nil
+
+	r.mu.Lock()
+	r.requests = append(r.requests, Request{Header: req.Header.Clone(), Body: body, SignatureValid: valid})
+	fail := r.failures > 0
+	if fail {
+		r.failures--
+	}
+	failCode := r.failCode
+	r.mu.Unlock()
+
+	switch {
+	case !valid:
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+	case fail:
+		http.Error(w, "failing on purpose", failCode)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
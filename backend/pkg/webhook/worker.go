@@ -0,0 +1,123 @@
+package webhook
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Outcome is the result of one delivery attempt
+type Outcome struct {
+	// StatusCode is 0 when no response was received
+	StatusCode int
+	// Error describes why the attempt failed; it is empty on success
+	Error     string
+	Delivered bool
+	// Dead is set once the delivery has failed its last allowed attempt
+	Dead bool
+	// RetryIn is when to try again, for failures that are not dead
+	RetryIn time.Duration
+}
+
+// Queue holds pending deliveries. Claim must hide the deliveries it returns from
+// other claims until they are recorded, so several workers can share a queue.
+type Queue interface {
+	Claim(ctx context.Context, limit int) ([]Delivery, error)
+	Record(ctx context.Context, delivery Delivery, outcome Outcome) error
+}
+
+// RetryPolicy retries failed deliveries after BaseDelay, doubling the delay after
+// every further failure up to MaxDelay, and gives up after MaxAttempts attempts
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy spreads eight attempts over about an hour
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 8, BaseDelay: 30 * time.Second, MaxDelay: time.Hour}
+
+// Backoff returns the delay before the attempt after the given failed one
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// Worker claims deliveries from a queue, sends them and records the outcomes
+type Worker struct {
+	queue     Queue
+	sender    *Sender
+	policy    RetryPolicy
+	batchSize int
+}
+
+func NewWorker(queue Queue, sender *Sender, policy RetryPolicy) *Worker {
+	return &Worker{queue: queue, sender: sender, policy: policy, batchSize: 20}
+}
+
+// RunOnce sends one batch of due deliveries concurrently and returns how many it
+// claimed
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	deliveries, err := w.queue.Claim(ctx, w.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.deliver(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// Run sends due deliveries every interval until ctx is cancelled. Full batches are
+// followed immediately by the next one, so a backlog drains without waiting.
+func (w *Worker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for {
+			claimed, err := w.RunOnce(ctx)
+			if err != nil {
+				log.Printf("webhook: failed to claim deliveries: %v", err)
+			}
+			if err != nil || claimed < w.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) deliver(ctx context.Context, delivery Delivery) {
+	statusCode, err := w.sender.Send(ctx, delivery)
+	outcome := Outcome{StatusCode: statusCode, Delivered: err == nil}
+	if err != nil {
+		outcome.Error = err.Error()
+		if delivery.Attempt >= w.policy.MaxAttempts {
+			outcome.Dead = true
+			log.Printf("webhook: delivery %s of %s to webhook %s dead-lettered after %d attempts: %v",
+				delivery.ID, delivery.Event, delivery.WebhookID, delivery.Attempt, err)
+		} else {
+			outcome.RetryIn = w.policy.Backoff(delivery.Attempt)
+		}
+	}
+
+	if err := w.queue.Record(ctx, delivery, outcome); err != nil {
+		log.Printf("webhook: failed to record delivery %s: %v", delivery.ID, err)
+	}
+}
@@ -34,8 +34,12 @@ export async function getAllUsers(): Promise<{ users: User[]; error?: Error }> {
 	}
 }
 
-export async function CreateUser(userName: string, password: string): Promise<User | void> {
-	console.log('Creating user:', userName, password);
+export type PasswordViolation = { code: string; message: string };
+
+export async function CreateUser(
+	userName: string,
+	password: string
+): Promise<{ user?: User; violations?: PasswordViolation[] }> {
 	const response = await authFetch(`${apiUrl}/api/admin/create-user`, {
 		method: 'POST',
 		body: JSON.stringify({
@@ -45,16 +49,34 @@ export async function CreateUser(userName: string, password: string): Promise<Us
 	});
 
 	if (!response.ok) {
-		console.error('Failed to create user:', response.status, await response.text().catch(() => ''));
-		return;
+		const body = await response.json().catch(() => null);
+		if (body?.violations) {
+			return { violations: body.violations };
+		}
+		console.error('Failed to create user:', response.status, body);
+		return {};
 	}
 
 	const apiResponse = await response.json();
-	console.log('response', apiResponse);
-	if (apiResponse.user) {
-		return apiResponse.user;
+	if (apiResponse.id) {
+		return { user: apiResponse };
 	} else {
-		console.error("API response for create user did not contain a 'user' object:", apiResponse);
-		return;
+		console.error('API response for create user did not contain a user:', apiResponse);
+		return {};
+	}
+}
+
+export type PasswordReset = { token: string; expires_at: string };
+
+// IssuePasswordReset creates a single-use reset link token for a user who forgot their password
+export async function IssuePasswordReset(userId: string): Promise<PasswordReset | null> {
+	const response = await authFetch(`${apiUrl}/api/admin/users/${userId}/password-reset`, {
+		method: 'POST'
+	});
+
+	if (!response.ok) {
+		console.error('Failed to issue password reset:', response.status, await response.text().catch(() => ''));
+		return null;
 	}
+	return response.json();
 }
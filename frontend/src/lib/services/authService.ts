@@ -1,9 +1,11 @@
 import { goto } from "$app/navigation";
-import { setAccessToken } from "$lib/stores/authentication";
+import { clearAccessToken, setAccessToken } from "$lib/stores/authentication";
 import { backendUrl } from "$lib/stores/backend";
 import { get } from "svelte/store";
 
-export async function adminLogin(secretKey: string): Promise<boolean> {
+export type AdminLoginResult = 'ok' | 'totpRequired' | 'failed';
+
+export async function adminLogin(username: string, password: string, code = ''): Promise<AdminLoginResult> {
   const url = get(backendUrl);
 			const response = await fetch(url + '/api/auth/admin/login', {
 				// Updated endpoint to include /login
@@ -11,7 +13,7 @@ export async function adminLogin(secretKey: string): Promise<boolean> {
 				headers: {
 					'Content-Type': 'application/json'
 				},
-				body: JSON.stringify({ secretKey }),
+				body: JSON.stringify({ username, password, code }),
 				credentials: 'include'
 			});
 			console.log('Response', response); // Log the response status
@@ -30,8 +32,23 @@ export async function adminLogin(secretKey: string): Promise<boolean> {
 					}
 				}
 			} else {
-				// Handle login error
+				// Accounts with two-factor authentication need a second submit with the code
+				const data = await response.json().catch(() => null);
+				if (data?.totpRequired) {
+					return 'totpRequired';
+				}
 				console.error('Login failed');
 			}
-            return response.ok;
-}
\ No newline at end of file
+            return response.ok ? 'ok' : 'failed';
+}
+export async function logout(): Promise<void> {
+  const url = get(backendUrl);
+  try {
+    await fetch(url + '/api/auth/logout', {
+      method: 'POST',
+      credentials: 'include'
+    });
+  } finally {
+    clearAccessToken();
+  }
+}
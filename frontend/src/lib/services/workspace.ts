@@ -50,4 +50,38 @@ export async function createChannel(workspaceId: string, channelData?: { name: s
 
 	const data = await response.json();
 	return data;
-}
\ No newline at end of file
+}
+export type InvitePreview = {
+	workspace_id: string;
+	workspace_name: string;
+	workspace_image_path: string;
+	role_name: string;
+	expires_at: string;
+};
+
+export async function getInvite(code: string): Promise<InvitePreview | null> {
+	const endPoint = get(backendUrl) + '/api/invites/' + encodeURIComponent(code);
+	const response = await authFetch(endPoint, {
+		method: 'GET'
+	});
+
+	if (!response.ok) {
+		console.error('Failed to fetch invite:', response.status, await response.text().catch(() => ''));
+		return null;
+	}
+	return response.json();
+}
+
+// acceptInvite joins the invite's workspace and returns its ID
+export async function acceptInvite(code: string): Promise<string> {
+	const endPoint = get(backendUrl) + '/api/invites/' + encodeURIComponent(code);
+	const response = await authFetch(endPoint, {
+		method: 'POST'
+	});
+
+	if (!response.ok) {
+		throw new Error(await response.text());
+	}
+	const data = await response.json();
+	return data.workspace_id;
+}
@@ -1,11 +1,37 @@
-import { getAccessToken, setAccessToken } from '$lib/stores/authentication';
+import { clearAccessToken, getAccessToken, setAccessToken } from '$lib/stores/authentication';
+import { backendUrl } from '$lib/stores/backend';
+import { get } from 'svelte/store';
 
-export const authFetch = async (url: string, options: RequestInit = {}): Promise<Response> => {
-  const accessToken = getAccessToken();
-  if (!accessToken) {
-    throw new Error('No access token found');
+// Shared so concurrent 401s trigger a single refresh; a second refresh with the
+// already-rotated token would be treated as reuse and end the session.
+let refreshInFlight: Promise<string | null> | null = null;
+
+export const refreshAccessToken = (): Promise<string | null> => {
+  if (!refreshInFlight) {
+    refreshInFlight = (async () => {
+      try {
+        const response = await fetch(get(backendUrl) + '/api/auth/refresh', {
+          method: 'POST',
+          credentials: 'include'
+        });
+        if (!response.ok) {
+          clearAccessToken();
+          return null;
+        }
+        const data = await response.json();
+        setAccessToken(data.accessToken);
+        return data.accessToken as string;
+      } catch {
+        return null;
+      } finally {
+        refreshInFlight = null;
+      }
+    })();
   }
+  return refreshInFlight;
+};
 
+const send = (url: string, options: RequestInit, accessToken: string): Promise<Response> => {
   const headers: Record<string, string> = {
     ...(options.headers as Record<string, string> || {}),
     Authorization: `Bearer ${accessToken}`
@@ -15,16 +41,28 @@ export const authFetch = async (url: string, options: RequestInit = {}): Promise
     headers['Content-Type'] = 'application/json';
   }
 
-  const response = await fetch(url, {
+  return fetch(url, {
     ...options,
     headers,
     credentials: 'include'
   });
+};
 
-  const newToken = response.headers.get('X-New-Access-Token');
-  if (newToken) {
-    setAccessToken(newToken);
+export const authFetch = async (url: string, options: RequestInit = {}): Promise<Response> => {
+  const accessToken = getAccessToken();
+  if (!accessToken) {
+    throw new Error('No access token found');
   }
 
-  return response;
+  const response = await send(url, options, accessToken);
+  if (response.status !== 401) {
+    return response;
+  }
+
+  // The access token expired; renew it once and retry
+  const newToken = await refreshAccessToken();
+  if (!newToken) {
+    return response;
+  }
+  return send(url, options, newToken);
 };
@@ -0,0 +1,10 @@
+import { getInvite } from '$lib/services/workspace';
+import type { PageLoad } from './$types';
+
+export const load: PageLoad = async ({ params }) => {
+	const invite = await getInvite(params.code);
+	return {
+		code: params.code,
+		invite
+	};
+};